@@ -7,9 +7,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
 )
 
 // Config holds all configuration options for the server.
@@ -18,6 +23,10 @@ type Config struct {
 	MaxWorkers     int           // Max concurrent container executions
 	QueueSize      int           // Max pending requests in queue
 	AcquireTimeout time.Duration // Time to wait for an available worker
+	AcquireMode    string        // "block", "reject" or "queue"; see AcquireModes
+	MaxPerClient   int           // Max concurrent slots one client may hold (0 = unlimited)
+
+	acquireModeErr error // Invalid ACQUIRE_MODE; see ValidateAcquireMode
 
 	// Execution settings
 	ExecutionTimeout time.Duration // Max script execution time
@@ -28,31 +37,154 @@ type Config struct {
 	DockerImage     string // Docker image to use for pandas execution
 	BuildLocal      bool   // Force local build from CutePandas.Dockerfile instead of pulling
 	NetworkDisabled bool   // Disable network in containers
+	NetworkMode     string // Container network: "none", "bridge", or a named network (overrides NetworkDisabled)
+
+	// Additional named images selectable per run_pandas_script call
+	// (IMAGES="ml=registry/ml-image:tag,lean=registry/lean:tag")
+	Images map[string]string
+
+	// Resource limits per image name ("default" is DOCKER_IMAGE), from
+	// IMAGE_PROFILES; see ValidateImageProfiles
+	ImageProfiles    map[string]executor.ResourceProfile
+	imageProfilesErr error
+
+	// Restricted networks run_pandas_script calls can request by host, from
+	// NETWORK_POLICIES; see ValidateNetworkPolicies
+	NetworkPolicies    map[string]executor.NetworkPolicy
+	networkPoliciesErr error
 
 	// Server settings
-	Transport string // Transport type: "stdio" or "http"
+	Transport string // Transport type: "stdio", "http", or "sse"
 	HTTPPort  int    // Port for HTTP transport
 
+	// HTTP server timeouts (0 disables). Event streams (SSE, the MCP GET
+	// stream) and WebSocket connections are exempt from the write timeout.
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+
 	// Storage settings (HTTP mode file uploads)
 	StorageDir    string        // Directory for uploaded files
 	UploadTTL     time.Duration // Auto-delete uploaded files after this duration
 	MaxUploadSize int64         // Maximum upload file size in bytes
 
+	// Bytes of a multipart upload held in memory before spilling to a temp
+	// file; 0 streams the file part straight to storage without buffering
+	MultipartMemory int64
+
+	// HMAC key for signed /storage/download URLs; empty disables /storage/sign
+	DownloadSigningKey string
+
+	// Bearer token required by the storage endpoints; signed download URLs
+	// don't need it. Required when DownloadSigningKey is set
+	StorageAPIKey string
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-Proto header is trusted;
+	// empty trusts none
+	TrustedProxies []string
+
+	// Browser origins allowed to open /ws connections from another origin
+	WSAllowedOrigins []string
+
 	// Malware scanning settings
 	ScanUploads bool   // Enable ClamAV malware scanning for uploads
 	ScanOnFail  string // Behavior when scanner unavailable: "reject" or "allow"
 
+	// Directory malware uploads are moved to instead of being deleted
+	// (outside StorageDir; empty deletes them)
+	QuarantineDir string
+
+	// clamd instances to scan on directly, tried in order with failover
+	// (comma-separated CLAMD_ADDRESSES: "host:3310" or a unix socket path)
+	ClamdAddresses []string
+
+	// How often to run freshclam to refresh virus definitions (0 = never)
+	FreshclamInterval time.Duration
+
 	// Temp directory for script execution (must be accessible to Docker daemon)
 	TempDir string
 
+	// Execution temp dirs older than this are removed at startup (0 = never)
+	TempSweepAge time.Duration
+
 	// Output directory for pandas script outputs (writable by pandas containers)
 	OutputDir string
 
 	// Output TTL for automatic cleanup of execution outputs
 	OutputTTL time.Duration
 
+	// Maximum lifetime an execution can be extended to with extend_output_ttl
+	OutputMaxTTL time.Duration
+
+	// How often expired uploads and execution outputs are swept
+	CleanupInterval time.Duration
+
 	// Chart theme file (optional Python file with matplotlib rcParams)
 	ChartThemeFile string
+
+	// Table format (csv, json, parquet) used when a tool call doesn't
+	// specify one; empty means csv
+	DefaultOutputFormat string
+
+	// Report mixed-type columns when reading inputs, and show pandas'
+	// DtypeWarning in run_pandas_script, instead of silencing them
+	WarnMixedTypes bool
+
+	// Shared data directory mounted read-only at /shared in every container
+	DataDir string
+
+	// User ("UID" or "UID:GID") script containers run as; empty uses the image's USER
+	ContainerUser string
+
+	// Preview limits for read_dataframe output
+	PreviewRows int // Default number of preview rows
+	PreviewCols int // Maximum number of columns shown in previews
+
+	// Maximum operations in one transform_data or pipeline request
+	MaxOperations int
+
+	// run_pandas_script request limits
+	MaxScriptBytes int // Maximum size of the script argument
+	MaxInputFiles  int // Maximum number of input files
+	MaxExtractMB   int // Maximum total size extracted from archive inputs
+
+	// Inputs of pandas-based tools larger than this multiple of
+	// MaxMemoryMB are rejected before running (0 = no check)
+	InputSizeRatio float64
+
+	// analyze_data output caps
+	MaxCorrColumns int // Maximum numeric columns in a corr matrix
+	MaxValueCounts int // Maximum values printed per column by value_counts
+
+	// Largest transform_data result returned inline with return_data
+	MaxReturnRows int
+
+	// Concurrent executions allowed per tool or analysis type, keyed by tool
+	// name or "analyze_data:<analysis_type>" (TOOL_CONCURRENCY)
+	ToolConcurrency map[string]int
+
+	// Output file extensions get_output returns as text, on top of the
+	// defaults; "-.ext" makes a default one binary (comma-separated TEXT_EXTENSIONS)
+	TextExtensions []string
+
+	// Tools that fail immediately with a busy error instead of waiting
+	// for a worker slot (comma-separated FAST_FAIL_TOOLS)
+	FastFailTools []string
+
+	// Completion callbacks for run_pandas_script callback_url
+	CallbackAllowedHosts []string // Hosts callbacks may target; empty disables callbacks
+	CallbackSecret       string   // HMAC-SHA256 key used to sign callback payloads
+}
+
+// AcquireModes are the ACQUIRE_MODE values. "block" waits up to
+// AcquireTimeout for a worker slot, "reject" fails at once when every slot is
+// busy, and "queue" waits like "block" but fails at once when QueueSize
+// requests are already waiting.
+var AcquireModes = []string{"block", "reject", "queue"}
+
+// ValidateAcquireMode reports an ACQUIRE_MODE that isn't one of AcquireModes.
+func (c *Config) ValidateAcquireMode() error {
+	return c.acquireModeErr
 }
 
 // DefaultConfig returns the default configuration.
@@ -63,6 +195,7 @@ func DefaultConfig() *Config {
 		MaxWorkers:       5,
 		QueueSize:        10,
 		AcquireTimeout:   30 * time.Second,
+		AcquireMode:      "block",
 		ExecutionTimeout: 60 * time.Second,
 		MaxMemoryMB:      512,
 		MaxCPU:           1.0,
@@ -71,15 +204,32 @@ func DefaultConfig() *Config {
 		NetworkDisabled:  true,
 		Transport:        "stdio",
 		HTTPPort:         8080,
+		HTTPReadTimeout:  5 * time.Minute,
+		HTTPWriteTimeout: 10 * time.Minute,
+		HTTPIdleTimeout:  2 * time.Minute,
 		StorageDir:       defaultStorageDir(),       // ~/.cache/cute-pandas/uploads or /storage in Docker
 		UploadTTL:        1 * time.Hour,             // Auto-delete after 1 hour
 		MaxUploadSize:    100 * 1024 * 1024,         // 100MB
+		MultipartMemory:  32 << 20,                  // 32MB buffered in memory
 		ScanUploads:      true,                      // Enable malware scanning by default
 		ScanOnFail:       "reject",                  // Reject uploads if scanner unavailable
 		TempDir:          defaultTempDir(),          // Temp dir accessible to Docker daemon
+		TempSweepAge:     6 * time.Hour,             // Remove temp dirs leaked by crashed runs
 		OutputDir:        defaultOutputDir(),        // Output dir for pandas scripts
 		OutputTTL:        24 * time.Hour,            // Auto-delete outputs after 24 hours
+		OutputMaxTTL:     7 * 24 * time.Hour,        // Extensions capped at 7 days after creation
+		CleanupInterval:  1 * time.Minute,           // Sweep expired uploads and outputs every minute
 		ChartThemeFile:   "",                         // No chart theme by default
+		PreviewRows:      5,
+		PreviewCols:      20,
+		MaxOperations:    100,
+		MaxScriptBytes:   1 << 20, // 1MB
+		MaxInputFiles:    20,
+		MaxExtractMB:     1024,
+		InputSizeRatio:   1.0,
+		MaxCorrColumns:   50,
+		MaxValueCounts:   20,
+		MaxReturnRows:    1000,
 	}
 }
 
@@ -142,6 +292,19 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if v := os.Getenv("ACQUIRE_MODE"); v != "" {
+		cfg.AcquireMode = strings.ToLower(v)
+		if !slices.Contains(AcquireModes, cfg.AcquireMode) {
+			cfg.acquireModeErr = fmt.Errorf("%q (use %s)", v, strings.Join(AcquireModes, ", "))
+		}
+	}
+
+	if v := os.Getenv("MAX_WORKERS_PER_CLIENT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxPerClient = n
+		}
+	}
+
 	if v := os.Getenv("EXECUTION_TIMEOUT"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.ExecutionTimeout = d
@@ -164,6 +327,21 @@ func LoadFromEnv() *Config {
 		cfg.DockerImage = v
 	}
 
+	if v := os.Getenv("IMAGES"); v != "" {
+		cfg.Images = make(map[string]string)
+		for _, item := range splitList(v) {
+			name, ref, ok := strings.Cut(item, "=")
+			name, ref = strings.TrimSpace(name), strings.TrimSpace(ref)
+			if ok && name != "" && ref != "" {
+				cfg.Images[name] = ref
+			}
+		}
+	}
+
+	if v := os.Getenv("IMAGE_PROFILES"); v != "" {
+		cfg.ImageProfiles, cfg.imageProfilesErr = parseImageProfiles(v)
+	}
+
 	if v := os.Getenv("BUILD_LOCAL"); v != "" {
 		cfg.BuildLocal = v == "true" || v == "1"
 	}
@@ -172,6 +350,14 @@ func LoadFromEnv() *Config {
 		cfg.NetworkDisabled = v == "true" || v == "1"
 	}
 
+	if v := os.Getenv("NETWORK_MODE"); v != "" {
+		cfg.NetworkMode = v
+	}
+
+	if v := os.Getenv("NETWORK_POLICIES"); v != "" {
+		cfg.NetworkPolicies, cfg.networkPoliciesErr = parseNetworkPolicies(v)
+	}
+
 	if v := os.Getenv("TRANSPORT"); v != "" {
 		cfg.Transport = v
 	}
@@ -182,6 +368,24 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if v := os.Getenv("HTTP_READ_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HTTPReadTimeout = d
+		}
+	}
+
+	if v := os.Getenv("HTTP_WRITE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HTTPWriteTimeout = d
+		}
+	}
+
+	if v := os.Getenv("HTTP_IDLE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HTTPIdleTimeout = d
+		}
+	}
+
 	if v := os.Getenv("STORAGE_DIR"); v != "" {
 		cfg.StorageDir = v
 	}
@@ -198,6 +402,28 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if v := os.Getenv("MULTIPART_MEMORY"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.MultipartMemory = n
+		}
+	}
+
+	if v := os.Getenv("DOWNLOAD_SIGNING_KEY"); v != "" {
+		cfg.DownloadSigningKey = v
+	}
+
+	if v := os.Getenv("STORAGE_API_KEY"); v != "" {
+		cfg.StorageAPIKey = v
+	}
+
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		cfg.TrustedProxies = splitList(v)
+	}
+
+	if v := os.Getenv("WS_ALLOWED_ORIGINS"); v != "" {
+		cfg.WSAllowedOrigins = splitList(v)
+	}
+
 	if v := os.Getenv("SCAN_UPLOADS"); v != "" {
 		cfg.ScanUploads = v == "true" || v == "1"
 	}
@@ -208,16 +434,48 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if v := os.Getenv("QUARANTINE_DIR"); v != "" {
+		cfg.QuarantineDir = v
+	}
+
+	if v := os.Getenv("CLAMD_ADDRESSES"); v != "" {
+		cfg.ClamdAddresses = splitList(v)
+	}
+
+	if v := os.Getenv("FRESHCLAM_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.FreshclamInterval = d
+		}
+	}
+
 	if v := os.Getenv("OUTPUT_TTL"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil && d > 0 {
 			cfg.OutputTTL = d
 		}
 	}
 
+	if v := os.Getenv("OUTPUT_MAX_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.OutputMaxTTL = d
+		}
+	}
+
+	if v := os.Getenv("CLEANUP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.CleanupInterval = d
+		}
+	}
+
 	if v := os.Getenv("TEMP_DIR"); v != "" {
 		cfg.TempDir = v
 	}
 
+	if v := os.Getenv("TEMP_SWEEP_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.TempSweepAge = d
+		}
+	}
+
 	if v := os.Getenv("OUTPUT_DIR"); v != "" {
 		cfg.OutputDir = v
 	}
@@ -226,5 +484,141 @@ func LoadFromEnv() *Config {
 		cfg.ChartThemeFile = v
 	}
 
+	if v := os.Getenv("DEFAULT_OUTPUT_FORMAT"); v != "" {
+		cfg.DefaultOutputFormat = strings.ToLower(v)
+	}
+
+	if v := os.Getenv("WARN_MIXED_TYPES"); v != "" {
+		cfg.WarnMixedTypes = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("DATA_DIR"); v != "" {
+		cfg.DataDir = v
+	}
+
+	if v := os.Getenv("CONTAINER_USER"); v != "" {
+		cfg.ContainerUser = v
+	}
+
+	if v := os.Getenv("PREVIEW_ROWS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.PreviewRows = n
+		}
+	}
+
+	if v := os.Getenv("PREVIEW_COLS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.PreviewCols = n
+		}
+	}
+
+	if v := os.Getenv("MAX_OPERATIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxOperations = n
+		}
+	}
+
+	if v := os.Getenv("MAX_SCRIPT_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxScriptBytes = n
+		}
+	}
+
+	if v := os.Getenv("MAX_INPUT_FILES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxInputFiles = n
+		}
+	}
+
+	if v := os.Getenv("MAX_EXTRACT_MB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxExtractMB = n
+		}
+	}
+
+	if v := os.Getenv("INPUT_SIZE_RATIO"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.InputSizeRatio = f
+		}
+	}
+
+	if v := os.Getenv("MAX_CORR_COLUMNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxCorrColumns = n
+		}
+	}
+
+	if v := os.Getenv("MAX_VALUE_COUNTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxValueCounts = n
+		}
+	}
+
+	if v := os.Getenv("MAX_RETURN_ROWS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxReturnRows = n
+		}
+	}
+
+	if v := os.Getenv("TOOL_CONCURRENCY"); v != "" {
+		cfg.ToolConcurrency = parseToolConcurrency(v)
+	}
+
+	if v := os.Getenv("TEXT_EXTENSIONS"); v != "" {
+		cfg.TextExtensions = splitList(v)
+	}
+
+	if v := os.Getenv("FAST_FAIL_TOOLS"); v != "" {
+		cfg.FastFailTools = splitList(v)
+	}
+
+	if v := os.Getenv("CALLBACK_ALLOWED_HOSTS"); v != "" {
+		cfg.CallbackAllowedHosts = splitList(v)
+	}
+
+	if v := os.Getenv("CALLBACK_SECRET"); v != "" {
+		cfg.CallbackSecret = v
+	}
+
 	return cfg
 }
+
+// ContainerNetworkMode returns the effective container network mode:
+// NetworkMode if set, otherwise "none" when NetworkDisabled is true and
+// "bridge" (Docker's default) when it is false.
+func (c *Config) ContainerNetworkMode() string {
+	if c.NetworkMode != "" {
+		return c.NetworkMode
+	}
+	if c.NetworkDisabled {
+		return "none"
+	}
+	return "bridge"
+}
+
+// parseToolConcurrency parses "analyze_data:corr=2,profile_data=1". Entries
+// without a positive limit are ignored.
+func parseToolConcurrency(v string) map[string]int {
+	limits := make(map[string]int)
+	for _, item := range splitList(v) {
+		key, n, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if limit, err := strconv.Atoi(strings.TrimSpace(n)); err == nil && limit > 0 {
+			limits[strings.TrimSpace(key)] = limit
+		}
+	}
+	return limits
+}
+
+// splitList splits a comma-separated list, trimming spaces and dropping empty entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
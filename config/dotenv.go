@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultEnvFile is the .env file loaded when no path is given.
+const DefaultEnvFile = ".env"
+
+// envFileKeys records variables set by LoadEnvFile, so reloading the file
+// can update them while still leaving the real process environment alone.
+var (
+	envFileKeys   = make(map[string]bool)
+	envFileKeysMu sync.Mutex
+)
+
+// LoadEnvFile sets environment variables from a .env file so LoadFromEnv
+// picks them up. Variables already present in the process environment take
+// precedence. Lines are KEY=VALUE; blank lines, # comments, and an optional
+// "export " prefix are allowed, and values may be single- or double-quoted.
+// Returns the number of variables set.
+func LoadEnvFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	envFileKeysMu.Lock()
+	defer envFileKeysMu.Unlock()
+
+	set := 0
+	sc := bufio.NewScanner(f)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return set, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return set, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+
+		if _, exists := os.LookupEnv(key); exists && !envFileKeys[key] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return set, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		envFileKeys[key] = true
+		set++
+	}
+	if err := sc.Err(); err != nil {
+		return set, err
+	}
+	return set, nil
+}
+
+// parseEnvValue unquotes a .env value. Single-quoted values are literal;
+// double-quoted values support \n, \t, \" and \\ escapes; unquoted values
+// end at an inline " #" comment.
+func parseEnvValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	switch q := v[0]; q {
+	case '\'', '"':
+		end := strings.LastIndexByte(v, q)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated %c quote", q)
+		}
+		inner := v[1:end]
+		if q == '\'' {
+			return inner, nil
+		}
+		return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(inner), nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+// parseImageProfiles parses IMAGE_PROFILES: semicolon-separated
+// "name:key=value,..." entries with keys memory (MB), cpu, timeout, and pids,
+// e.g. "lean:memory=256,timeout=30s;ml:memory=4096,cpu=4,timeout=300s".
+func parseImageProfiles(v string) (map[string]executor.ResourceProfile, error) {
+	profiles := make(map[string]executor.ResourceProfile)
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, settings, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("profile %q: expected name:key=value,...", entry)
+		}
+
+		var p executor.ResourceProfile
+		for _, kv := range splitList(settings) {
+			key, value, ok := strings.Cut(kv, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok {
+				return nil, fmt.Errorf("profile %s: expected key=value, got %q", name, kv)
+			}
+			var err error
+			switch key {
+			case "memory":
+				p.MemoryMB, err = strconv.ParseInt(value, 10, 64)
+			case "cpu":
+				p.CPU, err = strconv.ParseFloat(value, 64)
+			case "timeout":
+				p.Timeout, err = time.ParseDuration(value)
+			case "pids":
+				p.PidsLimit, err = strconv.ParseInt(value, 10, 64)
+			default:
+				return nil, fmt.Errorf("profile %s: unknown setting %q (use memory, cpu, timeout, pids)", name, key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: invalid %s %q", name, key, value)
+			}
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// ValidateImageProfiles reports IMAGE_PROFILES syntax errors, profiles for
+// images that are not configured, and out-of-range limits.
+func (c *Config) ValidateImageProfiles() error {
+	if c.imageProfilesErr != nil {
+		return c.imageProfilesErr
+	}
+	for name, p := range c.ImageProfiles {
+		if _, ok := c.Images[name]; !ok && name != "default" {
+			return fmt.Errorf("profile %s: no such image (configure it in IMAGES or use \"default\")", name)
+		}
+		if p.MemoryMB < 0 || (p.MemoryMB > 0 && p.MemoryMB < 6) {
+			return fmt.Errorf("profile %s: memory must be at least 6 MB", name)
+		}
+		if p.CPU < 0 {
+			return fmt.Errorf("profile %s: cpu must be positive", name)
+		}
+		if p.Timeout < 0 {
+			return fmt.Errorf("profile %s: timeout must be positive", name)
+		}
+		if p.PidsLimit < 0 {
+			return fmt.Errorf("profile %s: pids must be positive", name)
+		}
+	}
+	return nil
+}
+
+// parseNetworkPolicies parses NETWORK_POLICIES: semicolon-separated
+// "name:key=value,..." entries with keys network, proxy, and hosts (separated
+// by "|"), e.g. "api:network=api-egress,hosts=api.internal|*.corp.example".
+func parseNetworkPolicies(v string) (map[string]executor.NetworkPolicy, error) {
+	policies := make(map[string]executor.NetworkPolicy)
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, settings, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("policy %q: expected name:key=value,...", entry)
+		}
+
+		var p executor.NetworkPolicy
+		for _, kv := range splitList(settings) {
+			key, value, ok := strings.Cut(kv, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok {
+				return nil, fmt.Errorf("policy %s: expected key=value, got %q", name, kv)
+			}
+			switch key {
+			case "network":
+				p.Network = value
+			case "proxy":
+				p.Proxy = value
+			case "hosts":
+				for _, host := range strings.Split(value, "|") {
+					if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+						p.Hosts = append(p.Hosts, host)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("policy %s: unknown setting %q (use network, proxy, hosts)", name, key)
+			}
+		}
+		policies[name] = p
+	}
+	return policies, nil
+}
+
+// ValidateNetworkPolicies reports NETWORK_POLICIES syntax errors and policies
+// without a network or hosts. Whether the networks exist is checked by the
+// executor.
+func (c *Config) ValidateNetworkPolicies() error {
+	if c.networkPoliciesErr != nil {
+		return c.networkPoliciesErr
+	}
+	for name, p := range c.NetworkPolicies {
+		if p.Network == "" {
+			return fmt.Errorf("policy %s: network is required", name)
+		}
+		if len(p.Hosts) == 0 {
+			return fmt.Errorf("policy %s: hosts is required", name)
+		}
+		if p.Proxy != "" && !strings.Contains(p.Proxy, "://") {
+			return fmt.Errorf("policy %s: proxy must be a URL such as http://egress-proxy:3128", name)
+		}
+	}
+	return nil
+}
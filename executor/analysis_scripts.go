@@ -0,0 +1,1501 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+// Package executor provides script templates for dedicated analysis tools.
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// readDataHelper defines read_data(), which loads a data file into a DataFrame
+// using the reader that matches its extension.
+const readDataHelper = `
+# Extensions read by read_data(); anything else is tried as CSV
+DATA_EXTENSIONS = ['.csv', '.xlsx', '.xls', '.json', '.parquet', '.dta', '.sas7bdat', '.xpt', '.sav', '.zsav', '.html', '.htm']
+
+# Leading bytes of binary formats; these win over a file's extension
+FORMAT_MAGIC = [
+    (b'PAR1', '.parquet'),
+    (b'\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1', '.xls'),
+    (b'<stata_dta>', '.dta'),
+    (b'$FL2', '.sav'),
+    (b'$FL3', '.zsav'),
+    (b'HEADER RECORD*******LIBRARY HEADER RECORD', '.xpt'),
+    (bytes(12) + b'\xc2\xea\x81\x60\xb3\x14\x11\xcf\xbd\x92\x08\x00\x09\xc7\x31\x8c\x18\x1f\x10\x11', '.sas7bdat'),
+]
+BINARY_FORMATS = ['.parquet', '.xlsx', '.xls', '.dta', '.sav', '.zsav', '.xpt', '.sas7bdat']
+# Extensions read by the same reader
+FORMAT_FAMILY = {'.xls': '.xlsx', '.zsav': '.sav', '.htm': '.html'}
+
+def sniff_format(file_path):
+    """Guess a file's format from its content; returns an extension or None."""
+    try:
+        with open(file_path, 'rb') as f:
+            head = f.read(4096)
+    except OSError:
+        return None
+    for magic, ext in FORMAT_MAGIC:
+        if head.startswith(magic):
+            return ext
+    if head.startswith(b'PK\x03\x04'):
+        import zipfile
+        try:
+            with zipfile.ZipFile(file_path) as z:
+                if any(n.startswith('xl/') for n in z.namelist()):
+                    return '.xlsx'
+        except zipfile.BadZipFile:
+            pass
+        return None
+    text = head.lstrip(b'\xef\xbb\xbf \t\r\n').lower()
+    if text.startswith((b'{', b'[')):
+        return '.json'
+    if text.startswith(b'<') and (b'<html' in text or b'<table' in text):
+        return '.html'
+    return None
+
+def detect_format(file_path):
+    """
+    Return the extension read_data() reads file_path as. A binary format
+    recognised from the leading bytes wins over the extension; JSON and HTML
+    content is only sniffed when the extension isn't a known one, and
+    anything else is read as CSV.
+    """
+    ext = os.path.splitext(file_path)[1].lower()
+    sniffed = sniff_format(file_path)
+    if sniffed in BINARY_FORMATS and FORMAT_FAMILY.get(sniffed, sniffed) != FORMAT_FAMILY.get(ext, ext):
+        return sniffed
+    if ext in DATA_EXTENSIONS:
+        return ext
+    return sniffed or '.csv'
+
+def _read_file(file_path, ext, options, kwargs):
+    if ext in ['.xlsx', '.xls']:
+        df = pd.read_excel(file_path, **kwargs)
+        if isinstance(df, dict):
+            raise ValueError("sheet_name must select a single sheet")
+        return df
+    elif ext == '.json':
+        if options.get('normalize'):
+            with open(file_path) as f:
+                if kwargs.get('lines'):
+                    data = [json.loads(line) for line in f if line.strip()]
+                else:
+                    data = json.load(f)
+            df = pd.json_normalize(data, record_path=options.get('record_path'), meta=options.get('meta'), errors='ignore')
+            df.attrs['json_normalized'] = True
+            return df
+        return pd.read_json(file_path, **kwargs)
+    elif ext == '.parquet':
+        return pd.read_parquet(file_path, **kwargs)
+    elif ext == '.dta':
+        return pd.read_stata(file_path, **kwargs)
+    elif ext == '.sas7bdat':
+        return pd.read_sas(file_path, format='sas7bdat', **kwargs)
+    elif ext == '.xpt':
+        return pd.read_sas(file_path, format='xport', **kwargs)
+    elif ext in ['.sav', '.zsav']:
+        return pd.read_spss(file_path, **kwargs)
+    elif ext in ['.html', '.htm']:
+        tables = pd.read_html(file_path, **kwargs)
+        table_index = options.get('table_index', 0)
+        if table_index >= len(tables):
+            raise ValueError(f"table_index {table_index} is out of range: the page has {len(tables)} table(s)")
+        df = tables[table_index]
+        df.attrs['html_table_count'] = len(tables)
+        return df
+    return pd.read_csv(file_path, **kwargs)
+
+def _renamed_duplicates(file_path, ext, options, kwargs, df):
+    """
+    Return [{column, renamed}] for header names that appear more than once in
+    a CSV or Excel file, which pandas silently renames to name.1, name.2, ...
+    """
+    header = kwargs.get('header', 0)
+    if ext not in ['.csv', '.xlsx', '.xls'] or not isinstance(header, int) or kwargs.get('names') is not None:
+        return []
+    raw_kwargs = {k: v for k, v in kwargs.items() if k not in ('dtype', 'parse_dates', 'usecols', 'index_col', 'converters', 'skipfooter', 'engine')}
+    raw_kwargs.update(header=None, nrows=header + 1)
+    try:
+        raw = [str(c) for c in _read_file(file_path, ext, options, raw_kwargs).iloc[header]]
+    except Exception:
+        return []
+    if len(raw) != len(df.columns):
+        return []
+    from collections import Counter
+    counts = Counter(raw)
+    renamed = {}
+    for i, name in enumerate(raw):
+        if counts[name] > 1 and str(df.columns[i]) != name:
+            renamed.setdefault(name, []).append(str(df.columns[i]))
+    return [{"column": name, "renamed": cols} for name, cols in renamed.items()]
+
+def _available_columns(file_path, ext, options, kwargs):
+    """Column names of file_path read without its rows, or None if unknown."""
+    try:
+        if ext == '.parquet':
+            import pyarrow.parquet as pq
+            return [str(c) for c in pq.read_schema(file_path).names]
+        if ext in ['.csv', '.xlsx', '.xls']:
+            head_kwargs = {k: v for k, v in kwargs.items() if k not in ('dtype', 'parse_dates', 'skipfooter', 'engine')}
+            head_kwargs['nrows'] = 0
+            return [str(c) for c in _read_file(file_path, ext, options, head_kwargs).columns]
+    except Exception:
+        return None
+    return None
+
+def _apply_index_col(df, options):
+    """
+    Move the index_col column (a name, or an int position among the columns
+    read; strings are always names, even all-digit ones) into the index.
+    Without one, a leading "Unnamed: 0" column left by to_csv(index=True) is
+    dropped if drop_unnamed_index is set, or noted.
+    """
+    attrs = dict(df.attrs)
+    index_col = options.get('index_col')
+    if index_col is not None:
+        if isinstance(index_col, int) and not isinstance(index_col, bool):
+            if not 0 <= index_col < len(df.columns):
+                raise ValueError(f"index_col {index_col} is out of range for {len(df.columns)} columns")
+            index_col = df.columns[index_col]
+        elif index_col not in df.columns:
+            raise ValueError(f"index_col '{index_col}' not found. Available: {list(df.columns)}")
+        df = df.set_index(index_col)
+        df.attrs.update(attrs)
+        # Saved outputs keep the index as a column
+        df.attrs['keep_index'] = True
+    elif len(df.columns) and str(df.columns[0]).startswith('Unnamed: 0'):
+        unnamed = str(df.columns[0])
+        if options.get('drop_unnamed_index'):
+            df = df.drop(columns=df.columns[0])
+            df.attrs.update(attrs)
+            df.attrs['dropped_unnamed_index'] = unnamed
+        else:
+            df.attrs['unnamed_index'] = unnamed
+    return df
+
+class EmptyFileError(ValueError):
+    """Raised for zero-byte files and, unless allowed, header-only files."""
+
+class ParseError(ValueError):
+    """Raised when the reader for a format can't parse a file."""
+
+# Readers tried, in order, when a file doesn't parse as its detected format
+FALLBACK_FORMATS = ['.csv', '.xlsx', '.json', '.parquet', '.html']
+FORMAT_NAMES = {'.csv': 'CSV', '.xlsx': 'Excel', '.xls': 'Excel', '.json': 'JSON', '.parquet': 'Parquet', '.html': 'HTML', '.htm': 'HTML'}
+
+# Set by the server's WARN_MIXED_TYPES; the warn_mixed_types read option
+# overrides it per call
+WARN_MIXED_TYPES = os.environ.get('WARN_MIXED_TYPES') == '1'
+
+def mixed_type_columns(df):
+    """Return {column: [type names]} for object columns holding values of more than one type."""
+    mixed = {}
+    for col in df.columns:
+        if df[col].dtype != object:
+            continue
+        kinds = sorted({type(v).__name__ for v in df[col].dropna()})
+        if len(kinds) > 1:
+            mixed[str(col)] = kinds
+    return mixed
+
+def read_data(file_path, options=None):
+    """
+    Read a data file into a DataFrame based on its content and extension.
+    If it doesn't parse as the detected format, the other common formats are
+    tried in turn, so mislabeled files (an Excel file named .csv, ...) still
+    read; the mismatch is printed and the original error raised if none work.
+    """
+    options = options or {}
+    ext = detect_format(file_path)
+    try:
+        return _read_data(file_path, options, ext)
+    except ParseError as e:
+        error = e.__cause__ or e
+
+    family = FORMAT_FAMILY.get(ext, ext)
+    # Reader options are format specific, so fallbacks use the defaults
+    fallback_options = {k: v for k, v in options.items() if k != 'options'}
+    with open(file_path, 'rb') as f:
+        binary = b'\x00' in f.read(4096)
+    for other in FALLBACK_FORMATS:
+        if other == family or (binary and other in ['.csv', '.json', '.html']):
+            continue
+        try:
+            df = _read_data(file_path, fallback_options, other)
+        except Exception:
+            continue
+        named = os.path.splitext(file_path)[1].lower() or '(none)'
+        print(f"Note: {os.path.basename(file_path)}: file extension is {named} but content parsed as {FORMAT_NAMES[other]}")
+        df.attrs['format_mismatch'] = {"extension": named, "parsed_as": other.lstrip('.')}
+        return df
+    raise error
+
+def read_head(file_path, rows, failed):
+    """
+    Read the first rows rows of a file that didn't parse as its detected
+    format failed, trying the fallback formats with readers that stop after
+    rows rows. Formats without such a reader (HTML) are not tried, so a large
+    mislabeled file is never loaded whole; ValueError is raised if none work.
+    """
+    family = FORMAT_FAMILY.get(failed, failed)
+    with open(file_path, 'rb') as f:
+        binary = b'\x00' in f.read(4096)
+    for other in FALLBACK_FORMATS:
+        if other in [family, '.html'] or (binary and other in ['.csv', '.json']):
+            continue
+        try:
+            if other == '.parquet':
+                import pyarrow.parquet as pq
+                pf = pq.ParquetFile(file_path)
+                batch = next(pf.iter_batches(batch_size=rows), None)
+                df = batch.to_pandas() if batch is not None else pf.schema_arrow.empty_table().to_pandas()
+            elif other == '.xlsx':
+                df = pd.read_excel(file_path, nrows=rows)
+            elif other == '.json':
+                df = pd.read_json(file_path, lines=True, nrows=rows)
+            else:
+                df = pd.read_csv(file_path, nrows=rows)
+        except Exception:
+            continue
+        named = os.path.splitext(file_path)[1].lower() or '(none)'
+        print(f"Note: {os.path.basename(file_path)}: file extension is {named} but content parsed as {FORMAT_NAMES[other]}")
+        return df
+    raise ValueError("no other format could read the file")
+
+def _read_data(file_path, options, ext):
+    """Read file_path as the format of extension ext."""
+    name = os.path.basename(file_path)
+    if os.path.isfile(file_path) and os.path.getsize(file_path) == 0:
+        raise EmptyFileError(f"{name}: file is empty")
+
+    # Validate dtype overrides before reading so a typo fails fast
+    dtypes = options.get('dtypes') or {}
+    parse_dates = options.get('parse_dates') or []
+    for col, spec in dtypes.items():
+        try:
+            pd.api.types.pandas_dtype(spec)
+        except TypeError:
+            raise ValueError(f"Invalid dtype '{spec}' for column '{col}'. Use a pandas dtype name such as 'str', 'string', 'Int64', 'float64', 'bool', or 'category'")
+
+    # Passthrough reader options; CSV and Excel also apply dtype overrides
+    # while parsing, which preserves leading zeros
+    kwargs = dict(options.get('options') or {})
+    at_read = ext in ['.csv', '.xlsx', '.xls']
+    if at_read:
+        if dtypes:
+            kwargs['dtype'] = dtypes
+        if parse_dates:
+            kwargs['parse_dates'] = parse_dates
+
+    # Extra missing-value markers such as "N/A", "-" or "99999"
+    na_values = [str(v) for v in options.get('na_values') or []]
+    keep_default_na = options.get('keep_default_na')
+    if at_read:
+        if na_values:
+            kwargs['na_values'] = na_values
+        if keep_default_na is not None:
+            kwargs['keep_default_na'] = keep_default_na
+
+    # Preamble and trailer lines, e.g. report titles and footer totals
+    skiprows = options.get('skiprows') or 0
+    skipfooter = options.get('skipfooter') or 0
+    if skiprows or skipfooter:
+        if not at_read:
+            raise ValueError(f"{name}: skiprows and skipfooter only apply to CSV and Excel files")
+        if skiprows:
+            kwargs['skiprows'] = skiprows
+        if skipfooter:
+            kwargs['skipfooter'] = skipfooter
+            if ext == '.csv':
+                # Only the python parser supports skipfooter
+                kwargs['engine'] = 'python'
+
+    # Load only the usecols columns where the reader supports it; other
+    # formats are read whole and narrowed afterwards
+    usecols = list(dict.fromkeys(options.get('usecols') or []))
+    if usecols:
+        available = _available_columns(file_path, ext, options, kwargs)
+        if available is not None:
+            found = [c for c in usecols if c in available]
+            if not found:
+                raise ValueError(f"None of the usecols columns exist: {usecols}. Available: {available}")
+            kwargs['columns' if ext == '.parquet' else 'usecols'] = found
+
+    try:
+        df = _read_file(file_path, ext, options, kwargs)
+    except pd.errors.EmptyDataError:
+        raise EmptyFileError(f"{name}: file is empty") from None
+    except Exception as e:
+        raise ParseError(str(e)) from e
+    if len(df) == 0 and not options.get('allow_empty'):
+        if len(df.columns) == 0:
+            raise EmptyFileError(f"{name}: file is empty")
+        raise EmptyFileError(f"{name}: file has headers but no rows (columns: {', '.join(str(c) for c in df.columns)}); set allow_empty to read it as an empty frame")
+
+    if usecols:
+        missing_usecols = [c for c in usecols if c not in df.columns]
+        if len(missing_usecols) == len(usecols):
+            raise ValueError(f"None of the usecols columns exist: {usecols}. Available: {list(df.columns)}")
+        attrs = dict(df.attrs)
+        df = df[[c for c in usecols if c in df.columns]]
+        df.attrs.update(attrs)
+        if missing_usecols:
+            print(f"Warning: {name}: usecols not found and skipped: {missing_usecols}")
+            df.attrs['missing_usecols'] = missing_usecols
+
+    missing = [c for c in list(dtypes) + parse_dates if c not in df.columns]
+    if missing:
+        raise ValueError(f"Column(s) in dtypes/parse_dates not found: {missing}. Available: {list(df.columns)}")
+    if na_values and not at_read:
+        # Other readers have no na_values; match the cell text instead
+        for col in df.columns:
+            df[col] = df[col].mask(df[col].astype(str).isin(na_values))
+    if not at_read:
+        for col, spec in dtypes.items():
+            df[col] = df[col].astype(spec)
+        for col in parse_dates:
+            df[col] = pd.to_datetime(df[col])
+    if skiprows or skipfooter:
+        df.attrs['skipped'] = {"rows": skiprows, "footer": skipfooter}
+    warn_mixed = options.get('warn_mixed_types')
+    if warn_mixed if warn_mixed is not None else WARN_MIXED_TYPES:
+        # pandas' DtypeWarning is silenced with the other warnings; name the
+        # columns instead so they can be given a dtype
+        mixed = mixed_type_columns(df)
+        if mixed:
+            listed = ", ".join(f"{c} ({'/'.join(kinds)})" for c, kinds in mixed.items())
+            print(f"Warning: {name}: mixed types in column(s) {listed}; set dtypes to read them consistently")
+            df.attrs['mixed_type_columns'] = mixed
+    duplicates = _renamed_duplicates(file_path, ext, options, kwargs, df)
+    if duplicates:
+        df.attrs['renamed_duplicates'] = duplicates
+    df = _apply_index_col(df, options)
+    named = os.path.splitext(file_path)[1].lower()
+    if ext != (named if named in DATA_EXTENSIONS else '.csv'):
+        df.attrs['detected_format'] = ext.lstrip('.')
+    return df
+
+def read_labels(file_path):
+    """Return variable and value labels for Stata/SAS/SPSS files, or None."""
+    ext = detect_format(file_path)
+    readers = {'.dta': 'read_dta', '.sas7bdat': 'read_sas7bdat', '.xpt': 'read_xport', '.sav': 'read_sav', '.zsav': 'read_sav'}
+    if ext not in readers:
+        return None
+    try:
+        import pyreadstat
+        _, meta = getattr(pyreadstat, readers[ext])(file_path, metadataonly=True)
+    except Exception:
+        return None
+    variable_labels = {k: v for k, v in (meta.column_names_to_labels or {}).items() if v}
+    value_labels = {k: {str(code): label for code, label in v.items()} for k, v in (meta.variable_value_labels or {}).items()}
+    if not variable_labels and not value_labels:
+        return None
+    return {"variable_labels": variable_labels, "value_labels": value_labels}
+`
+
+// pyValue renders v as a Python expression by round-tripping it through JSON.
+// This keeps lists, dicts, None and booleans correct without hand-written quoting.
+func pyValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "None"
+	}
+	return fmt.Sprintf("json.loads(%q)", string(data))
+}
+
+// PivotTableScript generates a script that builds a pivot table, prints it,
+// and saves a flattened copy to the output directory.
+func PivotTableScript(containerPath string, index, columns, values []string, aggfunc string, outputFormat string) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s%s
+file_path = %q
+index = %s or []
+columns = %s or []
+values = %s or []
+aggfunc = %q
+output_format = %q
+
+# Pivots estimated to exceed this many cells get a warning
+MAX_CELLS = 100000
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+# Validate all referenced columns up front
+missing = [c for c in index + columns + values if c not in df.columns]
+if missing:
+    print(f"Error: Column(s) not found: {missing}. Available: {list(df.columns)}", file=sys.stderr)
+    sys.exit(1)
+
+# Warn about high-cardinality keys that would produce an enormous table
+warnings_list = []
+n_index = len(df[index].drop_duplicates())
+n_columns = len(df[columns].drop_duplicates()) if columns else 1
+n_values = max(len(values), 1)
+estimated_cells = n_index * n_columns * n_values
+if estimated_cells > MAX_CELLS:
+    msg = (f"Pivot may be very large: ~{n_index:,} index rows × {n_columns:,} column groups × {n_values} value(s) "
+           f"= ~{estimated_cells:,} cells. Consider lower-cardinality keys.")
+    warnings_list.append(msg)
+    print(f"Warning: {msg}")
+    print()
+
+try:
+    table = pd.pivot_table(
+        df,
+        index=index,
+        columns=columns or None,
+        values=values or None,
+        aggfunc=aggfunc,
+    )
+except Exception as e:
+    print(f"Error building pivot table: {e}", file=sys.stderr)
+    sys.exit(1)
+
+print(f"=== Pivot Table ({table.shape[0]} rows × {table.shape[1]} columns, aggfunc={aggfunc}) ===")
+if len(table) > 50:
+    print(f"(Showing first 50 of {len(table)} rows)")
+    print(table.head(50).to_string())
+else:
+    print(table.to_string())
+
+# Save a plain table: flattened columns with the index keys as columns
+flat = flatten_columns(table).reset_index()
+try:
+    output_file = save_frame(flat, 'pivot_table', output_format)
+    print(f"\nOutput saved to: {output_file}")
+except Exception as e:
+    print(f"Error saving output: {e}", file=sys.stderr)
+    sys.exit(1)
+
+emit_result({
+    "shape": {"rows": table.shape[0], "columns": table.shape[1]},
+    "columns": list(flat.columns),
+    "output_file": output_file,
+    "warnings": warnings_list,
+})
+`, emitResultHelper, readDataHelper, transformHelper, containerPath, pyValue(index), pyValue(columns), pyValue(values), aggfunc, outputFormat)
+}
+
+// CrosstabScript generates a script that builds a frequency table of the index
+// columns against the columns (pd.crosstab) and optionally runs a chi-square
+// test of independence on the raw counts.
+func CrosstabScript(containerPath string, index, columns []string, normalize string, margins, chiSquare bool, outputFormat string) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s%s
+file_path = %q
+index = %s or []
+columns = %s or []
+normalize = %q
+margins = %s
+chi_square = %s
+output_format = %q
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+missing = [c for c in index + columns if c not in df.columns]
+if missing:
+    print(f"Error: Column(s) not found: {missing}. Available: {list(df.columns)}", file=sys.stderr)
+    sys.exit(1)
+
+row_keys = [df[c] for c in index]
+col_keys = [df[c] for c in columns]
+
+try:
+    counts = pd.crosstab(row_keys, col_keys)
+    table = pd.crosstab(row_keys, col_keys, normalize=(normalize or False), margins=margins)
+except Exception as e:
+    print(f"Error building crosstab: {e}", file=sys.stderr)
+    sys.exit(1)
+
+label = f"{', '.join(index)} × {', '.join(columns)}"
+if normalize:
+    label += f", normalize={normalize}"
+print(f"=== Crosstab ({label}) ===")
+if len(table) > 50:
+    print(f"(Showing first 50 of {len(table)} rows)")
+    print(table.head(50).to_string())
+else:
+    print(table.to_string())
+
+# Save a plain table: flattened columns with the index keys as columns
+flat = flatten_columns(table).reset_index()
+try:
+    output_file = save_frame(flat, 'crosstab', output_format)
+    print(f"\nOutput saved to: {output_file}")
+except Exception as e:
+    print(f"Error saving output: {e}", file=sys.stderr)
+    sys.exit(1)
+
+result = {
+    "shape": {"rows": table.shape[0], "columns": table.shape[1]},
+    "total": int(counts.values.sum()),
+    "output_file": output_file,
+}
+
+if chi_square:
+    # The test always runs on raw counts, without margins or normalization
+    if counts.shape[0] < 2 or counts.shape[1] < 2:
+        print("\nChi-square test skipped: need at least 2 rows and 2 columns of categories")
+        result["chi_square"] = None
+    else:
+        from scipy.stats import chi2_contingency
+        chi2, p_value, dof, expected = chi2_contingency(counts.values)
+        low_expected = int((expected < 5).sum())
+        print("\n=== Chi-square Test of Independence ===")
+        print(f"  chi2:    {chi2:.4f}")
+        print(f"  p-value: {p_value:.4g}")
+        print(f"  dof:     {dof}")
+        if low_expected:
+            print(f"  Warning: {low_expected} of {expected.size} cells have expected counts below 5; the test may be unreliable")
+        result["chi_square"] = {
+            "statistic": float(chi2),
+            "p_value": float(p_value),
+            "dof": int(dof),
+            "low_expected_cells": low_expected,
+        }
+
+emit_result(result)
+`, emitResultHelper, readDataHelper, transformHelper, containerPath, pyValue(index), pyValue(columns), normalize, pyValue(margins), pyValue(chiSquare), outputFormat)
+}
+
+// ColumnCardinalityScript generates a script that reports distinct counts per
+// column and flags likely identifier and categorical columns.
+func ColumnCardinalityScript(containerPath string, columns []string) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+columns = %s
+
+# Columns whose unique ratio is at least this are flagged as likely identifiers
+IDENTIFIER_RATIO = 0.95
+# Columns with at most this many distinct values (or a very low unique ratio) are flagged as likely categoricals
+CATEGORICAL_MAX_UNIQUE = 20
+CATEGORICAL_MAX_RATIO = 0.05
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+if columns:
+    missing = [c for c in columns if c not in df.columns]
+    if missing:
+        print(f"Error: Column(s) not found: {missing}. Available: {list(df.columns)}", file=sys.stderr)
+        sys.exit(1)
+    df = df[columns]
+
+total_rows = len(df)
+report = []
+for col in df.columns:
+    nunique = int(df[col].nunique(dropna=True))
+    non_null = int(df[col].notna().sum())
+    ratio = (nunique / non_null) if non_null > 0 else 0.0
+    if nunique <= 1:
+        kind = "constant"
+    elif ratio >= IDENTIFIER_RATIO and non_null > CATEGORICAL_MAX_UNIQUE:
+        kind = "identifier"
+    elif nunique <= CATEGORICAL_MAX_UNIQUE or ratio <= CATEGORICAL_MAX_RATIO:
+        kind = "categorical"
+    else:
+        kind = "high_cardinality"
+    report.append({
+        "column": str(col),
+        "dtype": str(df[col].dtype),
+        "nunique": nunique,
+        "non_null": non_null,
+        "unique_ratio": round(ratio, 4),
+        "likely": kind,
+    })
+
+print(f"=== Column Cardinality ({total_rows:,} rows) ===")
+name_width = max([len(r["column"]) for r in report] + [6])
+print(f"  {'column':<{name_width}}  {'nunique':>10}  {'ratio':>7}  likely")
+for r in report:
+    print(f"  {r['column']:<{name_width}}  {r['nunique']:>10,}  {r['unique_ratio']:>7.3f}  {r['likely']}")
+
+result = {"rows": total_rows, "columns": report}
+print()
+print("=== JSON Output ===")
+print(json.dumps(result, default=str))
+emit_result(result)
+`, emitResultHelper, readDataHelper, containerPath, pyValue(columns))
+}
+
+// InferTypesScript generates a script that samples each column and guesses its
+// semantic type (datetime, numeric-as-string, boolean, identifier, ...) with a
+// confidence and a suggested conversion.
+func InferTypesScript(containerPath string, columns []string, sampleSize int) string {
+	header := fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import re
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+columns = %s
+SAMPLE_SIZE = %d
+`, emitResultHelper, readDataHelper, containerPath, pyValue(columns), sampleSize)
+
+	// The body is not passed through Sprintf, so strftime formats and regexes
+	// can use '%' freely.
+	return header + inferTypesBody
+}
+
+// inferTypesBody is the column classification logic for InferTypesScript.
+const inferTypesBody = `
+# Minimum fraction of sampled values that must match for a type to be chosen
+MATCH_THRESHOLD = 0.9
+IDENTIFIER_RATIO = 0.95
+CATEGORICAL_MAX_UNIQUE = 20
+
+BOOL_VALUES = {'true', 'false', 'yes', 'no', 'y', 'n', 't', 'f', '0', '1'}
+EMAIL_RE = r'^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$'
+URL_RE = r'^(?:https?|ftp)://\S+$'
+CURRENCY_RE = r'^[-+]?\s*[$€£¥]\s*[-+]?[\d,]*\.?\d+$|^[-+]?[\d,]*\.?\d+\s*[$€£¥]$'
+PERCENT_RE = r'^[-+]?\d*\.?\d+\s*%$'
+NUMERIC_RE = r'^[-+]?(?:\d{1,3}(?:,\d{3})+|\d+)?(?:\.\d+)?(?:[eE][-+]?\d+)?$'
+DATE_FORMATS = [
+    '%Y-%m-%d', '%Y-%m-%d %H:%M:%S', '%Y-%m-%dT%H:%M:%S', '%Y/%m/%d',
+    '%d/%m/%Y', '%m/%d/%Y', '%d-%m-%Y', '%d.%m.%Y', '%Y%m%d',
+]
+
+def _frac(mask):
+    return float(mask.mean()) if len(mask) else 0.0
+
+def _result(semantic_type, confidence, suggestion, operation=None):
+    return {
+        "semantic_type": semantic_type,
+        "confidence": round(confidence, 3),
+        "suggestion": suggestion,
+        "suggested_operation": operation,
+    }
+
+def infer_column(col, series):
+    non_null = series.dropna()
+    if len(non_null) == 0:
+        return _result("empty", 1.0, "Column is entirely null; consider dropping it.",
+                       {"type": "drop", "columns": [col]})
+
+    sample = non_null.sample(SAMPLE_SIZE, random_state=0) if len(non_null) > SAMPLE_SIZE else non_null
+    nunique = non_null.nunique()
+    unique_ratio = nunique / len(non_null)
+
+    # Native dtypes first
+    if pd.api.types.is_bool_dtype(series):
+        return _result("boolean", 1.0, "Already boolean.")
+    if pd.api.types.is_datetime64_any_dtype(series):
+        return _result("datetime", 1.0, "Already datetime.")
+    if pd.api.types.is_numeric_dtype(series):
+        if set(non_null.unique()) <= {0, 1}:
+            return _result("boolean", 1.0, "Numeric 0/1 flag; can be stored as bool.",
+                           {"type": "astype", "column": col, "dtype": "bool"})
+        if pd.api.types.is_integer_dtype(series) and unique_ratio >= IDENTIFIER_RATIO and len(non_null) > CATEGORICAL_MAX_UNIQUE:
+            return _result("identifier", unique_ratio, "Unique integers; likely an ID. Read as string to preserve formatting.",
+                           {"type": "astype", "column": col, "dtype": "str"})
+        return _result("numeric", 1.0, "Already numeric.")
+
+    values = sample.astype(str).str.strip()
+    lower = values.str.lower()
+
+    if lower.nunique() <= 2 and _frac(lower.isin(BOOL_VALUES)) >= MATCH_THRESHOLD:
+        return _result("boolean", _frac(lower.isin(BOOL_VALUES)),
+                       f"Boolean-as-string ({sorted(lower.unique().tolist())}); map to True/False.")
+
+    for name, pattern, suggestion in [
+        ("email", EMAIL_RE, "Email addresses; keep as string."),
+        ("url", URL_RE, "URLs; keep as string."),
+        ("currency", CURRENCY_RE, "Currency amounts; strip symbols and separators, then pd.to_numeric()."),
+        ("percentage", PERCENT_RE, "Percentages; strip '%' and divide by 100 for fractions."),
+    ]:
+        conf = _frac(values.str.match(pattern))
+        if conf >= MATCH_THRESHOLD:
+            return _result(name, conf, suggestion)
+
+    conf = _frac(values.str.match(NUMERIC_RE) & (values != ''))
+    if conf >= MATCH_THRESHOLD:
+        has_commas = bool(values.str.contains(',').any())
+        if has_commas:
+            return _result("numeric_as_string", conf, "Numbers with thousands separators; remove ',' then pd.to_numeric().")
+        return _result("numeric_as_string", conf, "Numbers stored as text; convert with pd.to_numeric().",
+                       {"type": "astype", "column": col, "dtype": "float"})
+
+    best_format, best_conf = None, 0.0
+    for fmt in DATE_FORMATS:
+        conf = _frac(pd.to_datetime(values, format=fmt, errors='coerce').notna())
+        if conf > best_conf:
+            best_format, best_conf = fmt, conf
+    if best_conf >= MATCH_THRESHOLD:
+        return _result("datetime", best_conf, f"Date looks parseable with format {best_format}: pd.to_datetime(df[col], format='{best_format}').")
+    conf = _frac(pd.to_datetime(values, errors='coerce').notna())
+    if conf >= MATCH_THRESHOLD:
+        return _result("datetime", conf, "Dates in a mixed or non-standard format; pd.to_datetime() can parse them.")
+
+    if unique_ratio >= IDENTIFIER_RATIO and len(non_null) > CATEGORICAL_MAX_UNIQUE and values.str.len().mean() <= 40 and not values.str.contains(' ').any():
+        return _result("identifier", unique_ratio, "Unique short strings; likely an identifier.")
+    if nunique <= CATEGORICAL_MAX_UNIQUE or unique_ratio <= 0.05:
+        return _result("categorical", 1.0 - unique_ratio, f"{nunique} distinct values; store as category.",
+                       {"type": "astype", "column": col, "dtype": "category"})
+    return _result("freetext", _frac(values.str.contains(' ')), "Free-form text.")
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+if columns:
+    missing = [c for c in columns if c not in df.columns]
+    if missing:
+        print(f"Error: Column(s) not found: {missing}. Available: {list(df.columns)}", file=sys.stderr)
+        sys.exit(1)
+    df = df[columns]
+
+report = []
+for col in df.columns:
+    info = infer_column(col, df[col])
+    info = {"column": str(col), "dtype": str(df[col].dtype), **info}
+    report.append(info)
+
+print(f"=== Inferred Column Types ({len(df):,} rows, up to {SAMPLE_SIZE:,} sampled per column) ===")
+for r in report:
+    print(f"  {r['column']}: {r['semantic_type']} (dtype {r['dtype']}, confidence {r['confidence']:.2f})")
+    print(f"      {r['suggestion']}")
+
+operations = [r["suggested_operation"] for r in report if r["suggested_operation"]]
+result = {"columns": report, "suggested_operations": operations}
+print()
+print("=== JSON Output ===")
+print(json.dumps(result, default=str))
+emit_result(result)
+`
+
+// PipelineScript generates a script that applies an ordered list of transform
+// and analysis steps to a single in-memory DataFrame. Steps with type
+// "analyze" run an analyze_data analysis; all other steps are transform_data
+// operations. If outputFormat is non-empty the final frame is saved.
+func PipelineScript(containerPath string, steps []map[string]interface{}, outputFormat string) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import re
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s%s%s
+file_path = %q
+steps = %s
+output_format = %q
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+original_shape = df.shape
+print(f"Original shape: {original_shape[0]} rows × {original_shape[1]} columns")
+print()
+
+step_results = []
+for i, step in enumerate(steps):
+    step_type = step.get('type')
+    try:
+        if step_type == 'analyze':
+            analysis_type = step.get('analysis_type')
+            print(f"--- Step {i+1}: analyze ({analysis_type}) ---")
+            data = run_analysis(df, analysis_type, step.get('columns'), step.get('group_by'), step)
+            step_results.append({"step": i + 1, "type": "analyze", "analysis_type": analysis_type, "data": data})
+        else:
+            print(f"--- Step {i+1}: {step_type} ---")
+            df = apply_operation(df, step)
+            if isinstance(df, dict):
+                raise ValueError("operations that split the frame are only supported by transform_data")
+            step_results.append({"step": i + 1, "type": step_type, "shape": {"rows": df.shape[0], "columns": df.shape[1]}})
+    except Exception as e:
+        print(f"Error in step {i+1} ({step_type}): {e}", file=sys.stderr)
+        sys.exit(1)
+    print()
+
+print(f"Final shape: {df.shape[0]} rows × {df.shape[1]} columns")
+
+output_file = None
+if output_format:
+    try:
+        output_file = save_frame(df, 'pipeline', output_format)
+        print(f"Output saved to: {output_file}")
+    except Exception as e:
+        print(f"Error saving output: {e}", file=sys.stderr)
+        sys.exit(1)
+
+emit_result({
+    "original_shape": {"rows": original_shape[0], "columns": original_shape[1]},
+    "final_shape": {"rows": df.shape[0], "columns": df.shape[1]},
+    "columns": list(df.columns),
+    "steps": step_results,
+    "output_file": output_file,
+})
+`, emitResultHelper, readDataHelper, transformHelper, analysisHelper, containerPath, pyValue(steps), outputFormat)
+}
+
+// MergeAsofScript generates a script that joins each left row to the nearest
+// right row on a sorted key column (pd.merge_asof), optionally matching exact
+// "by" keys first, and saves the merged frame to the output directory.
+func MergeAsofScript(leftPath, rightPath, on string, by []string, direction, tolerance, outputFormat string) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s%s
+left_path = %q
+right_path = %q
+on = %q
+by = %s or []
+direction = %q
+tolerance = %q
+output_format = %q
+
+try:
+    left = read_data(left_path)
+    right = read_data(right_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+for name, frame in [("left", left), ("right", right)]:
+    missing = [c for c in [on] + by if c not in frame.columns]
+    if missing:
+        print(f"Error: Column(s) {missing} not found in {name} file. Available: {list(frame.columns)}", file=sys.stderr)
+        sys.exit(1)
+
+# merge_asof needs matching, non-null, sorted keys. Text keys are parsed as datetimes.
+for name, frame in [("left", left), ("right", right)]:
+    if not (pd.api.types.is_numeric_dtype(frame[on]) or pd.api.types.is_datetime64_any_dtype(frame[on])):
+        try:
+            frame[on] = pd.to_datetime(frame[on])
+        except Exception as e:
+            print(f"Error: '{on}' in {name} file is neither numeric nor parseable as datetime: {e}", file=sys.stderr)
+            sys.exit(1)
+    dropped = int(frame[on].isna().sum())
+    if dropped:
+        print(f"Note: dropping {dropped} {name} row(s) with null '{on}'")
+
+left = left.dropna(subset=[on])
+right = right.dropna(subset=[on])
+for name, frame in [("left", left), ("right", right)]:
+    if not frame[on].is_monotonic_increasing:
+        print(f"Note: sorting {name} file by '{on}'")
+left = left.sort_values(on, kind='mergesort')
+right = right.sort_values(on, kind='mergesort')
+
+tol = None
+if tolerance:
+    try:
+        if pd.api.types.is_datetime64_any_dtype(left[on]):
+            tol = pd.Timedelta(tolerance)
+        else:
+            tol = float(tolerance)
+            if pd.api.types.is_integer_dtype(left[on]) and tol.is_integer():
+                tol = int(tol)
+    except Exception as e:
+        print(f"Error: invalid tolerance '{tolerance}': {e}", file=sys.stderr)
+        sys.exit(1)
+
+try:
+    merged = pd.merge_asof(left, right, on=on, by=by or None, direction=direction, tolerance=tol, suffixes=('', '_right'))
+except Exception as e:
+    print(f"Error during merge_asof: {e}", file=sys.stderr)
+    sys.exit(1)
+
+right_cols = [c for c in merged.columns if c not in left.columns]
+unmatched = int(merged[right_cols].isna().all(axis=1).sum()) if right_cols else 0
+
+print(f"=== merge_asof on '{on}' ({direction}) ===")
+if by:
+    print(f"Exact match on: {by}")
+if tolerance:
+    print(f"Tolerance: {tolerance}")
+print(f"Left rows: {len(left)}, right rows: {len(right)}, merged rows: {len(merged)}")
+print(f"Left rows without a match: {unmatched}")
+
+try:
+    output_file = save_frame(merged, 'merged_asof', output_format)
+    print(f"\nOutput saved to: {output_file}")
+except Exception as e:
+    print(f"Error saving output: {e}", file=sys.stderr)
+    sys.exit(1)
+
+print("\n=== Preview (first 10 rows) ===")
+print(merged.head(10).to_string())
+
+emit_result({
+    "shape": {"rows": merged.shape[0], "columns": merged.shape[1]},
+    "columns": [str(c) for c in merged.columns],
+    "unmatched_rows": unmatched,
+    "output_file": output_file,
+})
+`, emitResultHelper, readDataHelper, transformHelper, leftPath, rightPath, on, pyValue(by), direction, tolerance, outputFormat)
+}
+
+// ValidateSchemaScript generates a script that checks a dataset against a
+// schema of expected columns, dtypes and value constraints and reports
+// pass/fail per rule with offending row counts.
+func ValidateSchemaScript(containerPath string, schema map[string]interface{}) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import re
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+schema = %s
+
+# Number of distinct offending values reported per failed rule
+MAX_EXAMPLES = 5
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+def is_int_like(s):
+    # Integer columns with nulls are read as float; accept them when every value is whole
+    if pd.api.types.is_bool_dtype(s):
+        return False
+    if pd.api.types.is_integer_dtype(s):
+        return True
+    if pd.api.types.is_float_dtype(s):
+        values = s.dropna()
+        return bool(np.all(np.mod(values, 1) == 0))
+    return False
+
+DTYPE_CHECKS = {
+    "int": is_int_like,
+    "integer": is_int_like,
+    "float": lambda s: pd.api.types.is_float_dtype(s) or is_int_like(s),
+    "numeric": lambda s: pd.api.types.is_numeric_dtype(s) and not pd.api.types.is_bool_dtype(s),
+    "string": lambda s: pd.api.types.is_string_dtype(s) or pd.api.types.is_object_dtype(s),
+    "bool": pd.api.types.is_bool_dtype,
+    "boolean": pd.api.types.is_bool_dtype,
+    "datetime": pd.api.types.is_datetime64_any_dtype,
+    "category": lambda s: isinstance(s.dtype, pd.CategoricalDtype),
+}
+
+rules = []
+
+def add_rule(column, rule, mask=None, passed=None, detail=None, series=None):
+    entry = {"column": column, "rule": rule}
+    if mask is not None:
+        failing = int(mask.sum())
+        entry["failing_rows"] = failing
+        passed = failing == 0
+        if failing and series is not None:
+            examples = series[mask].drop_duplicates().head(MAX_EXAMPLES)
+            entry["examples"] = [v.item() if hasattr(v, "item") else v for v in examples]
+    entry["passed"] = bool(passed)
+    if detail is not None:
+        entry["detail"] = detail
+    rules.append(entry)
+
+def bound(s, value):
+    # Compare datetime columns against parsed timestamps
+    if pd.api.types.is_datetime64_any_dtype(s):
+        ts = pd.Timestamp(value)
+        if s.dt.tz is not None and ts.tzinfo is None:
+            ts = ts.tz_localize(s.dt.tz)
+        return ts
+    return value
+
+min_rows = schema.get("min_rows")
+if min_rows is not None:
+    add_rule(None, "min_rows", passed=len(df) >= min_rows, detail=f"{len(df)} rows, expected at least {int(min_rows)}")
+
+columns = schema.get("columns") or {}
+if schema.get("strict"):
+    extra = [str(c) for c in df.columns if str(c) not in columns]
+    add_rule(None, "no_extra_columns", passed=not extra, detail=f"unexpected columns: {extra}" if extra else None)
+
+for name, spec in columns.items():
+    if name not in df.columns:
+        required = spec.get("required", True)
+        add_rule(name, "required", passed=not required, detail="column is missing" if required else "optional column is missing")
+        continue
+    s = df[name]
+    present = s.notna()
+
+    dtype = spec.get("dtype")
+    if dtype:
+        check = DTYPE_CHECKS[dtype.lower()]
+        add_rule(name, "dtype", passed=check(s), detail=f"expected {dtype}, got {s.dtype}")
+
+    if spec.get("nullable") is False:
+        add_rule(name, "not_null", mask=~present)
+
+    for rule, op in (("min", "lt"), ("max", "gt")):
+        if spec.get(rule) is None:
+            continue
+        try:
+            limit = bound(s, spec[rule])
+            mask = present & getattr(s, op)(limit)
+            add_rule(name, rule, mask=mask, series=s, detail=f"{rule} {spec[rule]}")
+        except Exception as e:
+            add_rule(name, rule, passed=False, detail=f"can't compare {s.dtype} values with {spec[rule]!r}: {e}")
+
+    allowed = spec.get("allowed")
+    if allowed is not None:
+        mask = present & ~s.isin(allowed)
+        if mask.any() and not pd.api.types.is_numeric_dtype(s):
+            # Values read as text still match allowed numbers and booleans
+            mask = mask & ~s.astype(str).isin([str(v) for v in allowed])
+        add_rule(name, "allowed", mask=mask, series=s)
+
+    if spec.get("unique"):
+        add_rule(name, "unique", mask=present & s.duplicated(keep=False), series=s)
+
+    pattern = spec.get("pattern")
+    if pattern:
+        try:
+            regex = re.compile(pattern)
+        except re.error as e:
+            add_rule(name, "pattern", passed=False, detail=f"invalid pattern {pattern!r}: {e}")
+        else:
+            mask = present & ~s.astype(str).str.fullmatch(regex).fillna(False).astype(bool)
+            add_rule(name, "pattern", mask=mask, series=s, detail=pattern)
+
+failed = [r for r in rules if not r["passed"]]
+passed = not failed
+
+print(f"=== Schema Validation: {'PASSED' if passed else 'FAILED'} ===")
+print(f"Rows: {len(df):,}  Rules checked: {len(rules)}  Failed: {len(failed)}")
+print()
+for r in rules:
+    status = "PASS" if r["passed"] else "FAIL"
+    target = r["column"] if r["column"] is not None else "(table)"
+    line = f"  [{status}] {target}: {r['rule']}"
+    if r.get("failing_rows"):
+        line += f" - {r['failing_rows']:,} row(s)"
+    if r.get("detail") and not r["passed"]:
+        line += f" ({r['detail']})"
+    if r.get("examples"):
+        line += f" e.g. {r['examples']}"
+    print(line)
+
+result = {
+    "passed": passed,
+    "rows": len(df),
+    "rules_checked": len(rules),
+    "rules_failed": len(failed),
+    "rules": rules,
+}
+print()
+print("=== JSON Output ===")
+print(json.dumps(result, default=str))
+emit_result(result)
+`, emitResultHelper, readDataHelper, containerPath, pyValue(schema))
+}
+
+// PeekScript generates a script that reads only the first rows of a file to
+// report its columns, inferred dtypes and a preview without a full load.
+func PeekScript(containerPath string, rows int) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+rows = %d
+
+file_size = os.path.getsize(file_path)
+ext = detect_format(file_path)
+total_rows = None
+estimated_rows = None
+full_read = False
+
+try:
+    if ext == '.parquet':
+        # Row count comes from the footer metadata; only the first batch is decoded
+        import pyarrow.parquet as pq
+        pf = pq.ParquetFile(file_path)
+        total_rows = pf.metadata.num_rows
+        batch = next(pf.iter_batches(batch_size=rows), None)
+        df = batch.to_pandas() if batch is not None else pf.schema_arrow.empty_table().to_pandas()
+    elif ext in ['.xlsx', '.xls']:
+        df = pd.read_excel(file_path, nrows=rows)
+    elif ext == '.json':
+        try:
+            df = pd.read_json(file_path, lines=True, nrows=rows)
+        except ValueError:
+            # Not JSON Lines; a regular JSON document has to be parsed whole
+            df = pd.read_json(file_path).head(rows)
+            full_read = True
+    elif ext == '.dta':
+        with pd.read_stata(file_path, iterator=True) as reader:
+            df = reader.read(rows)
+    elif ext in ['.sas7bdat', '.xpt']:
+        with pd.read_sas(file_path, format='sas7bdat' if ext == '.sas7bdat' else 'xport', iterator=True) as reader:
+            df = reader.read(rows)
+    elif ext in [e for e in DATA_EXTENSIONS if e != '.csv']:
+        df = read_data(file_path).head(rows)
+        full_read = True
+    else:
+        df = pd.read_csv(file_path, nrows=rows)
+        # Estimate the row count from the average size of the lines read
+        with open(file_path, 'rb') as f:
+            sample = [f.readline() for _ in range(rows + 1)]
+        sample = [line for line in sample if line]
+        if len(df) < rows:
+            total_rows = len(df)
+        elif len(sample) > 1:
+            avg_line = sum(len(line) for line in sample) / len(sample)
+            estimated_rows = max(int(file_size / avg_line) - 1, len(df))
+except Exception as e:
+    # Try the other formats for mislabeled files, reading only the first rows
+    try:
+        df = read_head(file_path, rows, ext)
+        total_rows = len(df) if len(df) < rows else None
+        estimated_rows = None
+    except Exception:
+        print(f"Error reading file: {e}", file=sys.stderr)
+        sys.exit(1)
+
+if full_read:
+    total_rows = None
+
+result = {
+    "file_size_bytes": file_size,
+    "file_size_mb": round(file_size / (1024 * 1024), 2),
+    "rows_read": len(df),
+    "total_rows": total_rows,
+    "estimated_rows": estimated_rows,
+    "full_read": full_read,
+    "columns": [str(c) for c in df.columns],
+    "dtypes": {str(col): str(dtype) for col, dtype in df.dtypes.items()},
+    "preview": df.to_dict(orient='records'),
+}
+
+print("=== Peek ===")
+print(f"File size: {result['file_size_mb']} MB ({file_size:,} bytes)")
+print(f"Rows read: {len(df)}" + (" (format requires a full parse)" if full_read else ""))
+if total_rows is not None:
+    print(f"Total rows: {total_rows:,}")
+elif estimated_rows is not None:
+    print(f"Estimated total rows: ~{estimated_rows:,}")
+print(f"Columns: {df.shape[1]}")
+print()
+print("=== Columns (dtypes inferred from the rows read) ===")
+for col, dtype in result['dtypes'].items():
+    print(f"  {col}: {dtype}")
+print()
+print("=== Preview ===")
+print(df.to_string())
+
+emit_result(result)
+`, emitResultHelper, readDataHelper, containerPath, rows)
+}
+
+// GetSchemaScript generates a script that reports only the column names and
+// dtypes of a file, plus its row count where that needs no extra pass. Text
+// formats are sampled; Parquet schemas come from the file metadata.
+func GetSchemaScript(containerPath string, sampleRows int) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+sample_rows = %d
+
+ext = detect_format(file_path)
+row_count = None
+# How the dtypes were obtained: "metadata", "sample", or "full_read"
+source = "sample"
+
+try:
+    if ext == '.parquet':
+        import pyarrow.parquet as pq
+        metadata = pq.read_metadata(file_path)
+        row_count = metadata.num_rows
+        df = pq.read_schema(file_path).empty_table().to_pandas()
+        source = "metadata"
+    elif ext in ['.xlsx', '.xls']:
+        df = pd.read_excel(file_path, nrows=sample_rows)
+    elif ext == '.json':
+        try:
+            df = pd.read_json(file_path, lines=True, nrows=sample_rows)
+        except ValueError:
+            # Not JSON Lines; a regular JSON document has to be parsed whole
+            df = pd.read_json(file_path)
+            source = "full_read"
+    elif ext == '.dta':
+        with pd.read_stata(file_path, iterator=True) as reader:
+            df = reader.read(sample_rows)
+    elif ext in ['.sas7bdat', '.xpt']:
+        with pd.read_sas(file_path, format='sas7bdat' if ext == '.sas7bdat' else 'xport', iterator=True) as reader:
+            df = reader.read(sample_rows)
+    elif ext in [e for e in DATA_EXTENSIONS if e != '.csv']:
+        df = read_data(file_path)
+        source = "full_read"
+    else:
+        df = pd.read_csv(file_path, nrows=sample_rows)
+except Exception as e:
+    # Try the other formats for mislabeled files, reading only a sample
+    try:
+        df = read_head(file_path, sample_rows, ext)
+        row_count = None
+        source = "sample"
+    except Exception:
+        print(f"Error reading file: {e}", file=sys.stderr)
+        sys.exit(1)
+
+if source == "full_read" or (source == "sample" and len(df) < sample_rows):
+    # The whole file was read, so the count is exact
+    row_count = len(df)
+    if source == "sample":
+        source = "full_read"
+
+result = {
+    "columns": [{"name": str(col), "dtype": str(dtype)} for col, dtype in df.dtypes.items()],
+    "column_count": df.shape[1],
+    "row_count": row_count,
+    "dtype_source": source,
+}
+
+print(f"=== Schema ({df.shape[1]} columns) ===")
+name_width = max([len(c["name"]) for c in result["columns"]] + [6])
+for c in result["columns"]:
+    print(f"  {c['name']:<{name_width}}  {c['dtype']}")
+print()
+if row_count is not None:
+    print(f"Rows: {row_count:,}")
+else:
+    print("Rows: not counted (requires a full read)")
+if source == "sample":
+    print(f"Dtypes inferred from the first {sample_rows:,} rows")
+
+emit_result(result)
+`, emitResultHelper, readDataHelper, containerPath, sampleRows)
+}
+
+// EstimateImportanceScript generates a script that trains a random forest to
+// predict target from features (all other columns when empty) and ranks the
+// features by importance. task is "classification", "regression", or "auto"
+// to decide from the target; at most maxRows rows are sampled for training.
+func EstimateImportanceScript(containerPath, target string, features []string, task string, maxRows int) string {
+	return fmt.Sprintf(`#!/usr/bin/env python3
+import sys
+import os
+import json
+import pandas as pd
+import numpy as np
+
+# Suppress warnings
+import warnings
+warnings.filterwarnings('ignore')
+%s%s
+file_path = %q
+target = %q
+features = %s
+task = %q
+MAX_ROWS = %d
+# Integer targets with at most this many distinct values are classes
+MAX_CLASS_VALUES = 20
+# Text features with more distinct values than this are encoded only when
+# they aren't identifiers (unique in most rows)
+MAX_CATEGORIES = 50
+RANDOM_STATE = 0
+
+try:
+    df = read_data(file_path)
+except Exception as e:
+    print(f"Error reading file: {e}", file=sys.stderr)
+    sys.exit(1)
+
+if target not in df.columns:
+    print(f"Error: target column '{target}' not found. Available: {list(df.columns)}", file=sys.stderr)
+    sys.exit(1)
+features = features or [c for c in df.columns if c != target]
+missing = [c for c in features if c not in df.columns]
+if missing:
+    print(f"Error: feature column(s) not found: {missing}. Available: {list(df.columns)}", file=sys.stderr)
+    sys.exit(1)
+features = [c for c in features if c != target]
+if not features:
+    print("Error: no feature columns besides the target", file=sys.stderr)
+    sys.exit(1)
+
+df = df[df[target].notna()]
+total_rows = len(df)
+if total_rows < 10:
+    print(f"Error: need at least 10 rows with a target value, found {total_rows}", file=sys.stderr)
+    sys.exit(1)
+sampled = total_rows > MAX_ROWS
+if sampled:
+    df = df.sample(n=MAX_ROWS, random_state=RANDOM_STATE)
+
+y = df[target]
+if task == 'auto':
+    is_class = (not pd.api.types.is_numeric_dtype(y) or pd.api.types.is_bool_dtype(y)
+                or (pd.api.types.is_integer_dtype(y) and y.nunique() <= MAX_CLASS_VALUES))
+    task = 'classification' if is_class else 'regression'
+if task == 'regression':
+    if not pd.api.types.is_numeric_dtype(y) or pd.api.types.is_bool_dtype(y):
+        print(f"Error: regression needs a numeric target; '{target}' is {y.dtype}", file=sys.stderr)
+        sys.exit(1)
+    y = y.astype(float)
+else:
+    y = y.astype(str)
+    if y.nunique() < 2:
+        print(f"Error: target '{target}' has a single class", file=sys.stderr)
+        sys.exit(1)
+
+# Encode features: numbers as-is with missing values at the median, datetimes
+# as timestamps, and text, categories and booleans as category codes
+X = pd.DataFrame(index=df.index)
+encoding = {}
+dropped = {}
+for col in features:
+    s = df[col]
+    if pd.api.types.is_bool_dtype(s):
+        X[col] = s.astype(float)
+        encoding[str(col)] = "boolean"
+    elif pd.api.types.is_numeric_dtype(s):
+        X[col] = s.fillna(s.median()) if s.notna().any() else 0.0
+        encoding[str(col)] = "numeric"
+    elif pd.api.types.is_datetime64_any_dtype(s):
+        # NaT can't be cast to int64, so only the present values are converted
+        stamps = s.dropna().astype('int64').reindex(s.index)
+        X[col] = stamps.fillna(stamps.median()) if s.notna().any() else 0.0
+        encoding[str(col)] = "datetime"
+    else:
+        nunique = s.nunique()
+        if nunique < 2:
+            dropped[str(col)] = "constant"
+            continue
+        if nunique > MAX_CATEGORIES and nunique > 0.5 * len(s):
+            dropped[str(col)] = f"identifier-like ({nunique} distinct values)"
+            continue
+        X[col] = s.astype(str).where(s.notna()).astype('category').cat.codes
+        encoding[str(col)] = "category codes"
+if X.shape[1] == 0:
+    print("Error: no usable feature columns: " + ", ".join(f"{c} ({why})" for c, why in dropped.items()), file=sys.stderr)
+    sys.exit(1)
+
+from sklearn.ensemble import RandomForestClassifier, RandomForestRegressor
+from sklearn.model_selection import train_test_split
+
+Model = RandomForestClassifier if task == 'classification' else RandomForestRegressor
+X_train, X_test, y_train, y_test = train_test_split(X, y, test_size=0.25, random_state=RANDOM_STATE)
+# One job: the CPU count seen in the container is the host's, not its limit
+model = Model(n_estimators=100, random_state=RANDOM_STATE, n_jobs=1)
+model.fit(X_train, y_train)
+score = float(model.score(X_test, y_test))
+metric = "accuracy" if task == 'classification' else "r2"
+
+ranked = sorted(zip(X.columns, model.feature_importances_), key=lambda item: -item[1])
+importances = [
+    {"rank": i + 1, "feature": str(col), "importance": round(float(value), 6), "encoding": encoding[str(col)]}
+    for i, (col, value) in enumerate(ranked)
+]
+
+print(f"=== Feature Importance: {target} ({task}) ===")
+print(f"Random forest trained on {len(X_train):,} rows, scored on {len(X_test):,}" + (f" (sampled from {total_rows:,})" if sampled else ""))
+print(f"Holdout {metric}: {score:.3f}")
+print()
+name_width = max([len(r["feature"]) for r in importances] + [7])
+for r in importances:
+    bar = '#' * int(round(r["importance"] * 40))
+    print(f"  {r['rank']:>3}. {r['feature']:<{name_width}}  {r['importance']:.4f}  {bar}")
+if dropped:
+    print()
+    print("Not used: " + ", ".join(f"{c} ({why})" for c, why in dropped.items()))
+print()
+print("Impurity-based importances favour high-cardinality features; confirm with domain knowledge before acting on them.")
+
+result = {
+    "target": str(target),
+    "task": task,
+    "rows": total_rows,
+    "training_rows": len(X_train),
+    "sampled": sampled,
+    "score": {metric: round(score, 6)},
+    "importances": importances,
+}
+if dropped:
+    result["dropped_features"] = dropped
+emit_result(result)
+`, emitResultHelper, readDataHelper, containerPath, target, pyValue(features), task, maxRows)
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// requirePandas skips the test unless python3 with pandas is available to
+// run the generated scripts.
+func requirePandas(t *testing.T) string {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+	if err := exec.Command(python, "-c", "import pandas").Run(); err != nil {
+		t.Skip("pandas is not installed")
+	}
+	return python
+}
+
+func TestEmptyFileErrorMessages(t *testing.T) {
+	python := requirePandas(t)
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		options ReadOptions
+		want    string // Line printed by the script below
+	}{
+		{"zero-byte", "", ReadOptions{}, "EmptyFileError: zero-byte.csv: file is empty"},
+		{"zero-byte allowed", "", ReadOptions{AllowEmpty: true}, "EmptyFileError: zero-byte allowed.csv: file is empty"},
+		{"header-only", "a,b\n", ReadOptions{}, "EmptyFileError: header-only.csv: file has headers but no rows (columns: a, b); set allow_empty to read it as an empty frame"},
+		{"header-only allowed", "a,b\n", ReadOptions{AllowEmpty: true}, "rows=0 columns=a,b"},
+		{"data", "a,b\n1,2\n", ReadOptions{}, "rows=1 columns=a,b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			script := "import os, sys, json\nimport pandas as pd\n" + readDataHelper + fmt.Sprintf(`
+try:
+    df = read_data(%q, %s)
+    print(f"rows={len(df)} columns={','.join(map(str, df.columns))}")
+except EmptyFileError as e:
+    print(f"EmptyFileError: {e}")
+`, path, pyValue(tt.options))
+			out, err := exec.Command(python, "-c", script).CombinedOutput()
+			if err != nil {
+				t.Fatalf("script failed: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowEmptyPassthrough(t *testing.T) {
+	scripts := []struct {
+		name   string
+		script func(ReadOptions) string
+	}{
+		{"read_dataframe", func(o ReadOptions) string { return ReadDataFrameScript("/data/f.csv", 5, 20, o) }},
+		{"analyze_data", func(o ReadOptions) string {
+			return AnalyzeDataScript("/data/f.csv", "describe", nil, "", AnalysisParams{}, o)
+		}},
+		{"transform_data", func(o ReadOptions) string {
+			return TransformDataScript("/data/f.csv", nil, TransformOptions{OutputFormat: "csv", OutputName: "out"}, o)
+		}},
+	}
+	const allowEmpty = `\"allow_empty\":true`
+	for _, s := range scripts {
+		for _, allow := range []bool{false, true} {
+			script := s.script(ReadOptions{AllowEmpty: allow})
+			if got := strings.Contains(script, allowEmpty); got != allow {
+				t.Errorf("%s with allow_empty=%v: read options passed allow_empty=%v", s.name, allow, got)
+			}
+			if !strings.Contains(script, "class EmptyFileError(ValueError):") {
+				t.Errorf("%s: script does not include read_data", s.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExtensions lists the input archives ExecOptions.ExtractArchives
+// unpacks.
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// errExtractLimit is returned once an archive extracts to more than the
+// executor's maximum extracted size.
+var errExtractLimit = errors.New("extracted size limit exceeded")
+
+// IsArchive reports whether path names an archive ExtractArchives unpacks.
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveMountPath returns where the members of the i-th input file are
+// mounted when it is an extracted archive.
+func ArchiveMountPath(i int) string {
+	return fmt.Sprintf("/data/extracted_%d", i)
+}
+
+// SetMaxExtractSize limits the total bytes extracted from the archive inputs
+// of one execution (0 = no limit).
+func (e *DockerExecutor) SetMaxExtractSize(bytes int64) {
+	e.maxExtractBytes = bytes
+}
+
+// extractInputs unpacks the archive inputs into extracted_{i} directories
+// under tempDir and returns those directories keyed by input index.
+func (e *DockerExecutor) extractInputs(files []string, tempDir string) (map[int]string, error) {
+	dirs := make(map[int]string)
+	budget := e.maxExtractBytes
+	for i, f := range files {
+		if !IsArchive(f) {
+			continue
+		}
+		dir := filepath.Join(tempDir, fmt.Sprintf("extracted_%d", i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+		}
+		written, err := extractArchive(f, dir, budget)
+		if errors.Is(err, errExtractLimit) {
+			return nil, fmt.Errorf("%s: archive inputs extract to more than %d MB (MAX_EXTRACT_MB)", filepath.Base(f), e.maxExtractBytes>>20)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive %s: %w", filepath.Base(f), err)
+		}
+		if budget > 0 {
+			budget -= written
+		}
+		dirs[i] = dir
+	}
+	return dirs, nil
+}
+
+// extractArchive unpacks the zip or tar archive src into dir and returns the
+// bytes written. Only regular files are extracted; links and members whose
+// path leaves dir are rejected, and more than limit bytes (0 = no limit)
+// fail with errExtractLimit.
+func extractArchive(src, dir string, limit int64) (int64, error) {
+	var written int64
+	write := func(name string, r io.Reader) error {
+		n, err := extractMember(dir, name, r, limit-written, limit > 0)
+		written += n
+		return err
+	}
+
+	if strings.HasSuffix(strings.ToLower(src), ".zip") {
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer zr.Close()
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			if !zf.Mode().IsRegular() {
+				return written, fmt.Errorf("member %q is not a regular file", zf.Name)
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return written, fmt.Errorf("failed to read member %q: %w", zf.Name, err)
+			}
+			err = write(zf.Name, rc)
+			rc.Close()
+			if err != nil {
+				return written, err
+			}
+		}
+		return written, nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if lower := strings.ToLower(src); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read tar: %w", err)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeReg:
+			if err := write(header.Name, tr); err != nil {
+				return written, err
+			}
+		default:
+			return written, fmt.Errorf("member %q is not a regular file", header.Name)
+		}
+	}
+}
+
+// extractMember writes one archive member below dir. With limited, writing
+// more than remaining bytes fails with errExtractLimit.
+func extractMember(dir, name string, r io.Reader, remaining int64, limited bool) (int64, error) {
+	rel := filepath.FromSlash(strings.TrimPrefix(name, "./"))
+	if !filepath.IsLocal(rel) {
+		// Zip slip: "../x", "/etc/x" and the like
+		return 0, fmt.Errorf("member %q would be extracted outside the archive", name)
+	}
+	target := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract member %q: %w", name, err)
+	}
+	if limited {
+		r = io.LimitReader(r, remaining+1)
+	}
+	n, err := io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to extract member %q: %w", name, err)
+	}
+	if limited && n > remaining {
+		return n, errExtractLimit
+	}
+	return n, nil
+}
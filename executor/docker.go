@@ -10,20 +10,25 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
@@ -59,6 +64,48 @@ func getDockerfileContent() ([]byte, error) {
 	return nil, fmt.Errorf("%s not found. Please ensure %s exists in the current directory or alongside the executable. Searched: %v", dockerfileName, dockerfileName, searchPaths)
 }
 
+// OutputExporter publishes an execution's output files so that clients can
+// pass them to later tool calls as inputs.
+type OutputExporter interface {
+	// ExportOutput copies the file at path and returns a reference to it.
+	ExportOutput(filename, path string) (string, error)
+}
+
+// ExecOptions holds per-execution settings and the provenance recorded with
+// persisted outputs.
+type ExecOptions struct {
+	Timeout  time.Duration // Execution timeout (0 = executor default)
+	ToolName string        // Tool that requested the execution
+	Inputs   []string      // Input file references as passed by the client
+	Image    string        // Named image to run in ("" or DefaultImageName = primary image)
+
+	// Network policy from SetNetworkPolicies to attach to ("" = the
+	// executor's network mode)
+	NetworkPolicy string
+
+	// Command-line arguments for the script, passed after its path so they
+	// appear in sys.argv[1:]
+	Args []string
+
+	// Data piped to the script's stdin (unset leaves stdin empty)
+	Stdin string
+
+	// Mount inputs at /data/{basename} where the name is unique; see
+	// InputMountPaths
+	MountByName bool
+
+	// Unpack .zip and .tar(.gz) inputs and mount their members read-only
+	// at ArchiveMountPath(i)
+	ExtractArchives bool
+
+	// Arguments of the tool call, stored with persisted outputs for rerun
+	Arguments map[string]any
+
+	// ID to run under ("" = generate one), so callers can refer to the run
+	// even when it fails before producing a result
+	ExecutionID string
+}
+
 // ExecutionResult holds the result of a script execution.
 type ExecutionResult struct {
 	ExecutionID string // Unique ID for this execution
@@ -67,13 +114,21 @@ type ExecutionResult struct {
 	ExitCode    int
 	Duration    time.Duration
 	Error       string
-	OutputFiles []string // List of files saved to output dir
-	OutputPath  string   // Path to execution output directory
+	OutputFiles []string          // List of files saved to output dir
+	Discarded   []string          // Files saved while output persistence is off (no OUTPUT_DIR); removed after the run
+	OutputPath  string            // Path to execution output directory
+	OutputRefs  map[string]string // Output filename -> reference from the OutputExporter (e.g. upload://id)
+	Checksums   map[string]string // Output filename -> hex sha256, recorded in the execution metadata
+	Result      any               // Structured result written by emit_result(), if any
+	Warnings    []string          // Non-fatal problems encountered while collecting results
+	OOMKilled   bool              // Container was killed for exceeding its memory limit
+	TimedOut    bool              // Container was killed for exceeding the execution timeout
+
+	// Resource usage sampled from Docker stats (0 if no sample was taken)
+	MemoryPeakBytes uint64  // Highest container memory usage observed
+	CPUSeconds      float64 // CPU time used across all cores; compare with Duration to spot I/O-bound runs
 }
 
-// ErrImageNotReady is returned when the Docker image is still being built.
-var ErrImageNotReady = fmt.Errorf("Docker image is still being built. Please try again in a minute")
-
 // DockerExecutor manages Docker containers for script execution.
 type DockerExecutor struct {
 	client           *client.Client
@@ -81,18 +136,34 @@ type DockerExecutor struct {
 	memoryLimit      int64 // in bytes
 	cpuLimit         float64
 	networkDisabled  bool
-	executionTimeout time.Duration
+	networkMode      string        // HostConfig.NetworkMode; empty leaves Docker's default
+	executionTimeout time.Duration // Guarded by imageReadyMu; see SetExecutionTimeout
 	buildLocal       bool          // Force local build instead of pulling
 	tempDir          string        // Temp directory for scripts (must be accessible to Docker daemon)
 	outputDir        string        // Output directory for pandas script outputs (writable)
 	outputTTL        time.Duration // TTL for output cleanup
 	outputManager    *OutputManager
-	chartThemeFile   string // Optional Python file with matplotlib chart theme
+	outputExporter   OutputExporter // Optional, publishes outputs for reuse as inputs
+	chartThemeFile   string         // Optional Python file with matplotlib chart theme
+	sharedDataDir    string         // Optional host directory mounted read-only at SharedDataPath
+	containerUser    string         // Optional "UID[:GID]" scripts run as (default: the image's USER)
+	owner            *containerOwner
+	defaultFormat    string // Table format used when a tool call doesn't specify one
+	warnMixedTypes   bool   // Report mixed-type columns instead of silencing them
+	maxExtractBytes  int64  // Limit on bytes extracted from one execution's archive inputs
 
 	// Image readiness tracking
 	imageReady    bool
 	imageBuildErr error
 	imageReadyMu  sync.RWMutex
+	images        map[string]*imageState     // Additional named images, guarded by imageReadyMu
+	profiles      map[string]ResourceProfile // Per-image resource limits, guarded by imageReadyMu
+
+	networkPolicies map[string]NetworkPolicy // Named restricted networks, guarded by imageReadyMu
+
+	// In-flight executions, keyed by execution ID
+	running   map[string]RunningExecution
+	runningMu sync.Mutex
 }
 
 // commonDockerSockets lists common Docker socket locations to try.
@@ -220,6 +291,103 @@ func (e *DockerExecutor) Close() error {
 	return e.client.Close()
 }
 
+// SetOutputExporter sets the exporter used to publish output files after each
+// execution. Pass nil to disable exporting.
+func (e *DockerExecutor) SetOutputExporter(x OutputExporter) {
+	e.outputExporter = x
+}
+
+// OutputFormats lists the table formats tools can write.
+var OutputFormats = []string{"csv", "json", "parquet"}
+
+// SetDefaultOutputFormat sets the table format tools and save_output use when
+// none is specified. It must be one of OutputFormats.
+func (e *DockerExecutor) SetDefaultOutputFormat(format string) error {
+	if !slices.Contains(OutputFormats, format) {
+		return fmt.Errorf("unsupported output format %q (use %s)", format, strings.Join(OutputFormats, ", "))
+	}
+	e.defaultFormat = format
+	return nil
+}
+
+// SetWarnMixedTypes makes scripts report columns read with mixed value types,
+// and run_pandas_script show pandas' DtypeWarning, instead of silencing them.
+// The warn_mixed_types read option overrides it per call.
+func (e *DockerExecutor) SetWarnMixedTypes(on bool) {
+	e.warnMixedTypes = on
+}
+
+// DefaultOutputFormat returns the default table output format.
+func (e *DockerExecutor) DefaultOutputFormat() string {
+	if e.defaultFormat == "" {
+		return "csv"
+	}
+	return e.defaultFormat
+}
+
+// SetSharedDataDir mounts dir read-only at SharedDataPath in every container.
+// The directory must exist. Pass "" to disable the shared mount.
+func (e *DockerExecutor) SetSharedDataDir(dir string) error {
+	if dir == "" {
+		e.sharedDataDir = ""
+		return nil
+	}
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("shared data directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("shared data directory %s is not a directory", absPath)
+	}
+	e.sharedDataDir = absPath
+	return nil
+}
+
+// SetNetworkMode attaches script containers to mode: "none", "bridge", or the
+// name of an existing Docker network. Host and container-shared networking are
+// rejected since they bypass isolation.
+func (e *DockerExecutor) SetNetworkMode(ctx context.Context, mode string) error {
+	switch {
+	case mode == "", mode == "none", mode == "bridge":
+	case mode == "host", strings.HasPrefix(mode, "container:"):
+		return fmt.Errorf("network mode %q is not allowed", mode)
+	default:
+		if _, err := e.client.NetworkInspect(ctx, mode, network.InspectOptions{}); err != nil {
+			return fmt.Errorf("network %q not found: %w", mode, err)
+		}
+	}
+	e.networkMode = mode
+	e.networkDisabled = mode == "none"
+	return nil
+}
+
+// SetContainerUser runs script containers as user ("UID" or "UID:GID") and
+// creates output directories owned by it. Pass "" to use the image's USER.
+func (e *DockerExecutor) SetContainerUser(user string) error {
+	var owner *containerOwner
+	if user != "" {
+		var err error
+		if owner, err = parseContainerUser(user); err != nil {
+			return err
+		}
+	}
+	e.containerUser = user
+	e.owner = owner
+	if e.outputManager != nil {
+		e.outputManager.SetOwner(owner)
+	}
+	return nil
+}
+
+// SharedDataDir returns the host directory mounted at SharedDataPath, if any.
+func (e *DockerExecutor) SharedDataDir() string {
+	return e.sharedDataDir
+}
+
 // GetOutputManager returns the output manager for this executor.
 func (e *DockerExecutor) GetOutputManager() *OutputManager {
 	return e.outputManager
@@ -255,6 +423,8 @@ func (e *DockerExecutor) StartOutputCleanup(interval time.Duration) {
 // If BuildLocal is true, it builds from CutePandas.Dockerfile instead.
 // Returns immediately. Use IsImageReady() to check status.
 func (e *DockerExecutor) EnsureImageAsync(ctx context.Context) {
+	e.ensureExtraImagesAsync(ctx)
+
 	// Check if image exists locally
 	_, _, err := e.client.ImageInspectWithRaw(ctx, e.image)
 	if err == nil {
@@ -284,7 +454,7 @@ func (e *DockerExecutor) EnsureImageAsync(ctx context.Context) {
 			}
 		} else {
 			// Pull from registry (default behavior)
-			if err := e.pullImage(bgCtx); err != nil {
+			if err := e.pullImage(bgCtx, e.image); err != nil {
 				log.Printf("Failed to pull image: %v", err)
 				// Fallback to local build if pull fails
 				log.Printf("Attempting to build image locally as fallback...")
@@ -307,12 +477,12 @@ func (e *DockerExecutor) EnsureImageAsync(ctx context.Context) {
 }
 
 // pullImage pulls a Docker image from the registry.
-func (e *DockerExecutor) pullImage(ctx context.Context) error {
-	log.Printf("Pulling Docker image %s...", e.image)
+func (e *DockerExecutor) pullImage(ctx context.Context, ref string) error {
+	log.Printf("Pulling Docker image %s...", ref)
 
-	reader, err := e.client.ImagePull(ctx, e.image, image.PullOptions{})
+	reader, err := e.client.ImagePull(ctx, ref, image.PullOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to pull image %s: %w", e.image, err)
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
 	}
 	defer reader.Close()
 
@@ -346,7 +516,7 @@ func (e *DockerExecutor) pullImage(ctx context.Context) error {
 		}
 	}
 
-	log.Printf("Successfully pulled image %s", e.image)
+	log.Printf("Successfully pulled image %s", ref)
 	return nil
 }
 
@@ -508,14 +678,13 @@ func (e *DockerExecutor) createAccessibleTempDir() (string, error) {
 	}
 
 	// Default: use user's cache directory
-	homeDir, err := os.UserHomeDir()
+	cacheDir, err := defaultTempCacheDir()
 	if err != nil {
 		// Fallback to system temp if we can't get home dir
 		return os.MkdirTemp("", "pandas-exec-*")
 	}
 
 	// Create base cache directory
-	cacheDir := filepath.Join(homeDir, ".cache", "cute-pandas", "tmp")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		// Fallback to system temp
 		return os.MkdirTemp("", "pandas-exec-*")
@@ -525,54 +694,134 @@ func (e *DockerExecutor) createAccessibleTempDir() (string, error) {
 	return os.MkdirTemp(cacheDir, "exec-*")
 }
 
+// defaultTempCacheDir is the temp base used when no temp dir is configured.
+func defaultTempCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".cache", "cute-pandas", "tmp"), nil
+}
+
+// SweepTempDirs removes execution temp directories older than maxAge, which
+// are left behind when the server exits mid-run. It returns how many were
+// removed. Run it at startup; maxAge should exceed the longest execution of
+// any other server sharing the temp directory.
+func (e *DockerExecutor) SweepTempDirs(maxAge time.Duration) int {
+	type pattern struct{ dir, glob string }
+	patterns := []pattern{{os.TempDir(), "pandas-exec-*"}}
+	if e.tempDir != "" {
+		patterns = append(patterns, pattern{e.tempDir, "exec-*"})
+	} else if cacheDir, err := defaultTempCacheDir(); err == nil {
+		patterns = append(patterns, pattern{cacheDir, "exec-*"})
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, p := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(p.dir, p.glob))
+		for _, dir := range matches {
+			info, err := os.Lstat(dir)
+			if err != nil || !info.IsDir() || info.ModTime().After(cutoff) {
+				continue
+			}
+			// Execution output dirs share the exec- prefix; leave them to the
+			// output cleanup loop in case OUTPUT_DIR and TEMP_DIR coincide
+			if _, err := os.Stat(filepath.Join(dir, ".metadata.json")); err == nil {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("Warning: failed to remove stale temp dir %s: %v", dir, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed
+}
+
+// maxStatConcurrency bounds parallel stat calls when validating inputs, so
+// large multi-file runs on network filesystems don't flood the server.
+const maxStatConcurrency = 8
+
 // ValidateFilePaths validates that all file paths exist and are accessible.
 func ValidateFilePaths(files []string) error {
-	for _, f := range files {
-		// Check for path traversal attempts
-		clean := filepath.Clean(f)
-		if strings.Contains(clean, "..") {
-			return fmt.Errorf("access denied: path traversal detected in %s", f)
-		}
+	_, err := validateInputs(files)
+	return err
+}
 
-		// Check file exists
-		info, err := os.Stat(f)
-		if os.IsNotExist(err) {
-			return fmt.Errorf("file not found: %s", f)
-		}
+// validateInputs checks every input file, up to maxStatConcurrency at a time,
+// and returns their absolute paths in order. When several files fail, the
+// error for the earliest one is returned.
+func validateInputs(files []string) ([]string, error) {
+	absPaths := make([]string, len(files))
+	errs := make([]error, len(files))
+
+	sem := make(chan struct{}, maxStatConcurrency)
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			absPaths[i], errs[i] = validateInput(f)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("cannot access file %s: %w", f, err)
+			return nil, err
 		}
+	}
+	return absPaths, nil
+}
 
-		// Only allow regular files
-		if !info.Mode().IsRegular() {
-			return fmt.Errorf("access denied: %s is not a regular file", f)
-		}
+// validateInput checks one input file and returns its absolute path.
+func validateInput(f string) (string, error) {
+	// Check for path traversal attempts
+	clean := filepath.Clean(f)
+	if strings.Contains(clean, "..") {
+		return "", fmt.Errorf("access denied: path traversal detected in %s", f)
 	}
-	return nil
+
+	// Check file exists
+	info, err := os.Stat(f)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("file not found: %s", f)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot access file %s: %w", f, err)
+	}
+
+	// Only allow regular files
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("access denied: %s is not a regular file", f)
+	}
+
+	absPath, err := filepath.Abs(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", f, err)
+	}
+	return absPath, nil
 }
 
 // ExecuteScript executes a Python script in a Docker container with access to specified files.
-func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files []string, timeout time.Duration) (*ExecutionResult, error) {
+func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files []string, opts ExecOptions) (*ExecutionResult, error) {
 	startTime := time.Now()
 
-	// Check if image is ready
-	if !e.IsImageReady() {
-		if err := e.ImageBuildError(); err != nil {
-			return &ExecutionResult{
-				Error:    fmt.Sprintf("Docker image build failed: %v", err),
-				ExitCode: 1,
-				Duration: time.Since(startTime),
-			}, nil
-		}
+	// Check if the selected image is ready
+	imageRef, err := e.resolveImage(opts.Image)
+	if err != nil {
 		return &ExecutionResult{
-			Error:    "Docker image is still being built. Please try again in a minute. (First startup requires building the pandas environment)",
+			Error:    err.Error(),
 			ExitCode: 1,
 			Duration: time.Since(startTime),
 		}, nil
 	}
 
-	// Validate files first
-	if err := ValidateFilePaths(files); err != nil {
+	netPolicy, err := e.networkPolicy(opts.NetworkPolicy)
+	if err != nil {
 		return &ExecutionResult{
 			Error:    err.Error(),
 			ExitCode: 1,
@@ -580,11 +829,20 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 		}, nil
 	}
 
-	// Use provided timeout or default
-	if timeout <= 0 {
-		timeout = e.executionTimeout
+	// Validate files first, resolving their absolute paths for mounting
+	absInputs, err := validateInputs(files)
+	if err != nil {
+		return &ExecutionResult{
+			Error:    err.Error(),
+			ExitCode: 1,
+			Duration: time.Since(startTime),
+		}, nil
 	}
 
+	// Apply the image's resource profile; the requested timeout is capped by it
+	resources := e.resourcesFor(opts.Image, opts.Timeout)
+	timeout := resources.timeout
+
 	// Create execution context with timeout
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -598,6 +856,17 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 	}
 	defer os.RemoveAll(tempDir)
 
+	var extracted map[int]string
+	if opts.ExtractArchives {
+		if extracted, err = e.extractInputs(absInputs, tempDir); err != nil {
+			return &ExecutionResult{
+				Error:    err.Error(),
+				ExitCode: 1,
+				Duration: time.Since(startTime),
+			}, nil
+		}
+	}
+
 	// Write script to temp file
 	scriptPath := filepath.Join(tempDir, "script.py")
 	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
@@ -605,7 +874,11 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 	}
 
 	// Generate execution ID for this run
-	execID := GenerateExecutionID()
+	execID := opts.ExecutionID
+	if execID == "" {
+		execID = GenerateExecutionID()
+	}
+	defer e.trackRunning(execID, opts)()
 
 	// Determine output directory:
 	// - If OutputManager is configured, create execution-specific directory
@@ -614,7 +887,14 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 	var execOutputPath string
 	if e.outputManager != nil {
 		var err error
-		outputDir, err = e.outputManager.CreateExecutionDir(execID)
+		scriptHash := sha256.Sum256([]byte(script))
+		outputDir, err = e.outputManager.CreateExecutionDir(ExecutionMetadata{
+			ExecutionID: execID,
+			ToolName:    opts.ToolName,
+			ScriptHash:  hex.EncodeToString(scriptHash[:]),
+			Inputs:      opts.Inputs,
+			Arguments:   opts.Arguments,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create execution output directory: %w", err)
 		}
@@ -630,6 +910,9 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create output directory: %w", err)
 		}
+		if err := makeWritable(outputDir, e.owner); err != nil {
+			return nil, fmt.Errorf("failed to set output directory permissions: %w", err)
+		}
 	}
 
 	// Build mounts
@@ -663,43 +946,93 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 		}
 	}
 
+	// Mount the shared data directory if configured (always read-only)
+	if e.sharedDataDir != "" {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   e.sharedDataDir,
+			Target:   SharedDataPath,
+			ReadOnly: true,
+		})
+	}
+
 	// Mount input files
-	for i, f := range files {
-		absPath, err := filepath.Abs(f)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for %s: %w", f, err)
-		}
+	for i, target := range InputMountPaths(files, opts.MountByName) {
 		mounts = append(mounts, mount.Mount{
 			Type:     mount.TypeBind,
-			Source:   absPath,
-			Target:   fmt.Sprintf("/data/input_%d/%s", i, filepath.Base(f)),
+			Source:   absInputs[i],
+			Target:   target,
 			ReadOnly: true,
 		})
+		if dir, ok := extracted[i]; ok {
+			mounts = append(mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   dir,
+				Target:   ArchiveMountPath(i),
+				ReadOnly: true,
+			})
+		}
+	}
+
+	// Windows host paths need Docker's forward-slash form
+	for i := range mounts {
+		mounts[i].Source = mountSource(mounts[i].Source)
 	}
 
 	// Calculate CPU quota (100000 = 1 CPU)
-	cpuQuota := int64(e.cpuLimit * 100000)
+	cpuQuota := int64(resources.cpu * 100000)
 
 	// Create container config
 	containerConfig := &container.Config{
-		Image:           e.image,
-		Cmd:             []string{"/script.py"},
+		Image:           imageRef,
+		Cmd:             append([]string{"/script.py"}, opts.Args...),
 		WorkingDir:      "/",
 		NetworkDisabled: e.networkDisabled,
+		User:            e.containerUser,
 		Env: []string{
 			"PYTHONUNBUFFERED=1",
 			"PYTHONDONTWRITEBYTECODE=1",
+			"MPLBACKEND=Agg",
 		},
 	}
+	if e.containerUser != "" {
+		// The image's home directory belongs to its own user; give caches
+		// (matplotlib, fontconfig) somewhere writable
+		containerConfig.Env = append(containerConfig.Env, "HOME=/tmp", "MPLCONFIGDIR=/tmp/matplotlib")
+	}
+	if e.warnMixedTypes {
+		containerConfig.Env = append(containerConfig.Env, "WARN_MIXED_TYPES=1")
+	}
+	if opts.Stdin != "" {
+		containerConfig.OpenStdin = true
+		containerConfig.AttachStdin = true
+		containerConfig.StdinOnce = true
+	}
 
 	hostConfig := &container.HostConfig{
-		Mounts: mounts,
+		NetworkMode: container.NetworkMode(e.networkMode),
+		Mounts:      mounts,
 		Resources: container.Resources{
-			Memory:   e.memoryLimit,
+			Memory:   resources.memory,
 			CPUQuota: cpuQuota,
 		},
 		AutoRemove: false, // We'll remove manually after getting logs
 	}
+	if resources.pids > 0 {
+		hostConfig.Resources.PidsLimit = &resources.pids
+	}
+	if netPolicy != nil {
+		// The policy's network limits egress; the script can read the
+		// allowlist from ALLOWED_HOSTS
+		containerConfig.NetworkDisabled = false
+		hostConfig.NetworkMode = container.NetworkMode(netPolicy.Network)
+		containerConfig.Env = append(containerConfig.Env, "ALLOWED_HOSTS="+strings.Join(netPolicy.Hosts, ","))
+		if netPolicy.Proxy != "" {
+			for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+				containerConfig.Env = append(containerConfig.Env, name+"="+netPolicy.Proxy)
+			}
+		}
+	}
 
 	// Create container
 	resp, err := e.client.ContainerCreate(execCtx, containerConfig, hostConfig, nil, nil, "")
@@ -715,11 +1048,24 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 		_ = e.client.ContainerRemove(removeCtx, containerID, container.RemoveOptions{Force: true})
 	}()
 
+	// Attach before starting so no stdin is lost
+	if opts.Stdin != "" {
+		stdin, err := e.client.ContainerAttach(execCtx, containerID, container.AttachOptions{Stream: true, Stdin: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach stdin: %w", err)
+		}
+		defer stdin.Close()
+		go feedStdin(stdin, opts.Stdin)
+	}
+
 	// Start container
 	if err := e.client.ContainerStart(execCtx, containerID, container.StartOptions{}); err != nil {
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
 
+	// Track resource usage while the script runs so OOM failures can report it
+	usageWatch := e.watchUsage(containerID)
+
 	// Wait for container to finish
 	statusCh, errCh := e.client.ContainerWait(execCtx, containerID, container.WaitConditionNotRunning)
 
@@ -730,10 +1076,16 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 			if execCtx.Err() == context.DeadlineExceeded {
 				// Kill the container on timeout
 				_ = e.client.ContainerKill(context.Background(), containerID, "SIGKILL")
+				usage := usageWatch.Stop()
 				return &ExecutionResult{
-					Error:    fmt.Sprintf("execution timeout: script exceeded %v", timeout),
-					ExitCode: 124, // Standard timeout exit code
-					Duration: time.Since(startTime),
+					ExecutionID:     execID,
+					OutputPath:      execOutputPath,
+					Error:           fmt.Sprintf("execution timeout: script exceeded %v", timeout),
+					ExitCode:        124, // Standard timeout exit code
+					TimedOut:        true,
+					Duration:        time.Since(startTime),
+					MemoryPeakBytes: usage.MemoryPeakBytes,
+					CPUSeconds:      usage.CPUTime.Seconds(),
 				}, nil
 			}
 			return nil, fmt.Errorf("container wait error: %w", err)
@@ -741,6 +1093,14 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 	case status := <-statusCh:
 		exitCode = status.StatusCode
 	}
+	usage := usageWatch.Stop()
+	peakMemory := usage.MemoryPeakBytes
+
+	// The exit code alone doesn't distinguish an OOM kill from other SIGKILLs
+	oomKilled := false
+	if info, err := e.client.ContainerInspect(context.Background(), containerID); err == nil && info.State != nil {
+		oomKilled = info.State.OOMKilled
+	}
 
 	// Get container logs
 	logOptions := container.LogsOptions{
@@ -762,11 +1122,22 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 
 	result := &ExecutionResult{
 		ExecutionID: execID,
-		Stdout:      stdout.String(),
-		Stderr:      stderr.String(),
 		ExitCode:    int(exitCode),
 		Duration:    time.Since(startTime),
 		OutputPath:  execOutputPath,
+		OOMKilled:   oomKilled,
+
+		MemoryPeakBytes: peakMemory,
+		CPUSeconds:      usage.CPUTime.Seconds(),
+	}
+
+	// Convert logs to text without silently corrupting non-UTF-8 output
+	var warning string
+	if result.Stdout, warning = sanitizeOutput("stdout", stdout.Bytes()); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	if result.Stderr, warning = sanitizeOutput("stderr", stderr.Bytes()); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
 	}
 
 	// Scan output files if using execution-specific directory
@@ -774,16 +1145,124 @@ func (e *DockerExecutor) ExecuteScript(ctx context.Context, script string, files
 		files, err := e.outputManager.ScanOutputFiles(execOutputPath)
 		if err == nil {
 			result.OutputFiles = files
+			if sums, err := e.outputManager.RecordChecksums(execOutputPath, files); err == nil {
+				result.Checksums = sums
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("output checksums not recorded: %v", err))
+			}
+		}
+	} else if outputDir != e.outputDir {
+		// Without an OutputManager the temporary output directory is removed
+		// with tempDir, so report what the script saved there
+		if files, err := listOutputFiles(outputDir); err == nil {
+			result.Discarded = files
+		}
+	}
+
+	// Publish outputs before a temporary output directory is removed. The
+	// legacy shared directory is skipped since it mixes executions.
+	if e.outputExporter != nil && outputDir != e.outputDir {
+		e.exportOutputs(result, outputDir)
+	}
+
+	// Read the structured result written by emit_result(), if any
+	if data, err := os.ReadFile(filepath.Join(outputDir, ResultFileName)); err == nil {
+		var structured any
+		if err := json.Unmarshal(data, &structured); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("ignored invalid %s: %v", ResultFileName, err))
+		} else {
+			result.Result = structured
 		}
 	}
 
-	if exitCode != 0 {
-		result.Error = fmt.Sprintf("script exited with code %d", exitCode)
+	if oomKilled {
+		result.Error = fmt.Sprintf("container was killed: out of memory (limit %dMB", resources.memory/(1024*1024))
+		if peakMemory > 0 {
+			result.Error += fmt.Sprintf(", peak usage %dMB", peakMemory/(1024*1024))
+		}
+		result.Error += "). Process the data in chunks, select fewer columns, or raise MAX_MEMORY_MB."
+	} else if exitCode != 0 {
+		result.Error = exitCodeMessage(exitCode)
 	}
 
 	return result, nil
 }
 
+// feedStdin writes data to an attached stdin and closes it so the script sees
+// EOF. A script that never reads stdin can leave the write blocked; closing
+// the connection once the container exits releases it.
+func feedStdin(conn types.HijackedResponse, data string) {
+	if _, err := io.Copy(conn.Conn, strings.NewReader(data)); err != nil {
+		return
+	}
+	conn.CloseWrite()
+}
+
+// exitCodeMessage describes a non-zero container exit code, explaining the
+// common signal and timeout codes.
+func exitCodeMessage(code int64) string {
+	msg := fmt.Sprintf("script exited with code %d", code)
+	switch code {
+	case 124:
+		return msg + " (timed out)"
+	case 134:
+		return msg + " (aborted, SIGABRT)"
+	case 137:
+		return msg + " (killed, SIGKILL; usually the memory limit or an external kill)"
+	case 139:
+		return msg + " (segmentation fault, SIGSEGV; usually a crash in a native extension)"
+	case 143:
+		return msg + " (terminated, SIGTERM)"
+	}
+	if code > 128 && code < 160 {
+		return fmt.Sprintf("%s (killed by signal %d)", msg, code-128)
+	}
+	return msg
+}
+
+// exportOutputs publishes each output file in dir through the exporter,
+// recording references in result.OutputRefs and failures as warnings.
+func (e *DockerExecutor) exportOutputs(result *ExecutionResult, dir string) {
+	files, err := listOutputFiles(dir)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("could not list outputs for export: %v", err))
+		return
+	}
+
+	for _, name := range files {
+		ref, err := e.outputExporter.ExportOutput(name, filepath.Join(dir, name))
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("output %s was not exported: %v", name, err))
+			continue
+		}
+		if result.OutputRefs == nil {
+			result.OutputRefs = make(map[string]string)
+		}
+		result.OutputRefs[name] = ref
+	}
+}
+
+// sanitizeOutput converts captured container output to a string.
+// Invalid UTF-8 sequences (e.g. printed binary or latin-1 data) are replaced with
+// U+FFFD and a warning describing the problem is returned alongside the text.
+func sanitizeOutput(stream string, data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+
+	invalid := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		}
+		i += size
+	}
+
+	warning := fmt.Sprintf("%s contained %d byte(s) of non-UTF-8 data, shown as U+FFFD. Save binary or non-UTF-8 text with save_output() instead of printing it.", stream, invalid)
+	return strings.ToValidUTF8(string(data), "\uFFFD"), warning
+}
+
 // CopyFromContainer copies a file from a container to a local destination.
 func (e *DockerExecutor) CopyFromContainer(ctx context.Context, containerID, srcPath string) ([]byte, error) {
 	reader, _, err := e.client.CopyFromContainer(ctx, containerID, srcPath)
@@ -815,12 +1294,32 @@ func (e *DockerExecutor) CopyFromContainer(ctx context.Context, containerID, src
 	return nil, fmt.Errorf("file not found in container: %s", srcPath)
 }
 
+// InputMountPaths returns the container path each input file is mounted at:
+// /data/input_{i}/{basename}, or with byName /data/{basename}. byName falls
+// back to the indexed path for basenames shared by several inputs, and for
+// names starting with "input_" that could clash with an indexed directory.
+func InputMountPaths(files []string, byName bool) []string {
+	counts := make(map[string]int, len(files))
+	for _, f := range files {
+		counts[filepath.Base(f)]++
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		base := filepath.Base(f)
+		if byName && counts[base] == 1 && !strings.HasPrefix(base, "input_") {
+			paths[i] = "/data/" + base
+		} else {
+			paths[i] = fmt.Sprintf("/data/input_%d/%s", i, base)
+		}
+	}
+	return paths
+}
+
 // BuildFileMapping creates a mapping from original file paths to container paths.
 func BuildFileMapping(files []string) map[string]string {
 	mapping := make(map[string]string)
-	for i, f := range files {
-		containerPath := fmt.Sprintf("/data/input_%d/%s", i, filepath.Base(f))
-		mapping[f] = containerPath
+	for i, containerPath := range InputMountPaths(files, false) {
+		mapping[files[i]] = containerPath
 	}
 	return mapping
 }
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"runtime"
+	"strings"
+)
+
+// mountSource converts a host path to the form Docker accepts as a bind
+// mount source. Only Windows paths need converting.
+func mountSource(path string) string {
+	if runtime.GOOS == "windows" {
+		return windowsMountSource(path)
+	}
+	return path
+}
+
+// windowsMountSource converts a Windows path to a Docker-style path with
+// forward slashes: C:\data\sales.csv becomes /c/data/sales.csv and the UNC
+// path \\server\share\f.csv becomes //server/share/f.csv. Paths already in
+// that form are returned unchanged.
+func windowsMountSource(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	// Extended-length prefixes (\\?\C:\...) aren't understood by Docker
+	if rest, ok := strings.CutPrefix(path, "//?/UNC/"); ok {
+		path = "//" + rest
+	} else {
+		path = strings.TrimPrefix(path, "//?/")
+	}
+	if len(path) >= 2 && path[1] == ':' && isDriveLetter(path[0]) {
+		return "/" + strings.ToLower(path[:1]) + path[2:]
+	}
+	return path
+}
+
+// isDriveLetter reports whether c is an ASCII letter.
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestWindowsMountSource(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"drive", `C:\data`, "/c/data"},
+		{"drive forward slashes", "c:/data", "/c/data"},
+		{"UNC", `\\server\share\x`, "//server/share/x"},
+		{"extended-length drive", `\\?\C:\x`, "/c/x"},
+		{"extended-length UNC", `\\?\UNC\server\share`, "//server/share"},
+		{"already converted", "/c/data", "/c/data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowsMountSource(tt.path); got != tt.want {
+				t.Errorf("windowsMountSource(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountSourcePOSIX(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX paths are only passed through unchanged off Windows")
+	}
+	const path = "/var/data/sales.csv"
+	if got := mountSource(path); got != path {
+		t.Errorf("mountSource(%q) = %q, want it unchanged", path, got)
+	}
+}
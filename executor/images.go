@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+// Package executor provides selection among multiple configured images.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultImageName selects the executor's primary image in ExecOptions.Image.
+const DefaultImageName = "default"
+
+// imageState tracks preparation of an additional named image.
+type imageState struct {
+	ref   string
+	ready bool
+	err   error
+}
+
+// ResourceProfile overrides the executor's resource limits for one image.
+// Zero fields keep the executor-wide defaults (MAX_MEMORY_MB, MAX_CPU,
+// EXECUTION_TIMEOUT, and no PID limit).
+type ResourceProfile struct {
+	MemoryMB  int64         // Container memory limit in MB
+	CPU       float64       // CPU limit (1.0 = 1 core)
+	Timeout   time.Duration // Default, and cap for requested timeouts
+	PidsLimit int64         // Maximum processes/threads in the container
+}
+
+// containerResources are the limits applied to one execution.
+type containerResources struct {
+	memory  int64 // bytes
+	cpu     float64
+	timeout time.Duration
+	pids    int64 // 0 = unlimited
+}
+
+// SetImageProfiles sets per-image resource profiles, keyed by image name
+// (DefaultImageName for the primary image).
+func (e *DockerExecutor) SetImageProfiles(profiles map[string]ResourceProfile) {
+	e.imageReadyMu.Lock()
+	defer e.imageReadyMu.Unlock()
+	e.profiles = profiles
+}
+
+// SetExecutionTimeout changes the default execution timeout for new runs.
+func (e *DockerExecutor) SetExecutionTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	e.imageReadyMu.Lock()
+	defer e.imageReadyMu.Unlock()
+	e.executionTimeout = d
+}
+
+// resourcesFor returns the limits for an execution in the named image.
+// A requested timeout is used when set but never exceeds the profile's.
+func (e *DockerExecutor) resourcesFor(name string, requested time.Duration) containerResources {
+	if name == "" {
+		name = DefaultImageName
+	}
+	e.imageReadyMu.RLock()
+	p := e.profiles[name]
+	r := containerResources{
+		memory:  e.memoryLimit,
+		cpu:     e.cpuLimit,
+		timeout: e.executionTimeout,
+		pids:    p.PidsLimit,
+	}
+	e.imageReadyMu.RUnlock()
+
+	if p.MemoryMB > 0 {
+		r.memory = p.MemoryMB * 1024 * 1024
+	}
+	if p.CPU > 0 {
+		r.cpu = p.CPU
+	}
+	if p.Timeout > 0 {
+		r.timeout = p.Timeout
+	}
+	if requested > 0 && (p.Timeout <= 0 || requested < p.Timeout) {
+		r.timeout = requested
+	}
+	return r
+}
+
+// MemoryLimit returns the container memory limit in bytes for executions in
+// the named image ("" = primary image).
+func (e *DockerExecutor) MemoryLimit(image string) int64 {
+	return e.resourcesFor(image, 0).memory
+}
+
+// ExecutionTimeout returns the timeout an execution in the named image runs
+// under when requested (0 = default) is asked for.
+func (e *DockerExecutor) ExecutionTimeout(image string, requested time.Duration) time.Duration {
+	return e.resourcesFor(image, requested).timeout
+}
+
+// SetImages registers additional named images (name -> image reference) that
+// executions can select with ExecOptions.Image. Call before EnsureImageAsync.
+// Additional images are always pulled; BUILD_LOCAL applies only to the
+// default image.
+func (e *DockerExecutor) SetImages(images map[string]string) {
+	e.imageReadyMu.Lock()
+	defer e.imageReadyMu.Unlock()
+	e.images = make(map[string]*imageState, len(images))
+	for name, ref := range images {
+		if name == DefaultImageName {
+			continue
+		}
+		e.images[name] = &imageState{ref: ref}
+	}
+}
+
+// ImageNames returns the names accepted by ExecOptions.Image, with
+// DefaultImageName first.
+func (e *DockerExecutor) ImageNames() []string {
+	e.imageReadyMu.RLock()
+	defer e.imageReadyMu.RUnlock()
+	names := make([]string, 0, len(e.images))
+	for name := range e.images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultImageName}, names...)
+}
+
+// HasImage reports whether name selects a configured image.
+func (e *DockerExecutor) HasImage(name string) bool {
+	if name == "" || name == DefaultImageName {
+		return true
+	}
+	e.imageReadyMu.RLock()
+	defer e.imageReadyMu.RUnlock()
+	_, ok := e.images[name]
+	return ok
+}
+
+// ImageStatus returns the reference and preparation state of a named image.
+func (e *DockerExecutor) ImageStatus(name string) (ref string, ready bool, err error) {
+	if name == "" || name == DefaultImageName {
+		return e.image, e.IsImageReady(), e.ImageBuildError()
+	}
+	e.imageReadyMu.RLock()
+	defer e.imageReadyMu.RUnlock()
+	st, ok := e.images[name]
+	if !ok {
+		return "", false, fmt.Errorf("unknown image %q", name)
+	}
+	return st.ref, st.ready, st.err
+}
+
+// Image readiness states reported by ErrImageNotReady.
+const (
+	ImageStateBuilding = "building" // Still being built or pulled
+	ImageStateFailed   = "failed"   // Build or pull failed; the server needs attention
+)
+
+// ErrImageNotReady is returned when the selected image can't run scripts yet.
+type ErrImageNotReady struct {
+	Image string // Image name (DefaultImageName for the primary image)
+	Ref   string // Image reference
+	State string // ImageStateBuilding or ImageStateFailed
+	Err   error  // Build or pull failure, when State is ImageStateFailed
+}
+
+func (e *ErrImageNotReady) Error() string {
+	if e.Image == DefaultImageName {
+		if e.State == ImageStateFailed {
+			return fmt.Sprintf("Docker image build failed: %v", e.Err)
+		}
+		return "Docker image is still being built. Please try again in a minute. (First startup requires building the pandas environment)"
+	}
+	if e.State == ImageStateFailed {
+		return fmt.Sprintf("Docker image %s (%s) could not be pulled: %v", e.Image, e.Ref, e.Err)
+	}
+	return fmt.Sprintf("Docker image %s (%s) is still being pulled. Please try again shortly.", e.Image, e.Ref)
+}
+
+func (e *ErrImageNotReady) Unwrap() error { return e.Err }
+
+// CheckImageReady returns nil if the named image can run scripts, an
+// *ErrImageNotReady if it is still being prepared or failed, or an error
+// for an unknown name.
+func (e *DockerExecutor) CheckImageReady(name string) error {
+	_, err := e.resolveImage(name)
+	return err
+}
+
+// resolveImage returns the reference for a named image once it is ready.
+func (e *DockerExecutor) resolveImage(name string) (string, error) {
+	if name == "" {
+		name = DefaultImageName
+	}
+	if !e.HasImage(name) {
+		return "", fmt.Errorf("unknown image %q (available: %s)", name, strings.Join(e.ImageNames(), ", "))
+	}
+	ref, ready, err := e.ImageStatus(name)
+	switch {
+	case ready:
+		return ref, nil
+	case err != nil:
+		return "", &ErrImageNotReady{Image: name, Ref: ref, State: ImageStateFailed, Err: err}
+	default:
+		return "", &ErrImageNotReady{Image: name, Ref: ref, State: ImageStateBuilding}
+	}
+}
+
+// ensureExtraImagesAsync pulls any additional images missing locally.
+func (e *DockerExecutor) ensureExtraImagesAsync(ctx context.Context) {
+	e.imageReadyMu.RLock()
+	pending := make(map[string]*imageState, len(e.images))
+	for name, st := range e.images {
+		pending[name] = st
+	}
+	e.imageReadyMu.RUnlock()
+
+	for name, st := range pending {
+		if _, _, err := e.client.ImageInspectWithRaw(ctx, st.ref); err == nil {
+			log.Printf("Docker image %s (%s) found locally", name, st.ref)
+			e.imageReadyMu.Lock()
+			st.ready = true
+			e.imageReadyMu.Unlock()
+			continue
+		}
+
+		go func(name string, st *imageState) {
+			err := e.pullImage(context.Background(), st.ref)
+			e.imageReadyMu.Lock()
+			defer e.imageReadyMu.Unlock()
+			if err != nil {
+				st.err = err
+				log.Printf("Image %s preparation failed: %v", name, err)
+				return
+			}
+			st.ready = true
+			log.Printf("Docker image %s (%s) is now ready!", name, st.ref)
+		}(name, st)
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+// NetworkPolicy lets executions reach an allowlist of hosts. Network is a
+// Docker network whose egress is limited to Hosts outside the container, e.g.
+// an internal network behind a filtering proxy; Proxy, if set, is exported to
+// the script as HTTP_PROXY and HTTPS_PROXY.
+type NetworkPolicy struct {
+	Network string   // Docker network containers are attached to
+	Proxy   string   // Optional proxy URL exported as HTTP_PROXY/HTTPS_PROXY
+	Hosts   []string // Allowed hosts; "*.example.com" matches any subdomain
+}
+
+// SetNetworkPolicies registers the named network policies executions can
+// select with ExecOptions.NetworkPolicy. Each network must exist and must not
+// be host or container-shared networking.
+func (e *DockerExecutor) SetNetworkPolicies(ctx context.Context, policies map[string]NetworkPolicy) error {
+	for name, p := range policies {
+		if p.Network == "host" || strings.HasPrefix(p.Network, "container:") {
+			return fmt.Errorf("policy %s: network %q is not allowed", name, p.Network)
+		}
+		if _, err := e.client.NetworkInspect(ctx, p.Network, network.InspectOptions{}); err != nil {
+			return fmt.Errorf("policy %s: network %q not found: %w", name, p.Network, err)
+		}
+	}
+	e.imageReadyMu.Lock()
+	defer e.imageReadyMu.Unlock()
+	e.networkPolicies = policies
+	return nil
+}
+
+// NetworkPolicies returns the configured network policies by name.
+func (e *DockerExecutor) NetworkPolicies() map[string]NetworkPolicy {
+	e.imageReadyMu.RLock()
+	defer e.imageReadyMu.RUnlock()
+	return e.networkPolicies
+}
+
+// NetworkPolicyFor returns the name of the first policy, by name, whose
+// allowlist covers every host in hosts ("host" or "host:port"). The error
+// explains which hosts no policy permits.
+func (e *DockerExecutor) NetworkPolicyFor(hosts []string) (string, error) {
+	policies := e.NetworkPolicies()
+	if len(policies) == 0 {
+		return "", fmt.Errorf("network access is disabled on this server (no NETWORK_POLICIES are configured)")
+	}
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	requested := make([]string, len(hosts))
+	for i, h := range hosts {
+		requested[i] = normalizeHost(h)
+	}
+	for _, name := range names {
+		if policyAllows(policies[name], requested) {
+			return name, nil
+		}
+	}
+
+	var denied, allowed []string
+	for _, h := range requested {
+		ok := false
+		for _, p := range policies {
+			ok = ok || policyAllows(p, []string{h})
+		}
+		if !ok {
+			denied = append(denied, h)
+		}
+	}
+	for _, name := range names {
+		allowed = append(allowed, fmt.Sprintf("%s: %s", name, strings.Join(policies[name].Hosts, ", ")))
+	}
+	if len(denied) == 0 {
+		return "", fmt.Errorf("no single network policy allows all of %s (policies: %s)", strings.Join(requested, ", "), strings.Join(allowed, "; "))
+	}
+	return "", fmt.Errorf("network access to %s is not permitted by policy (policies: %s)", strings.Join(denied, ", "), strings.Join(allowed, "; "))
+}
+
+// networkPolicy returns the named policy; "" means none.
+func (e *DockerExecutor) networkPolicy(name string) (*NetworkPolicy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := e.NetworkPolicies()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown network policy %q", name)
+	}
+	return &p, nil
+}
+
+// normalizeHost lower-cases h and strips a port.
+func normalizeHost(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		return host
+	}
+	return h
+}
+
+// policyAllows reports whether every host matches one of p's patterns.
+func policyAllows(p NetworkPolicy, hosts []string) bool {
+	for _, h := range hosts {
+		ok := false
+		for _, pattern := range p.Hosts {
+			if suffix, wildcard := strings.CutPrefix(pattern, "*."); wildcard {
+				ok = strings.HasSuffix(h, "."+suffix)
+			} else {
+				ok = h == pattern
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -7,50 +7,164 @@
 package executor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Errors returned by OutputManager, for use with errors.Is.
+var (
+	// ErrOutputDisabled is returned when no output directory is configured.
+	ErrOutputDisabled = errors.New("output directory not configured")
+
+	// ErrNotFound is wrapped by errors for executions and output files that
+	// don't exist, including expired ones that were cleaned up.
+	ErrNotFound = errors.New("not found")
+)
+
 // ExecutionMetadata holds metadata for an execution's output directory.
 type ExecutionMetadata struct {
 	ExecutionID string    `json:"execution_id"`
 	CreatedAt   time.Time `json:"created_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
+
+	// Provenance: what produced the outputs
+	ToolName   string   `json:"tool_name,omitempty"`
+	ScriptHash string   `json:"script_hash,omitempty"` // sha256 of the executed script
+	Inputs     []string `json:"inputs,omitempty"`      // Input file references as passed by the client
+
+	// Tool call arguments, recorded so the run can be repeated with rerun
+	Arguments map[string]any `json:"arguments,omitempty"`
+
+	// Integrity: sha256 of each output file, recorded when the run finished
+	Checksums map[string]string `json:"checksums,omitempty"`
 }
 
 // ExecutionInfo represents information about an execution and its files.
 type ExecutionInfo struct {
-	ExecutionID string    `json:"execution_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	ExpiresAt   time.Time `json:"expires_at"`
-	Files       []string  `json:"files"`
-	OutputPath  string    `json:"output_path"`
+	ExecutionID string            `json:"execution_id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	ToolName    string            `json:"tool_name,omitempty"`
+	ScriptHash  string            `json:"script_hash,omitempty"`
+	Inputs      []string          `json:"inputs,omitempty"`
+	Arguments   map[string]any    `json:"arguments,omitempty"`
+	Files       []string          `json:"files"`
+	Checksums   map[string]string `json:"checksums,omitempty"`
+	OutputPath  string            `json:"output_path"`
 }
 
 // OutputManager manages execution output directories with TTL-based cleanup.
 type OutputManager struct {
-	baseDir    string
-	ttl        time.Duration
-	mu         sync.RWMutex
-	stopCh     chan struct{}
-	cleanupWg  sync.WaitGroup
+	baseDir   string
+	ttl       atomic.Int64    // time.Duration; changed live by SetTTL
+	maxTTL    time.Duration   // Max lifetime via ExtendTTL (0 = no cap)
+	owner     *containerOwner // UID/GID scripts run as, if configured
+	mu        sync.RWMutex
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	cleanupWg sync.WaitGroup
 }
 
 // NewOutputManager creates a new OutputManager.
 func NewOutputManager(baseDir string, ttl time.Duration) *OutputManager {
-	return &OutputManager{
+	m := &OutputManager{
 		baseDir: baseDir,
-		ttl:     ttl,
 		stopCh:  make(chan struct{}),
 	}
+	m.ttl.Store(int64(ttl))
+	return m
+}
+
+// TTL returns the lifetime given to new executions.
+func (m *OutputManager) TTL() time.Duration {
+	return time.Duration(m.ttl.Load())
+}
+
+// SetTTL changes the lifetime given to new executions. Existing executions
+// keep the expiry recorded in their metadata.
+func (m *OutputManager) SetTTL(d time.Duration) {
+	m.ttl.Store(int64(d))
+}
+
+// SetOwner sets the UID/GID that execution directories are created for.
+// With nil (no CONTAINER_USER), makeWritable's default applies.
+func (m *OutputManager) SetOwner(owner *containerOwner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owner = owner
+}
+
+// SetMaxTTL caps the total lifetime an execution can reach through ExtendTTL,
+// measured from its creation. Zero disables the cap.
+func (m *OutputManager) SetMaxTTL(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxTTL = d
+}
+
+// ExtendTTL pushes an execution's expiry d past the later of now and its
+// current expiry, capped at CreatedAt plus the configured maximum TTL.
+// It returns the new expiry and whether the cap was applied.
+func (m *OutputManager) ExtendTTL(execID string, d time.Duration) (time.Time, bool, error) {
+	if m.baseDir == "" {
+		return time.Time{}, false, ErrOutputDisabled
+	}
+	if d <= 0 {
+		return time.Time{}, false, fmt.Errorf("extension must be positive")
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metadata, err := m.readMetadata(execDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, false, fmt.Errorf("execution %s %w", execID, ErrNotFound)
+		}
+		return time.Time{}, false, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	base := metadata.ExpiresAt
+	if now := time.Now(); now.After(base) {
+		base = now
+	}
+	expires := base.Add(d)
+
+	capped := false
+	if m.maxTTL > 0 {
+		if limit := metadata.CreatedAt.Add(m.maxTTL); expires.After(limit) {
+			expires = limit
+			capped = true
+		}
+	}
+	if expires.Before(metadata.ExpiresAt) {
+		expires = metadata.ExpiresAt
+	}
+	metadata.ExpiresAt = expires
+
+	if err := m.writeMetadata(execDir, metadata); err != nil {
+		return time.Time{}, false, err
+	}
+	return expires, capped, nil
 }
 
 // GenerateExecutionID creates a new unique execution ID.
@@ -60,40 +174,48 @@ func GenerateExecutionID() string {
 	return fmt.Sprintf("exec-%s", id[:8])
 }
 
+// execDir returns the directory of execution execID. IDs other than a single
+// "exec-*" path element (such as "..", or ones containing a separator) are
+// rejected, so no caller can reach outside the base directory.
+func (m *OutputManager) execDir(execID string) (string, error) {
+	if !strings.HasPrefix(execID, "exec-") || strings.ContainsAny(execID, `/\`) || filepath.Base(execID) != execID {
+		return "", fmt.Errorf("invalid execution ID %q: %w", execID, ErrNotFound)
+	}
+	return filepath.Join(m.baseDir, execID), nil
+}
+
 // CreateExecutionDir creates a new execution directory with metadata.
-func (m *OutputManager) CreateExecutionDir(execID string) (string, error) {
+// CreatedAt and ExpiresAt are set by the manager; the provenance fields of
+// metadata are recorded as given.
+func (m *OutputManager) CreateExecutionDir(metadata ExecutionMetadata) (string, error) {
+	execID := metadata.ExecutionID
 	if m.baseDir == "" {
-		return "", fmt.Errorf("output directory not configured")
+		return "", ErrOutputDisabled
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return "", err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	execDir := filepath.Join(m.baseDir, execID)
-	if err := os.MkdirAll(execDir, 0777); err != nil {
+	if err := os.MkdirAll(execDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create execution directory: %w", err)
 	}
-	
-	// Ensure directory is writable by all users (for Docker containers running as different UIDs)
-	if err := os.Chmod(execDir, 0777); err != nil {
+
+	// Ensure the container user can write its outputs
+	if err := makeWritable(execDir, m.owner); err != nil {
 		return "", fmt.Errorf("failed to set directory permissions: %w", err)
 	}
 
 	// Write metadata file
-	metadata := ExecutionMetadata{
-		ExecutionID: execID,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(m.ttl),
-	}
+	metadata.CreatedAt = time.Now()
+	metadata.ExpiresAt = metadata.CreatedAt.Add(m.TTL())
 
-	metadataPath := filepath.Join(execDir, ".metadata.json")
-	data, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write metadata: %w", err)
+	if err := m.writeMetadata(execDir, &metadata); err != nil {
+		return "", err
 	}
 
 	return execDir, nil
@@ -102,7 +224,7 @@ func (m *OutputManager) CreateExecutionDir(execID string) (string, error) {
 // ListExecutions returns all executions with their metadata and files.
 func (m *OutputManager) ListExecutions() ([]ExecutionInfo, error) {
 	if m.baseDir == "" {
-		return nil, fmt.Errorf("output directory not configured")
+		return nil, ErrOutputDisabled
 	}
 
 	m.mu.RLock()
@@ -134,18 +256,43 @@ func (m *OutputManager) ListExecutions() ([]ExecutionInfo, error) {
 	return executions, nil
 }
 
+// GetExecution returns the metadata and files of a single execution.
+func (m *OutputManager) GetExecution(execID string) (*ExecutionInfo, error) {
+	if m.baseDir == "" {
+		return nil, ErrOutputDisabled
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := os.Stat(execDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("execution %s %w", execID, ErrNotFound)
+	}
+
+	return m.getExecutionInfo(execDir)
+}
+
 // ListFiles returns the files in a specific execution directory.
 func (m *OutputManager) ListFiles(execID string) ([]string, error) {
 	if m.baseDir == "" {
-		return nil, fmt.Errorf("output directory not configured")
+		return nil, ErrOutputDisabled
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return nil, err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	execDir := filepath.Join(m.baseDir, execID)
 	if _, err := os.Stat(execDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("execution %s not found", execID)
+		return nil, fmt.Errorf("execution %s %w", execID, ErrNotFound)
 	}
 
 	return m.listFilesInDir(execDir)
@@ -153,8 +300,21 @@ func (m *OutputManager) ListFiles(execID string) ([]string, error) {
 
 // GetFile reads the contents of a file from an execution directory.
 func (m *OutputManager) GetFile(execID, filename string) ([]byte, error) {
+	data, _, err := m.GetFileRange(execID, filename, 0, 0)
+	return data, err
+}
+
+// GetFileRange reads the first head bytes or the last tail bytes of an
+// output file (the whole file when both are zero) and returns them with the
+// file's total size. A recorded checksum is verified against the whole file.
+func (m *OutputManager) GetFileRange(execID, filename string, head, tail int64) ([]byte, int64, error) {
 	if m.baseDir == "" {
-		return nil, fmt.Errorf("output directory not configured")
+		return nil, 0, ErrOutputDisabled
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	m.mu.RLock()
@@ -162,42 +322,116 @@ func (m *OutputManager) GetFile(execID, filename string) ([]byte, error) {
 
 	// Sanitize filename to prevent path traversal
 	filename = filepath.Base(filename)
-	filePath := filepath.Join(m.baseDir, execID, filename)
+	filePath := filepath.Join(execDir, filename)
 
 	// Ensure the path is still within the execution directory
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("invalid path: %w", err)
+		return nil, 0, fmt.Errorf("invalid path: %w", err)
 	}
-	execDir := filepath.Join(m.baseDir, execID)
 	absExecDir, _ := filepath.Abs(execDir)
 	if !strings.HasPrefix(absPath, absExecDir) {
-		return nil, fmt.Errorf("path traversal detected")
+		return nil, 0, fmt.Errorf("path traversal detected")
 	}
 
-	data, err := os.ReadFile(filePath)
+	f, err := os.Open(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("file %s not found in execution %s", filename, execID)
+			return nil, 0, fmt.Errorf("file %s %w in execution %s", filename, ErrNotFound, execID)
+		}
+		return nil, 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	size := info.Size()
+
+	// Verify against the checksum recorded when the run finished, if any
+	if metadata, err := m.readMetadata(execDir); err == nil {
+		if want, ok := metadata.Checksums[filename]; ok {
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err != nil {
+				return nil, 0, fmt.Errorf("failed to read file: %w", err)
+			}
+			if got := hex.EncodeToString(h.Sum(nil)); got != want {
+				return nil, 0, fmt.Errorf("checksum mismatch for %s in execution %s: file was modified after the run (expected sha256 %s, got %s)", filename, execID, want, got)
+			}
+		}
+	}
+
+	offset, length := int64(0), size
+	if head > 0 && head < size {
+		length = head
+	} else if tail > 0 && tail < size {
+		offset, length = size-tail, tail
+	}
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, size, nil
+}
+
+// RecordChecksums computes the sha256 of each named output file in execDir
+// and stores them in the execution's metadata, so later reads can be verified.
+func (m *OutputManager) RecordChecksums(execDir string, files []string) (map[string]string, error) {
+	sums := make(map[string]string, len(files))
+	for _, name := range files {
+		sum, err := fileSHA256(filepath.Join(execDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %s: %w", name, err)
 		}
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		sums[name] = sum
 	}
 
-	return data, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metadata, err := m.readMetadata(execDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	metadata.Checksums = sums
+	if err := m.writeMetadata(execDir, metadata); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// fileSHA256 returns the hex sha256 of a file's contents.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // DeleteExecution removes an execution directory and all its contents.
 func (m *OutputManager) DeleteExecution(execID string) error {
 	if m.baseDir == "" {
-		return fmt.Errorf("output directory not configured")
+		return ErrOutputDisabled
+	}
+
+	execDir, err := m.execDir(execID)
+	if err != nil {
+		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	execDir := filepath.Join(m.baseDir, execID)
 	if _, err := os.Stat(execDir); os.IsNotExist(err) {
-		return fmt.Errorf("execution %s not found", execID)
+		return fmt.Errorf("execution %s %w", execID, ErrNotFound)
 	}
 
 	if err := os.RemoveAll(execDir); err != nil {
@@ -210,7 +444,7 @@ func (m *OutputManager) DeleteExecution(execID string) error {
 // DeleteAllExecutions removes all execution directories.
 func (m *OutputManager) DeleteAllExecutions() (int, error) {
 	if m.baseDir == "" {
-		return 0, fmt.Errorf("output directory not configured")
+		return 0, ErrOutputDisabled
 	}
 
 	m.mu.Lock()
@@ -266,12 +500,13 @@ func (m *OutputManager) StartCleanupLoop(interval time.Duration) {
 			}
 		}
 	}()
-	log.Printf("Output cleanup loop started (interval: %v, TTL: %v)", interval, m.ttl)
+	log.Printf("Output cleanup loop started (interval: %v, TTL: %v)", interval, m.TTL())
 }
 
-// Stop stops the cleanup loop.
+// Stop stops the cleanup loop and waits for an in-progress sweep to finish.
+// It is safe to call more than once.
 func (m *OutputManager) Stop() {
-	close(m.stopCh)
+	m.stopOnce.Do(func() { close(m.stopCh) })
 	m.cleanupWg.Wait()
 }
 
@@ -305,7 +540,7 @@ func (m *OutputManager) cleanupExpired() {
 			if err != nil {
 				continue
 			}
-			if now.Sub(info.ModTime()) > m.ttl {
+			if now.Sub(info.ModTime()) > m.TTL() {
 				if err := os.RemoveAll(execDir); err == nil {
 					cleaned++
 				}
@@ -339,7 +574,7 @@ func (m *OutputManager) getExecutionInfo(execDir string) (*ExecutionInfo, error)
 		metadata = &ExecutionMetadata{
 			ExecutionID: filepath.Base(execDir),
 			CreatedAt:   info.ModTime(),
-			ExpiresAt:   info.ModTime().Add(m.ttl),
+			ExpiresAt:   info.ModTime().Add(m.TTL()),
 		}
 	}
 
@@ -349,7 +584,12 @@ func (m *OutputManager) getExecutionInfo(execDir string) (*ExecutionInfo, error)
 		ExecutionID: metadata.ExecutionID,
 		CreatedAt:   metadata.CreatedAt,
 		ExpiresAt:   metadata.ExpiresAt,
+		ToolName:    metadata.ToolName,
+		ScriptHash:  metadata.ScriptHash,
+		Inputs:      metadata.Inputs,
+		Arguments:   metadata.Arguments,
 		Files:       files,
+		Checksums:   metadata.Checksums,
 		OutputPath:  execDir,
 	}, nil
 }
@@ -370,8 +610,26 @@ func (m *OutputManager) readMetadata(execDir string) (*ExecutionMetadata, error)
 	return &metadata, nil
 }
 
-// listFilesInDir lists all files in a directory (excluding metadata).
+// writeMetadata writes the metadata file of an execution directory.
+func (m *OutputManager) writeMetadata(execDir string, metadata *ExecutionMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(execDir, ".metadata.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// listFilesInDir lists all files in a directory (excluding metadata and the structured result).
 func (m *OutputManager) listFilesInDir(dir string) ([]string, error) {
+	return listOutputFiles(dir)
+}
+
+// listOutputFiles lists the user-visible output files in dir.
+func listOutputFiles(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
@@ -379,7 +637,7 @@ func (m *OutputManager) listFilesInDir(dir string) ([]string, error) {
 
 	var files []string
 	for _, entry := range entries {
-		if entry.IsDir() || entry.Name() == ".metadata.json" {
+		if entry.IsDir() || entry.Name() == ".metadata.json" || entry.Name() == ResultFileName {
 			continue
 		}
 		files = append(files, entry.Name())
@@ -392,3 +650,57 @@ func (m *OutputManager) listFilesInDir(dir string) ([]string, error) {
 func (m *OutputManager) ScanOutputFiles(execDir string) ([]string, error) {
 	return m.listFilesInDir(execDir)
 }
+
+// containerOwner is the numeric UID/GID that script containers run as.
+type containerOwner struct {
+	uid, gid int
+}
+
+// parseContainerUser parses a CONTAINER_USER value of the form "UID" or
+// "UID:GID". Names are rejected since they can't be resolved on the host.
+func parseContainerUser(user string) (*containerOwner, error) {
+	uidStr, gidStr, hasGID := strings.Cut(user, ":")
+	uid, err := strconv.Atoi(uidStr)
+	if err != nil || uid < 0 {
+		return nil, fmt.Errorf("invalid container user %q: expected UID or UID:GID", user)
+	}
+	gid := uid
+	if hasGID {
+		if gid, err = strconv.Atoi(gidStr); err != nil || gid < 0 {
+			return nil, fmt.Errorf("invalid container user %q: expected UID or UID:GID", user)
+		}
+	}
+	return &containerOwner{uid: uid, gid: gid}, nil
+}
+
+// imageOwner is the UID/GID of the image's pandas user, which scripts run as
+// when no container user is configured.
+var imageOwner = &containerOwner{uid: 1000, gid: 1000}
+
+// Replaced in tests
+var (
+	geteuid = os.Geteuid
+	chown   = os.Chown
+)
+
+// makeWritable lets the container user write to dir. With an explicit owner
+// (CONTAINER_USER) the directory is kept at 0755 and chowned to it when it
+// differs from the server's own UID; if that isn't permitted an error is
+// returned. Without one, a server running as root chowns dir to imageOwner,
+// and any other server makes dir world-writable, since it can't give the
+// image's user ownership.
+func makeWritable(dir string, owner *containerOwner) error {
+	if owner == nil {
+		if geteuid() != 0 {
+			return os.Chmod(dir, 0777)
+		}
+		owner = imageOwner
+	}
+	if owner.uid == geteuid() {
+		return nil
+	}
+	if err := chown(dir, owner.uid, owner.gid); err != nil {
+		return fmt.Errorf("cannot give container UID %d ownership of %s (run the server as root or as that UID, or set CONTAINER_USER to match): %w", owner.uid, dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupExpired(t *testing.T) {
+	base := t.TempDir()
+	m := NewOutputManager(base, time.Hour)
+	now := time.Now()
+
+	dirs := []struct {
+		name     string
+		metadata *ExecutionMetadata // nil leaves the directory without metadata
+		modTime  time.Time          // Only used without metadata
+		expired  bool
+	}{
+		{name: "exec-fresh", metadata: &ExecutionMetadata{CreatedAt: now, ExpiresAt: now.Add(time.Hour)}},
+		{name: "exec-expired", metadata: &ExecutionMetadata{CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Minute)}, expired: true},
+		{name: "exec-extended", metadata: &ExecutionMetadata{CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Minute)}},
+		{name: "exec-untracked-fresh", modTime: now},
+		{name: "exec-untracked-old", modTime: now.Add(-2 * time.Hour), expired: true},
+		{name: "not-an-execution", modTime: now.Add(-2 * time.Hour)},
+	}
+	for _, d := range dirs {
+		dir := filepath.Join(base, d.name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "result.csv"), []byte("a\n1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if d.metadata != nil {
+			d.metadata.ExecutionID = d.name
+			if err := m.writeMetadata(dir, d.metadata); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := os.Chtimes(dir, d.modTime, d.modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.cleanupExpired()
+
+	for _, d := range dirs {
+		_, err := os.Stat(filepath.Join(base, d.name))
+		switch {
+		case d.expired && !os.IsNotExist(err):
+			t.Errorf("%s: expected it to be removed, stat error %v", d.name, err)
+		case !d.expired && err != nil:
+			t.Errorf("%s: expected it to be kept, got %v", d.name, err)
+		}
+	}
+}
+
+func TestMakeWritable(t *testing.T) {
+	tests := []struct {
+		name      string
+		owner     *containerOwner // nil = no CONTAINER_USER
+		euid      int             // Server's effective UID
+		chownErr  error
+		wantChown *containerOwner // nil = not chowned
+		wantMode  os.FileMode
+		wantErr   bool
+	}{
+		{name: "default as non-root", euid: 501, wantMode: 0777},
+		{name: "default as root", euid: 0, wantChown: imageOwner, wantMode: 0755},
+		{name: "explicit as same UID", owner: &containerOwner{uid: 501, gid: 20}, euid: 501, wantMode: 0755},
+		{name: "explicit as root", owner: &containerOwner{uid: 2000, gid: 2000}, euid: 0, wantChown: &containerOwner{uid: 2000, gid: 2000}, wantMode: 0755},
+		{name: "explicit chown denied", owner: &containerOwner{uid: 2000, gid: 2000}, euid: 501, chownErr: os.ErrPermission, wantChown: &containerOwner{uid: 2000, gid: 2000}, wantMode: 0755, wantErr: true},
+	}
+	defer func(e func() int, c func(string, int, int) error) { geteuid, chown = e, c }(geteuid, chown)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			var chowned *containerOwner
+			geteuid = func() int { return tt.euid }
+			chown = func(_ string, uid, gid int) error {
+				chowned = &containerOwner{uid: uid, gid: gid}
+				return tt.chownErr
+			}
+
+			err := makeWritable(dir, tt.owner)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("makeWritable error = %v, want error %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.wantChown == nil && chowned != nil:
+				t.Errorf("chowned to %+v, want no chown", *chowned)
+			case tt.wantChown != nil && (chowned == nil || *chowned != *tt.wantChown):
+				t.Errorf("chowned to %+v, want %+v", chowned, *tt.wantChown)
+			}
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.wantMode {
+				t.Errorf("mode = %o, want %o", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestInvalidExecutionIDs(t *testing.T) {
+	parent := t.TempDir()
+	base := filepath.Join(parent, "outputs")
+	if err := os.Mkdir(base, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewOutputManager(base, time.Hour)
+
+	ops := map[string]func(id string) error{
+		"GetExecution":    func(id string) error { _, err := m.GetExecution(id); return err },
+		"ListFiles":       func(id string) error { _, err := m.ListFiles(id); return err },
+		"GetFileRange":    func(id string) error { _, _, err := m.GetFileRange(id, "secret.txt", 0, 0); return err },
+		"ExtendTTL":       func(id string) error { _, _, err := m.ExtendTTL(id, time.Hour); return err },
+		"DeleteExecution": func(id string) error { return m.DeleteExecution(id) },
+		"CreateExecutionDir": func(id string) error {
+			_, err := m.CreateExecutionDir(ExecutionMetadata{ExecutionID: id})
+			return err
+		},
+	}
+	for _, id := range []string{"..", ".", "", "outputs", "exec-a/../..", "../exec-a", "exec-a/b", `exec-a\..`, "/tmp/exec-a"} {
+		for name, op := range ops {
+			if err := op(id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s(%q): got %v, want an error wrapping ErrNotFound", name, id, err)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "secret.txt")); err != nil {
+		t.Errorf("file outside the output directory was touched: %v", err)
+	}
+
+	// A well-formed ID still works
+	if _, err := m.CreateExecutionDir(ExecutionMetadata{ExecutionID: "exec-valid"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetExecution("exec-valid"); err != nil {
+		t.Errorf("GetExecution of a valid ID: %v", err)
+	}
+}
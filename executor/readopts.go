@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package executor
+
+// AllowedReadOptions lists the pandas reader keyword arguments that may be
+// forwarded through ReadOptions.Options. Anything that can execute code or
+// touch other files (converters, storage_options, ...) is deliberately absent.
+var AllowedReadOptions = map[string]bool{
+	"sep":              true,
+	"delimiter":        true,
+	"header":           true,
+	"skiprows":         true,
+	"nrows":            true,
+	"na_values":        true,
+	"keep_default_na":  true,
+	"thousands":        true,
+	"decimal":          true,
+	"encoding":         true,
+	"comment":          true,
+	"quotechar":        true,
+	"skipinitialspace": true,
+	"sheet_name":       true,
+	"lines":            true,
+	"orient":           true,
+}
+
+// ReadOptions controls how read_data() loads an input file. Zero values keep
+// the reader defaults.
+type ReadOptions struct {
+	// TableIndex selects which table to use when an HTML page contains several.
+	TableIndex int `json:"table_index,omitempty"`
+
+	// Dtypes maps column names to pandas dtypes (e.g. "str" for ZIP codes).
+	Dtypes map[string]string `json:"dtypes,omitempty"`
+
+	// ParseDates lists columns to parse as datetimes.
+	ParseDates []string `json:"parse_dates,omitempty"`
+
+	// Normalize flattens nested JSON with pd.json_normalize. RecordPath and
+	// Meta are passed through as its record_path and meta arguments.
+	Normalize  bool          `json:"normalize,omitempty"`
+	RecordPath []string      `json:"record_path,omitempty"`
+	Meta       []interface{} `json:"meta,omitempty"`
+
+	// NAValues are extra strings read as missing values. KeepDefaultNA false
+	// stops CSV and Excel readers treating "NA", "null", "" etc. as missing.
+	NAValues      []string `json:"na_values,omitempty"`
+	KeepDefaultNA *bool    `json:"keep_default_na,omitempty"`
+
+	// SkipRows and SkipFooter skip lines at the start and end of CSV and
+	// Excel files, such as report titles and footer totals.
+	SkipRows   int `json:"skiprows,omitempty"`
+	SkipFooter int `json:"skipfooter,omitempty"`
+
+	// Usecols limits reading to these columns, in this order. CSV, Excel and
+	// Parquet files load only them; missing ones are skipped with a warning.
+	Usecols []string `json:"usecols,omitempty"`
+
+	// IndexCol moves a column, by name (string) or position (int), into
+	// the index.
+	// DropUnnamedIndex drops a leading "Unnamed: 0" column instead.
+	IndexCol         interface{} `json:"index_col,omitempty"`
+	DropUnnamedIndex bool        `json:"drop_unnamed_index,omitempty"`
+
+	// AllowEmpty reads a header-only file as a frame with no rows instead of
+	// failing. Zero-byte files are always an error.
+	AllowEmpty bool `json:"allow_empty,omitempty"`
+
+	// WarnMixedTypes reports object columns holding values of several types
+	// (numbers and strings, ...). nil uses the server's WARN_MIXED_TYPES.
+	WarnMixedTypes *bool `json:"warn_mixed_types,omitempty"`
+
+	// Options are extra keyword arguments forwarded to the pd.read_* call.
+	// Keys must be in AllowedReadOptions.
+	Options map[string]interface{} `json:"options,omitempty"`
+}
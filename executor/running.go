@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+// Package executor provides tracking of in-flight executions.
+package executor
+
+import (
+	"sort"
+	"time"
+)
+
+// RunningExecution describes an execution that has not finished yet.
+type RunningExecution struct {
+	ExecutionID string
+	ToolName    string
+	Inputs      []string
+	Image       string
+	StartedAt   time.Time
+}
+
+// trackRunning records an in-flight execution and returns a func that
+// removes it once the execution finishes.
+func (e *DockerExecutor) trackRunning(execID string, opts ExecOptions) func() {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImageName
+	}
+
+	e.runningMu.Lock()
+	if e.running == nil {
+		e.running = make(map[string]RunningExecution)
+	}
+	e.running[execID] = RunningExecution{
+		ExecutionID: execID,
+		ToolName:    opts.ToolName,
+		Inputs:      opts.Inputs,
+		Image:       image,
+		StartedAt:   time.Now(),
+	}
+	e.runningMu.Unlock()
+
+	return func() {
+		e.runningMu.Lock()
+		delete(e.running, execID)
+		e.runningMu.Unlock()
+	}
+}
+
+// Running returns the in-flight executions, oldest first.
+func (e *DockerExecutor) Running() []RunningExecution {
+	e.runningMu.Lock()
+	list := make([]RunningExecution, 0, len(e.running))
+	for _, r := range e.running {
+		list = append(list, r)
+	}
+	e.runningMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list
+}
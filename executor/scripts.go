@@ -11,9 +11,132 @@ import (
 	"strings"
 )
 
+// ResultFileName is the file in /output that emit_result() writes to.
+// The executor reads it back after the run and returns it as structured content.
+const ResultFileName = "_result.json"
+
+// SharedDataPath is where DATA_DIR is mounted (read-only) inside containers.
+// Scripts can read shared files directly, e.g. pd.read_csv('/shared/lookup.csv').
+const SharedDataPath = "/shared"
+
+// emitResultHelper defines emit_result(), which writes a machine-readable result
+// separately from the human-readable stdout log.
+const emitResultHelper = `
+def _json_safe(obj):
+    """Convert obj into plain Python types that serialize as strict JSON."""
+    if isinstance(obj, dict):
+        return {str(k): _json_safe(v) for k, v in obj.items()}
+    if isinstance(obj, (list, tuple, set)):
+        return [_json_safe(v) for v in obj]
+    if hasattr(obj, 'to_dict') and hasattr(obj, 'columns'):  # DataFrame
+        return _json_safe(obj.to_dict(orient='records'))
+    if hasattr(obj, 'to_dict'):  # Series
+        return _json_safe(obj.to_dict())
+    if hasattr(obj, 'tolist'):  # numpy arrays, scalars, and pandas Index
+        return _json_safe(obj.tolist())
+    if isinstance(obj, float) and (obj != obj or obj in (float('inf'), float('-inf'))):
+        return None  # NaN/Inf are not valid JSON
+    return obj
+
+def emit_result(obj):
+    """
+    Emit a structured, machine-readable result for this execution.
+    
+    The result is returned to the client as a separate structured block, so
+    stdout can stay a human-readable log. Calling it again replaces the result.
+    
+    Args:
+        obj: JSON-serializable object (dict, list, DataFrame, Series, scalars)
+    
+    Example:
+        emit_result({'rows': len(df), 'mean_price': df['price'].mean()})
+    """
+    import json
+    with open('/output/` + ResultFileName + `', 'w') as f:
+        json.dump(_json_safe(obj), f, default=str, allow_nan=False)
+`
+
+// compressionHelper defines compression_args(), which validates a compression
+// codec for an output format. It is shared by save_output() and save_frame().
+const compressionHelper = `
+# Compression codecs per table format. CSV and JSON files get the codec's
+# file extension appended; Parquet compresses internally.
+OUTPUT_COMPRESSION = {
+    'csv': {'gzip': '.gz', 'bz2': '.bz2', 'xz': '.xz'},
+    'json': {'gzip': '.gz', 'bz2': '.bz2', 'xz': '.xz'},
+    'parquet': {'snappy': '', 'gzip': '', 'zstd': '', 'brotli': '', 'lz4': ''},
+}
+
+def compression_args(path, format, compression):
+    """Return (path, to_* kwargs) for saving a table as format with compression."""
+    if compression is None:
+        return path, {}
+    if compression == 'none':
+        return path, {'compression': None}
+    codecs = OUTPUT_COMPRESSION.get(format, {})
+    if compression not in codecs:
+        supported = ', '.join(['none'] + list(codecs))
+        raise ValueError(f"compression '{compression}' is not supported for {format} output (supported: {supported})")
+    ext = codecs[compression]
+    if ext and not path.endswith(ext):
+        path += ext
+    return path, {'compression': compression}
+
+def file_size_text(path):
+    """Format the size of path for log output."""
+    return size_text(os.path.getsize(path))
+
+def size_text(size):
+    """Format a byte count for log output."""
+    if size < 1024:
+        return f"{size:,} bytes"
+    if size < 1024 * 1024:
+        return f"{size / 1024:.1f} KB"
+    return f"{size / (1024 * 1024):.1f} MB"
+`
+
+// saveDataFrameHelper defines save_dataframe(), which writes the DataFrames
+// passed to save_output(). It needs DEFAULT_OUTPUT_FORMAT and compressionHelper.
+const saveDataFrameHelper = `
+# Table formats save_output() writes DataFrames as, by extension
+DATAFRAME_FORMATS = {
+    'csv': 'csv', 'txt': 'csv',
+    'json': 'json',
+    'parquet': 'parquet', 'pq': 'parquet',
+    'xlsx': 'xlsx', 'excel': 'xlsx', 'xls': 'xlsx',
+}
+
+def save_dataframe(df, path, format, compression):
+    """Save df to path as format (a DATAFRAME_FORMATS key) and return the path
+    written. Without a format, DEFAULT_OUTPUT_FORMAT is used and its extension
+    appended; any other format is an error rather than a silent default."""
+    if not format:
+        format = DEFAULT_OUTPUT_FORMAT
+        path += '.' + format
+    elif format not in DATAFRAME_FORMATS:
+        raise ValueError(f"unsupported extension '.{format}' for saving a DataFrame "
+                         f"(use {', '.join('.' + ext for ext in DATAFRAME_FORMATS)}, or none for {DEFAULT_OUTPUT_FORMAT})")
+    table_format = DATAFRAME_FORMATS[format]
+    path, kwargs = compression_args(path, table_format, compression)
+    if table_format == 'json':
+        df.to_json(path, orient='records', indent=2, **kwargs)
+    elif table_format == 'parquet':
+        df.to_parquet(path, index=False, **kwargs)
+    elif table_format == 'xlsx':
+        df.to_excel(path, index=False, engine='openpyxl')
+    else:
+        df.to_csv(path, index=False, **kwargs)
+    return path
+`
+
 // WrapScript wraps user script with file path mappings and imports.
 // If themeCode is non-empty, it is injected before the user script (e.g., matplotlib rcParams).
-func WrapScript(userScript string, fileMapping map[string]string, themeCode string) string {
+// If debug is true, the mounted inputs are printed before the user script runs.
+// SHARED_DIR points at SharedDataPath; it only exists when DATA_DIR is configured.
+// save_output writes DataFrames without a table extension as defaultFormat.
+// archives maps the original paths of extracted archive inputs to the
+// directories their members are mounted in (see ArchiveMountPath).
+func WrapScript(userScript string, fileMapping, archives map[string]string, themeCode string, debug bool, defaultFormat string) string {
 	var sb strings.Builder
 
 	// Write standard imports
@@ -26,11 +149,26 @@ import numpy as np
 import duckdb
 import polars as pl
 
+# Use a non-interactive matplotlib backend (containers have no display)
+import matplotlib
+matplotlib.use('Agg')
+
 # Suppress warnings for cleaner output
 import warnings
 warnings.filterwarnings('ignore')
-
-# File path mapping (original path -> container path)
+if os.environ.get('WARN_MIXED_TYPES') == '1':
+    # Mixed-type columns are worth knowing about; show pandas' warning
+    warnings.simplefilter('default', pd.errors.DtypeWarning)
+
+# Read-only shared data directory (mounted when the server sets DATA_DIR)
+SHARED_DIR = '` + SharedDataPath + `'
+
+# File path mapping (original path -> container path). Inputs are mounted
+# read-only at /data/input_{i}/{filename}, i being the file's position in the
+# files list; with mount_by_name they are at /data/{filename} instead, except
+# files sharing a name with another input, which keep the indexed path. With
+# extract_archives, the members of .zip/.tar(.gz) inputs are under
+# /data/extracted_{i}/ (see ARCHIVE_DIRS)
 FILE_MAPPING = {
 `)
 
@@ -40,25 +178,77 @@ FILE_MAPPING = {
 	}
 	sb.WriteString("}\n\n")
 
+	// Add extracted archive directories
+	sb.WriteString("# Extracted archive inputs (original path -> directory holding its members),\n")
+	sb.WriteString("# set with extract_archives\nARCHIVE_DIRS = {\n")
+	for original, dir := range archives {
+		sb.WriteString(fmt.Sprintf("    %q: %q,\n", original, dir))
+	}
+	sb.WriteString("}\n\n")
+
 	// Add helper function to resolve paths
-	sb.WriteString(`def resolve_path(path):
-    """Resolve original file path to container path."""
+	sb.WriteString(`def archive_members():
+    """List (archive original path, member path, container path) for extracted archive members."""
+    members = []
+    for orig, root in ARCHIVE_DIRS.items():
+        for dirpath, _, names in os.walk(root):
+            for name in sorted(names):
+                container = os.path.join(dirpath, name)
+                members.append((orig, os.path.relpath(container, root), container))
+    return members
+
+def resolve_path(path):
+    """
+    Resolve original file path to container path. Members of extracted
+    archives resolve as '<archive path>/<member path>' or by file name.
+    """
     if path in FILE_MAPPING:
         return FILE_MAPPING[path]
+    for orig, root in ARCHIVE_DIRS.items():
+        if path.startswith(orig + '/'):
+            return os.path.join(root, path[len(orig) + 1:])
     # Check if it's already a container path
-    if path.startswith('/data/'):
+    if path.startswith('/data/') or path.startswith(SHARED_DIR + '/'):
         return path
     # Try to find by basename
     basename = os.path.basename(path)
     for orig, container in FILE_MAPPING.items():
         if os.path.basename(container) == basename:
             return container
+    for orig, member, container in archive_members():
+        if member == path or os.path.basename(member) == basename:
+            return container
     return path
 
+def list_inputs():
+    """
+    List the files mounted for this execution.
+    
+    Returns:
+        list: One dict per input with 'original' (the path to pass to resolve_path),
+              'container' (where the file is mounted) and 'size' (bytes, or None if missing).
+              Extracted archive members are listed after the inputs, with 'archive'
+              set to the archive's original path
+    
+    Example:
+        for f in list_inputs():
+            print(f['original'], '->', f['container'])
+    """
+    inputs = []
+    for orig, container in FILE_MAPPING.items():
+        size = os.path.getsize(container) if os.path.exists(container) else None
+        inputs.append({'original': orig, 'container': container, 'size': size})
+    for orig, member, container in archive_members():
+        inputs.append({'original': f"{orig}/{member}", 'container': container, 'size': os.path.getsize(container), 'archive': orig})
+    return inputs
+
 # Output directory for saving results
 OUTPUT_DIR = '/output'
 
-def save_output(obj, filename, format=None):
+# Format for DataFrames saved without a table extension
+DEFAULT_OUTPUT_FORMAT = '` + defaultFormat + `'
+` + compressionHelper + saveDataFrameHelper + `
+def save_output(obj, filename, format=None, compression=None):
     """
     Save various types of objects to output directory.
     
@@ -80,6 +270,9 @@ def save_output(obj, filename, format=None):
              - bytes: Binary data
         filename: Output filename (format auto-detected from extension)
         format: Optional format override (csv, json, png, etc.)
+        compression: Optional codec for DataFrames: gzip, bz2 or xz for csv/json
+                     (the extension is appended, e.g. data.csv.gz), or snappy,
+                     gzip, zstd, brotli or lz4 for parquet
     
     Returns:
         str: Path to saved file
@@ -89,6 +282,7 @@ def save_output(obj, filename, format=None):
         save_output(fig, 'plot.png')             # Figure to PNG
         save_output(plt, 'current_plot.png')     # Current pyplot figure
         save_output({'key': 'val'}, 'data.json') # Dict to JSON
+        save_output(df, 'data.csv', compression='gzip')  # data.csv.gz
         
         # For PDFs with reportlab
         from io import BytesIO
@@ -106,18 +300,12 @@ def save_output(obj, filename, format=None):
     if format is None:
         format = os.path.splitext(filename)[1].lstrip('.').lower()
     
+    if compression is not None and compression != 'none' and not hasattr(obj, 'to_csv'):
+        raise ValueError("compression is only supported when saving DataFrames")
+    
     # Handle pandas DataFrame
     if hasattr(obj, 'to_csv'):  # Duck typing for DataFrame
-        if format in ['csv', 'txt']:
-            obj.to_csv(path, index=False)
-        elif format == 'json':
-            obj.to_json(path, orient='records', indent=2)
-        elif format in ['parquet', 'pq']:
-            obj.to_parquet(path, index=False)
-        elif format in ['xlsx', 'excel', 'xls']:
-            obj.to_excel(path, index=False, engine='openpyxl')
-        else:
-            obj.to_csv(path, index=False)  # Default to CSV
+        path = save_dataframe(obj, path, format, compression)
     
     # Handle matplotlib figure or pyplot module
     elif hasattr(obj, 'savefig'):  # matplotlib figure
@@ -169,7 +357,7 @@ def save_output(obj, filename, format=None):
     else:
         raise TypeError(f"Unsupported type for save_output: {type(obj)}. Supported: DataFrame, Figure, plt module, dict, list, str, bytes, BytesIO/file-like objects")
     
-    print(f"Saved output to: {path}")
+    print(f"Saved output to: {path} ({file_size_text(path)})")
     return path
 
 def save_base64(base64_string, filename):
@@ -205,8 +393,48 @@ def save_base64(base64_string, filename):
     print(f"Saved base64 output to: {path}")
     return path
 
-# ===== USER SCRIPT BEGINS =====
+def save_figure(fig, filename):
+    """
+    Save a matplotlib figure to the output directory.
+    
+    Args:
+        fig: matplotlib Figure to save, or None to save the current figure
+        filename: Output filename (format auto-detected from extension, e.g. png, svg, pdf)
+    
+    Returns:
+        str: Path to saved file
+    
+    Example:
+        import matplotlib.pyplot as plt
+        plt.plot([1, 2, 3], [4, 5, 6])
+        save_figure(None, 'line.png')
+    """
+    import matplotlib.pyplot as plt
+    
+    if fig is None:
+        fig = plt.gcf()
+    if not fig.get_axes():
+        raise ValueError("No active matplotlib figure to save. Create a plot before calling save_figure().")
+    
+    path = os.path.join(OUTPUT_DIR, filename)
+    fig.savefig(path, dpi=300, bbox_inches='tight')
+    
+    print(f"Saved figure to: {path}")
+    return path
+`)
+
+	sb.WriteString(emitResultHelper)
+	sb.WriteString("\n# ===== USER SCRIPT BEGINS =====\n")
+
+	// Print mounted inputs so the script author can see what is available
+	if debug {
+		sb.WriteString(`print("=== Mounted Inputs ===")
+for _f in list_inputs():
+    print(f"  {_f['original']} -> {_f['container']} ({_f['size']} bytes)")
+print()
+
 `)
+	}
 
 	// Inject chart theme code if provided
 	if themeCode != "" {
@@ -222,7 +450,7 @@ def save_base64(base64_string, filename):
 }
 
 // ReadDataFrameScript generates a script to read and describe a DataFrame.
-func ReadDataFrameScript(containerPath string, previewRows int) string {
+func ReadDataFrameScript(containerPath string, previewRows, previewCols int, opts ReadOptions) string {
 	return fmt.Sprintf(`#!/usr/bin/env python3
 import sys
 import os
@@ -233,25 +461,23 @@ import numpy as np
 # Suppress warnings
 import warnings
 warnings.filterwarnings('ignore')
-
+%s%s
 file_path = %q
 preview_rows = %d
+preview_cols = %d
+read_options = %s
 
-# Determine file type and read accordingly
-ext = os.path.splitext(file_path)[1].lower()
+# Keep previews of wide frames readable
+pd.set_option('display.max_columns', preview_cols)
+pd.set_option('display.width', 200)
+pd.set_option('display.max_colwidth', 50)
 
 try:
-    if ext == '.csv':
-        df = pd.read_csv(file_path)
-    elif ext in ['.xlsx', '.xls']:
-        df = pd.read_excel(file_path)
-    elif ext == '.json':
-        df = pd.read_json(file_path)
-    elif ext == '.parquet':
-        df = pd.read_parquet(file_path)
-    else:
-        # Try CSV as default
-        df = pd.read_csv(file_path)
+    df = read_data(file_path, read_options)
+    labels = read_labels(file_path)
+    
+    preview_df = df.iloc[:, :preview_cols]
+    hidden_cols = df.shape[1] - preview_df.shape[1]
     
     # Collect info
     result = {
@@ -260,12 +486,64 @@ try:
         "dtypes": {col: str(dtype) for col, dtype in df.dtypes.items()},
         "memory_usage_mb": df.memory_usage(deep=True).sum() / (1024 * 1024),
         "null_counts": df.isnull().sum().to_dict(),
-        "preview": df.head(preview_rows).to_dict(orient='records')
+        "preview": preview_df.head(preview_rows).to_dict(orient='records')
     }
+    if hidden_cols:
+        result["preview_hidden_columns"] = hidden_cols
+    if labels:
+        result["labels"] = labels
+    if 'html_table_count' in df.attrs:
+        result["html_tables"] = {"found": df.attrs['html_table_count'], "table_index": read_options.get('table_index', 0)}
+    if df.attrs.get('renamed_duplicates'):
+        result["duplicate_columns"] = df.attrs['renamed_duplicates']
+    if df.attrs.get('detected_format'):
+        result["detected_format"] = df.attrs['detected_format']
+    if df.attrs.get('mixed_type_columns'):
+        result["mixed_type_columns"] = df.attrs['mixed_type_columns']
+    if df.attrs.get('missing_usecols'):
+        result["missing_usecols"] = df.attrs['missing_usecols']
+    if df.attrs.get('skipped'):
+        result["skipped"] = df.attrs['skipped']
+    if read_options.get('index_col') is not None:
+        result["index"] = str(df.index.name)
+    if df.attrs.get('unnamed_index'):
+        result["unnamed_index_column"] = df.attrs['unnamed_index']
+    if df.attrs.get('dropped_unnamed_index'):
+        result["dropped_columns"] = [{"column": df.attrs['dropped_unnamed_index'], "reason": "saved index column (drop_unnamed_index)"}]
+    if df.attrs.get('json_normalized'):
+        result["normalized"] = {
+            "record_path": read_options.get('record_path'),
+            "meta": read_options.get('meta'),
+            "flattened_columns": [str(c) for c in df.columns if '.' in str(c)],
+        }
     
     print("=== DataFrame Info ===")
     print(f"Shape: {result['shape']['rows']} rows × {result['shape']['columns']} columns")
     print(f"Memory Usage: {result['memory_usage_mb']:.2f} MB")
+    if 'html_tables' in result:
+        found = result['html_tables']['found']
+        print(f"HTML Tables: {found} found, showing table_index {result['html_tables']['table_index']}")
+        if found > 1:
+            print(f"  (pass table_index 0-{found - 1} to choose another table)")
+    if 'skipped' in result:
+        print(f"Skipped: {result['skipped']['rows']} leading and {result['skipped']['footer']} trailing line(s); shape after skipping: {result['shape']['rows']} rows × {result['shape']['columns']} columns")
+    if 'index' in result:
+        print(f"Index: {result['index']}")
+    if 'unnamed_index_column' in result:
+        print(f"Note: '{result['unnamed_index_column']}' looks like an index saved by to_csv(index=True); pass index_col: 0 to use it as the index, or drop_unnamed_index: true to drop it")
+    if 'dropped_columns' in result:
+        print(f"Dropped saved index column: {result['dropped_columns'][0]['column']}")
+    if 'detected_format' in result:
+        print(f"Format: read as {result['detected_format']} based on the file's content, not its extension")
+    if 'normalized' in result:
+        flattened = result['normalized']['flattened_columns']
+        print(f"JSON normalized: {len(result['columns'])} columns, {len(flattened)} flattened from nested fields")
+    if 'duplicate_columns' in result:
+        print()
+        print("Warning: the header repeats column names; pandas renamed the repeats:")
+        for dup in result['duplicate_columns']:
+            print(f"  '{dup['column']}' -> {', '.join(repr(c) for c in dup['renamed'])}")
+        print("  Refer to the renamed columns in later operations; the original name selects only the first.")
     print()
     print("=== Columns ===")
     for col in result['columns']:
@@ -273,20 +551,393 @@ try:
         nulls = result['null_counts'][col]
         print(f"  {col}: {dtype} ({nulls} nulls)")
     print()
+    if labels:
+        print("=== Labels ===")
+        for col in result['columns']:
+            var_label = labels['variable_labels'].get(col)
+            val_labels = labels['value_labels'].get(col)
+            if var_label or val_labels:
+                print(f"  {col}: {var_label or ''}")
+                for code, label in (val_labels or {}).items():
+                    print(f"      {code} = {label}")
+        print()
     print("=== Preview ===")
-    print(df.head(preview_rows).to_string())
+    print(preview_df.head(preview_rows).to_string())
+    if hidden_cols:
+        print(f"... {hidden_cols} more columns (raise preview_cols to show them)")
     print()
     print("=== JSON Output ===")
     print(json.dumps(result, default=str))
+    emit_result(result)
     
 except Exception as e:
     print(f"Error reading file: {e}", file=sys.stderr)
     sys.exit(1)
-`, containerPath, previewRows)
+`, emitResultHelper, readDataHelper, containerPath, previewRows, previewCols, pyValue(opts))
+}
+
+// analysisHelper defines run_analysis(), which prints one analyze_data analysis
+// of a DataFrame and returns its data for emit_result.
+const analysisHelper = `
+# Aggregations and printed tail length of rolling analyses
+ROLLING_AGGS = ['mean', 'sum', 'std', 'min', 'max']
+ROLLING_TAIL = 10
+
+def run_analysis(df, analysis_type, columns=None, group_by=None, params=None):
+    """
+    Run a single analysis on df, printing the report and returning its data.
+    params holds analysis-specific settings such as histogram bins.
+    """
+    params = params or {}
+    # Filter columns if specified
+    if columns:
+        available_cols = [c for c in columns if c in df.columns]
+        if not available_cols:
+            raise ValueError(f"None of the specified columns exist. Available: {list(df.columns)}")
+        df_subset = df[available_cols]
+    else:
+        df_subset = df
+
+    if analysis_type == 'describe':
+        print("=== Statistical Description ===")
+        desc = df_subset.describe(include='all')
+        print(desc.to_string())
+        return desc.to_dict()
+
+    elif analysis_type == 'info':
+        print("=== DataFrame Info ===")
+        print(f"Shape: {df.shape[0]} rows × {df.shape[1]} columns")
+        print(f"\nColumn Types:")
+        for col in df.columns:
+            print(f"  {col}: {df[col].dtype}")
+        print(f"\nMemory Usage: {df.memory_usage(deep=True).sum() / (1024*1024):.2f} MB")
+        print(f"\nNull Values:")
+        null_counts = df.isnull().sum()
+        for col, count in null_counts.items():
+            if count > 0:
+                print(f"  {col}: {count} ({count/len(df)*100:.1f}%)")
+        return {
+            "shape": {"rows": df.shape[0], "columns": df.shape[1]},
+            "dtypes": {col: str(dtype) for col, dtype in df.dtypes.items()},
+            "null_counts": null_counts.to_dict(),
+        }
+
+    elif analysis_type == 'corr':
+        numeric_df = df_subset.select_dtypes(include=[np.number])
+        if numeric_df.empty:
+            raise ValueError("No numeric columns found for correlation analysis")
+        max_cols = params['max_corr_columns']
+        total = numeric_df.shape[1]
+        if total > max_cols:
+            numeric_df = numeric_df.iloc[:, :max_cols]
+        print("=== Correlation Matrix ===")
+        corr = numeric_df.corr()
+        print(corr.to_string())
+        data = corr.to_dict()
+        if total > max_cols:
+            print(f"... [truncated: showing the first {max_cols} of {total} numeric columns; pass columns to choose others]")
+            data["_truncated"] = {"shown": max_cols, "total": total}
+        return data
+
+    elif analysis_type == 'corr_sig':
+        from scipy import stats
+        method = params.get('method') or 'pearson'
+        if method not in ('pearson', 'spearman'):
+            raise ValueError(f"method must be 'pearson' or 'spearman', got {method!r}")
+        alpha = params.get('alpha') or 0.05
+        if not 0 < alpha < 1:
+            raise ValueError(f"alpha must be between 0 and 1, got {alpha!r}")
+        numeric_df = df_subset.select_dtypes(include=[np.number])
+        if numeric_df.empty:
+            raise ValueError("No numeric columns found for correlation analysis")
+        max_cols = params['max_corr_columns']
+        total = numeric_df.shape[1]
+        if total > max_cols:
+            numeric_df = numeric_df.iloc[:, :max_cols]
+        # A constant column has no defined correlation
+        constant = [str(c) for c in numeric_df.columns if numeric_df[c].nunique() < 2]
+        cols = [c for c in numeric_df.columns if str(c) not in constant]
+        test = stats.pearsonr if method == 'pearson' else stats.spearmanr
+        pairs = []
+        for i, x in enumerate(cols):
+            for y in cols[i + 1:]:
+                both = numeric_df[[x, y]].dropna()
+                pair = {"x": str(x), "y": str(y), "n": len(both), "r": None, "p_value": None, "significant": False}
+                if len(both) < 3:
+                    pair["note"] = "fewer than 3 rows with both values"
+                elif both[x].nunique() < 2 or both[y].nunique() < 2:
+                    pair["note"] = "constant where both values are present"
+                else:
+                    r, p = test(both[x], both[y])
+                    pair.update(r=float(r), p_value=float(p), significant=bool(p < alpha))
+                pairs.append(pair)
+        pairs.sort(key=lambda p: (p["p_value"] is None, p["p_value"] or 0))
+
+        label = method.capitalize()
+        print(f"=== {label} Correlation Significance (alpha = {alpha}) ===")
+        significant = [p for p in pairs if p["significant"]]
+        if significant:
+            table = pd.DataFrame(significant)[["x", "y", "r", "p_value", "n"]]
+            print(table.to_string(index=False))
+        else:
+            print("No significant correlations")
+        print(f"\n{len(significant)} of {len(pairs)} column pairs significant at p < {alpha}")
+        untested = [p for p in pairs if p["p_value"] is None]
+        if untested:
+            print(f"{len(untested)} pair(s) not tested (too few rows or constant values)")
+        if constant:
+            print(f"Skipped constant columns: {', '.join(constant)}")
+        data = {"method": method, "alpha": alpha, "pairs": pairs}
+        if constant:
+            data["constant_columns"] = constant
+        if total > max_cols:
+            print(f"... [truncated: showing the first {max_cols} of {total} numeric columns; pass columns to choose others]")
+            data["_truncated"] = {"shown": max_cols, "total": total}
+        return data
+
+    elif analysis_type == 'decompose':
+        from statsmodels.tsa.seasonal import seasonal_decompose
+        time_col = params.get('time_column')
+        value_col = params.get('value_column')
+        if not time_col or not value_col:
+            raise ValueError("decompose requires time_column and value_column")
+        for col in (time_col, value_col):
+            if col not in df.columns:
+                raise ValueError(f"Column '{col}' not found. Available: {list(df.columns)}")
+        period = params.get('period')
+        if not isinstance(period, int) or period < 2:
+            raise ValueError(f"decompose requires period, the number of observations per seasonal cycle (an integer >= 2), got {period!r}")
+        model = params.get('model') or 'additive'
+        if model not in ('additive', 'multiplicative'):
+            raise ValueError(f"model must be 'additive' or 'multiplicative', got {model!r}")
+
+        times = pd.to_datetime(df[time_col], errors='coerce')
+        unparsed = times.isna() & df[time_col].notna()
+        if unparsed.any():
+            raise ValueError(f"Column '{time_col}' has {int(unparsed.sum())} value(s) that can't be parsed as datetimes, e.g. {df.loc[unparsed, time_col].iloc[0]!r}")
+        values = pd.to_numeric(df[value_col], errors='coerce')
+        if values.notna().sum() == 0:
+            raise ValueError(f"Column '{value_col}' has no numeric values")
+        series = pd.Series(values.values, index=times)
+        series = series[series.index.notna()].sort_index()
+        duplicates = int(series.index.duplicated().sum())
+        if duplicates:
+            series = series.groupby(level=0).mean()
+        missing = int(series.isna().sum())
+        if missing:
+            series = series.interpolate(limit_direction='both')
+        if len(series) < 2 * period:
+            raise ValueError(f"Series has {len(series)} observations; decompose with period {period} needs at least {2 * period} (two full cycles)")
+        if model == 'multiplicative' and (series <= 0).any():
+            raise ValueError("multiplicative model requires all values to be positive; use model 'additive'")
+
+        parts = seasonal_decompose(series, model=model, period=period)
+        components = {"observed": parts.observed, "trend": parts.trend, "seasonal": parts.seasonal, "resid": parts.resid}
+        print(f"=== Seasonal Decomposition: {value_col} by {time_col} ({model}, period {period}) ===")
+        print(f"Observations: {len(series)} ({series.index.min()} to {series.index.max()})")
+        if duplicates:
+            print(f"Note: averaged {duplicates} row(s) sharing a timestamp")
+        if missing:
+            print(f"Note: interpolated {missing} missing value(s)")
+        summary = pd.DataFrame({name: s.describe()[['count', 'mean', 'std', 'min', 'max']] for name, s in components.items()}).T
+        print(summary.to_string())
+        # Strength of trend and seasonality (0 = none, 1 = dominates the residual)
+        resid = parts.resid.dropna()
+        strength = {}
+        if model == 'additive' and len(resid) > 1:
+            for name, component in (("trend", parts.trend), ("seasonal", parts.seasonal)):
+                combined = (component + parts.resid).dropna()
+                if combined.var() > 0:
+                    strength[name] = float(max(0.0, 1 - resid.var() / combined.var()))
+            if strength:
+                print("\nStrength: " + ", ".join(f"{name} {value:.2f}" for name, value in strength.items()))
+        data = {
+            "model": model,
+            "period": period,
+            "observations": len(series),
+            "components": summary.to_dict(orient='index'),
+            "seasonal_pattern": parts.seasonal.iloc[:period].tolist(),
+        }
+        if strength:
+            data["strength"] = strength
+        if duplicates:
+            data["averaged_duplicates"] = duplicates
+        if missing:
+            data["interpolated"] = missing
+        if params.get('plot'):
+            import matplotlib.pyplot as plt
+            fig = parts.plot()
+            fig.set_size_inches(10, 8)
+            path = '/output/decompose.png'
+            fig.savefig(path, dpi=150, bbox_inches='tight')
+            plt.close(fig)
+            print(f"Saved plot to: {path}")
+            data["plot"] = os.path.basename(path)
+        return data
+
+    elif analysis_type == 'rolling':
+        window = params.get('window')
+        if isinstance(window, float) and window.is_integer():
+            window = int(window)
+        window = str(window if window is not None else '').strip()
+        if not window:
+            raise ValueError("rolling requires window: a number of rows (e.g. 7), or with time_column a time span (e.g. '7D')")
+        agg = params.get('agg') or 'mean'
+        if agg not in ROLLING_AGGS:
+            raise ValueError(f"agg must be one of {', '.join(ROLLING_AGGS)}, got {agg!r}")
+        time_col = params.get('time_column')
+        if time_col and time_col not in df.columns:
+            raise ValueError(f"Column '{time_col}' not found. Available: {list(df.columns)}")
+        numeric_df = df_subset.select_dtypes(include=[np.number]).drop(columns=[time_col] if time_col else [], errors='ignore')
+        if numeric_df.empty:
+            raise ValueError("No numeric columns found for rolling analysis")
+        rows = int(window) if window.isdigit() else None
+        if rows == 0:
+            raise ValueError("window must be at least 1 row")
+        if time_col:
+            times = pd.to_datetime(df[time_col], errors='coerce')
+            unparsed = times.isna() & df[time_col].notna()
+            if unparsed.any():
+                raise ValueError(f"Column '{time_col}' has {int(unparsed.sum())} value(s) that can't be parsed as datetimes, e.g. {df.loc[unparsed, time_col].iloc[0]!r}")
+            frame = numeric_df.set_axis(times, axis=0)
+            frame = frame[frame.index.notna()].sort_index()
+        elif rows is None:
+            raise ValueError(f"time-based window '{window}' requires time_column; without one, window is a number of rows")
+        else:
+            frame = numeric_df
+        try:
+            rolled = getattr(frame.rolling(rows or window), agg)()
+        except ValueError as e:
+            raise ValueError(f"invalid window '{window}': {e}")
+
+        by = f", by {time_col}" if time_col else ""
+        unit = "rows" if rows else "time span"
+        print(f"=== Rolling {agg} (window {window} {unit}{by}) ===")
+        skipped = [str(c) for c in df_subset.columns if c not in numeric_df.columns and c != time_col]
+        if skipped:
+            print(f"(Skipped non-numeric columns: {', '.join(skipped)})")
+        tail = rolled.tail(ROLLING_TAIL)
+        print(f"Last {len(tail)} of {len(rolled)} rows:")
+        print(tail.to_string())
+        summary = rolled.describe().T[['count', 'mean', 'std', 'min', 'max']]
+        print("\nSummary of rolling values:")
+        print(summary.to_string())
+        data = {
+            "window": window,
+            "agg": agg,
+            "time_based": rows is None,
+            "tail": {str(k): v for k, v in tail.to_dict(orient='index').items()},
+            "summary": summary.to_dict(orient='index'),
+        }
+        if skipped:
+            data["_skipped"] = skipped
+        return data
+
+    elif analysis_type == 'value_counts':
+        max_values = params['max_value_counts']
+        print("=== Value Counts ===")
+        data = {}
+        truncated = {}
+        for col in df_subset.columns:
+            print(f"\n--- {col} ---")
+            vc = df_subset[col].value_counts()
+            if len(vc) > max_values:
+                print(f"(Showing top {max_values} of {len(vc)} unique values)")
+                print(vc.head(max_values).to_string())
+                print(f"... [truncated: {len(vc) - max_values} more values]")
+                truncated[str(col)] = len(vc)
+            else:
+                print(vc.to_string())
+            data[col] = vc.head(max_values).to_dict()
+        if truncated:
+            data["_truncated"] = truncated
+        return data
+
+    elif analysis_type == 'groupby':
+        if not group_by:
+            raise ValueError("group_by parameter required for groupby analysis")
+        if group_by not in df.columns:
+            raise ValueError(f"Column '{group_by}' not found. Available: {list(df.columns)}")
+
+        print(f"=== Group By: {group_by} ===")
+        numeric_cols = df_subset.select_dtypes(include=[np.number]).columns.tolist()
+        if not numeric_cols:
+            print("No numeric columns to aggregate")
+            sizes = df.groupby(group_by).size()
+            print(sizes.to_string())
+            return sizes.to_dict()
+        grouped = df.groupby(group_by)[numeric_cols].agg(['mean', 'sum', 'count'])
+        print(grouped.to_string())
+        return grouped.to_dict()
+
+    elif analysis_type == 'histogram':
+        bins = params.get('bins') or 10
+        if not isinstance(bins, int) or not 1 <= bins <= 100:
+            raise ValueError(f"bins must be an integer between 1 and 100, got {bins!r}")
+        numeric_df = df_subset.select_dtypes(include=[np.number])
+        if numeric_df.empty:
+            raise ValueError("No numeric columns found for histogram")
+        print(f"=== Histograms ({bins} bins) ===")
+        skipped = [str(c) for c in df_subset.columns if c not in numeric_df.columns]
+        if skipped:
+            print(f"(Skipped non-numeric columns: {', '.join(skipped)})")
+        bar_width = 40
+        data = {}
+        for col in numeric_df.columns:
+            values = numeric_df[col].astype(float)
+            finite = values[np.isfinite(values)]
+            nulls = int(values.isna().sum())
+            print(f"\n--- {col} ({len(finite)} values" + (f", {nulls} null" if nulls else "") + ") ---")
+            if finite.empty:
+                print("  (no finite values)")
+                continue
+            counts, edges = np.histogram(finite, bins=bins)
+            labels = [f"[{edges[i]:.4g}, {edges[i + 1]:.4g}" + ("]" if i == len(counts) - 1 else ")") for i in range(len(counts))]
+            width = max(len(label) for label in labels)
+            peak = counts.max() or 1
+            for label, count in zip(labels, counts):
+                bar = '#' * int(round(count / peak * bar_width))
+                if count and not bar:
+                    bar = '.'  # Nonzero bins stay visible next to a tall peak
+                print(f"  {label:>{width}} | {bar} {count}")
+            data[str(col)] = {"edges": edges.tolist(), "counts": counts.tolist(), "nulls": nulls}
+        if skipped:
+            data["_skipped"] = skipped
+        return data
+
+    raise ValueError(f"Unknown analysis type '{analysis_type}'")
+`
+
+// AnalysisParams holds settings specific to some analyze_data analysis types.
+type AnalysisParams struct {
+	Bins int `json:"bins,omitempty"` // histogram bin count (default 10)
+
+	// corr_sig correlation method ("pearson" or "spearman") and significance
+	// threshold (default 0.05)
+	Method string  `json:"method,omitempty"`
+	Alpha  float64 `json:"alpha,omitempty"`
+
+	// decompose series, seasonal period in observations, model ("additive"
+	// or "multiplicative") and whether to save decompose.png
+	TimeColumn  string `json:"time_column,omitempty"`
+	ValueColumn string `json:"value_column,omitempty"`
+	Period      int    `json:"period,omitempty"`
+	Model       string `json:"model,omitempty"`
+	Plot        bool   `json:"plot,omitempty"`
+
+	// rolling window, as a row count ("7") or with TimeColumn a time span
+	// ("7D"), and aggregation (default mean)
+	Window string `json:"window,omitempty"`
+	Agg    string `json:"agg,omitempty"`
+
+	// Output caps set by the server from its config; the scripts have no
+	// defaults of their own, so both must be positive
+	MaxCorrColumns int `json:"max_corr_columns"` // corr columns
+	MaxValueCounts int `json:"max_value_counts"` // value_counts values per column
 }
 
 // AnalyzeDataScript generates a script to analyze data.
-func AnalyzeDataScript(containerPath string, analysisType string, columns []string, groupBy string) string {
+func AnalyzeDataScript(containerPath string, analysisType string, columns []string, groupBy string, params AnalysisParams, opts ReadOptions) string {
 	columnsJSON := "None"
 	if len(columns) > 0 {
 		columnsJSON = fmt.Sprintf("%q", strings.Join(columns, `", "`))
@@ -308,109 +959,612 @@ import numpy as np
 # Suppress warnings
 import warnings
 warnings.filterwarnings('ignore')
-
+%s%s%s
 file_path = %q
 analysis_type = %q
 columns = %s
 group_by = %s
+params = %s
+read_options = %s
 
 # Read file
-ext = os.path.splitext(file_path)[1].lower()
 try:
-    if ext == '.csv':
-        df = pd.read_csv(file_path)
-    elif ext in ['.xlsx', '.xls']:
-        df = pd.read_excel(file_path)
-    elif ext == '.json':
-        df = pd.read_json(file_path)
-    elif ext == '.parquet':
-        df = pd.read_parquet(file_path)
-    else:
-        df = pd.read_csv(file_path)
+    df = read_data(file_path, read_options)
 except Exception as e:
     print(f"Error reading file: {e}", file=sys.stderr)
     sys.exit(1)
 
-# Filter columns if specified
-if columns:
-    available_cols = [c for c in columns if c in df.columns]
-    if not available_cols:
-        print(f"Error: None of the specified columns exist. Available: {list(df.columns)}", file=sys.stderr)
-        sys.exit(1)
-    df_subset = df[available_cols]
-else:
-    df_subset = df
+result = {"analysis_type": analysis_type}
 
 try:
-    if analysis_type == 'describe':
-        print("=== Statistical Description ===")
-        print(df_subset.describe(include='all').to_string())
-        
-    elif analysis_type == 'info':
-        print("=== DataFrame Info ===")
-        print(f"Shape: {df.shape[0]} rows × {df.shape[1]} columns")
-        print(f"\nColumn Types:")
-        for col in df.columns:
-            print(f"  {col}: {df[col].dtype}")
-        print(f"\nMemory Usage: {df.memory_usage(deep=True).sum() / (1024*1024):.2f} MB")
-        print(f"\nNull Values:")
-        null_counts = df.isnull().sum()
-        for col, count in null_counts.items():
-            if count > 0:
-                print(f"  {col}: {count} ({count/len(df)*100:.1f}%%)")
-                
-    elif analysis_type == 'corr':
-        numeric_df = df_subset.select_dtypes(include=[np.number])
-        if numeric_df.empty:
-            print("Error: No numeric columns found for correlation analysis", file=sys.stderr)
-            sys.exit(1)
-        print("=== Correlation Matrix ===")
-        print(numeric_df.corr().to_string())
-        
-    elif analysis_type == 'value_counts':
-        print("=== Value Counts ===")
-        for col in df_subset.columns:
-            print(f"\n--- {col} ---")
-            vc = df_subset[col].value_counts()
-            if len(vc) > 20:
-                print(f"(Showing top 20 of {len(vc)} unique values)")
-                print(vc.head(20).to_string())
+    result["data"] = run_analysis(df, analysis_type, columns, group_by, params)
+except Exception as e:
+    print(f"Error during analysis: {e}", file=sys.stderr)
+    sys.exit(1)
+
+emit_result(result)
+`, emitResultHelper, readDataHelper, analysisHelper, containerPath, analysisType, columnsJSON, groupByStr, pyValue(params), pyValue(opts))
+}
+
+// transformHelper defines apply_operation(), which applies one transform_data
+// operation to a DataFrame and returns the result.
+const transformHelper = compressionHelper + `
+def apply_operation(df, op):
+    """Apply a single declarative operation to df and return the new frame."""
+    op_type = op.get('type')
+
+    if op_type == 'filter':
+        column = op['column']
+        operator = op['operator']
+        value = op['value']
+
+        if operator == '==':
+            df = df[df[column] == value]
+        elif operator == '!=':
+            df = df[df[column] != value]
+        elif operator == '>':
+            df = df[df[column] > value]
+        elif operator == '>=':
+            df = df[df[column] >= value]
+        elif operator == '<':
+            df = df[df[column] < value]
+        elif operator == '<=':
+            df = df[df[column] <= value]
+        elif operator == 'contains':
+            df = df[df[column].astype(str).str.contains(str(value), na=False)]
+        elif operator == 'isin':
+            df = df[df[column].isin(value if isinstance(value, list) else [value])]
+        else:
+            print(f"  Warning: Unknown operator '{operator}'")
+        print(f"  Filtered on {column} {operator} {value}: {len(df)} rows remaining")
+
+    elif op_type == 'select':
+        columns = op['columns']
+        df = df[columns]
+        print(f"  Selected columns: {columns}")
+
+    elif op_type == 'drop':
+        columns = op['columns']
+        df = df.drop(columns=columns)
+        print(f"  Dropped columns: {columns}")
+
+    elif op_type == 'sort':
+        column = op['column']
+        ascending = op.get('ascending', True)
+        df = df.sort_values(by=column, ascending=ascending)
+        print(f"  Sorted by {column} ({'ascending' if ascending else 'descending'})")
+
+    elif op_type == 'rename':
+        mapping = op['mapping']
+        df = df.rename(columns=mapping)
+        print(f"  Renamed columns: {mapping}")
+
+    elif op_type == 'dropna':
+        subset = op.get('subset')
+        if subset:
+            df = df.dropna(subset=subset)
+            print(f"  Dropped NA in columns: {subset}, {len(df)} rows remaining")
+        else:
+            df = df.dropna()
+            print(f"  Dropped all rows with NA: {len(df)} rows remaining")
+
+    elif op_type == 'fillna':
+        column = op.get('column')
+        fill_value = op.get('fill_value', 0)
+        if column:
+            df[column] = df[column].fillna(fill_value)
+            print(f"  Filled NA in {column} with {fill_value}")
+        else:
+            df = df.fillna(fill_value)
+            print(f"  Filled all NA with {fill_value}")
+
+    elif op_type == 'astype':
+        column = op['column']
+        dtype = op['dtype']
+        df[column] = df[column].astype(dtype)
+        print(f"  Converted {column} to {dtype}")
+
+    elif op_type == 'head':
+        n = op.get('n', 5)
+        df = df.head(n)
+        print(f"  Took first {n} rows")
+
+    elif op_type == 'tail':
+        n = op.get('n', 5)
+        df = df.tail(n)
+        print(f"  Took last {n} rows")
+
+    elif op_type == 'slice':
+        start = op.get('start')
+        stop = op.get('stop')
+        step = op.get('step')
+        n = len(df)
+        for key, value in (('start', start), ('stop', stop), ('step', step)):
+            if value is not None and not isinstance(value, int):
+                raise ValueError(f"slice {key} must be an integer, got {value!r}")
+        if step == 0:
+            raise ValueError("slice step cannot be zero")
+        if start is not None and not -n <= start < max(n, 1):
+            raise ValueError(f"slice start {start} is out of range for {n} rows")
+        if stop is not None and not -n <= stop <= n:
+            raise ValueError(f"slice stop {stop} is out of range for {n} rows")
+        df = df.iloc[start:stop:step]
+        print(f"  Sliced rows [{start}:{stop}:{step}]: {len(df)} rows")
+
+    elif op_type == 'sample':
+        n = op.get('n')
+        frac = op.get('frac')
+        replace = bool(op.get('replace', False))
+        random_state = op.get('random_state')
+        # Without replacement a sample can't be larger than the frame
+        if n:
+            df = df.sample(n=n if replace else min(n, len(df)), replace=replace, random_state=random_state)
+            print(f"  Sampled {len(df)} rows" + (" with replacement" if replace else ""))
+        elif frac:
+            df = df.sample(frac=frac if replace else min(frac, 1), replace=replace, random_state=random_state)
+            print(f"  Sampled {len(df)} rows ({frac*100}%)" + (" with replacement" if replace else ""))
+
+    elif op_type == 'unique':
+        columns = op.get('columns')
+        if columns:
+            df = df.drop_duplicates(subset=columns)
+        else:
+            df = df.drop_duplicates()
+        print(f"  Removed duplicates: {len(df)} rows remaining")
+
+    elif op_type == 'drop_constant':
+        columns = op.get('columns') or list(df.columns)
+        dropped = [c for c in columns if df[c].nunique(dropna=False) <= 1]
+        for c in dropped:
+            value = df[c].iloc[0] if len(df) else None
+            record_dropped(c, f"constant (every value is {value!r})")
+        df = df.drop(columns=dropped)
+        print(f"  Dropped {len(dropped)} constant column(s): {dropped}")
+
+    elif op_type == 'drop_sparse':
+        threshold = op.get('threshold', 0.9)
+        if isinstance(threshold, bool) or not isinstance(threshold, (int, float)) or not 0 <= threshold < 1:
+            raise ValueError(f"drop_sparse threshold must be a fraction from 0 to 1, got {threshold!r}")
+        columns = op.get('columns') or list(df.columns)
+        null_frac = df[columns].isna().mean() if len(df) else pd.Series(0.0, index=columns)
+        dropped = [c for c in columns if null_frac[c] > threshold]
+        for c in dropped:
+            record_dropped(c, f"{null_frac[c]*100:.1f}% null (threshold {threshold*100:g}%)")
+        df = df.drop(columns=dropped)
+        print(f"  Dropped {len(dropped)} sparse column(s): {dropped}")
+
+    elif op_type == 'optimize_dtypes':
+        max_ratio = op.get('category_threshold', 0.5)
+        if isinstance(max_ratio, bool) or not isinstance(max_ratio, (int, float)) or not 0 <= max_ratio <= 1:
+            raise ValueError(f"optimize_dtypes category_threshold must be a fraction from 0 to 1, got {max_ratio!r}")
+        before = df.memory_usage(deep=True).sum()
+        df = df.copy()
+        changed = {}
+        for c in (op.get('columns') or list(df.columns)):
+            s = df[c]
+            old = str(s.dtype)
+            if pd.api.types.is_bool_dtype(s):
+                continue
+            if pd.api.types.is_integer_dtype(s):
+                kind = 'unsigned' if len(s) and s.min() >= 0 else 'integer'
+                df[c] = pd.to_numeric(s, downcast=kind)
+            elif pd.api.types.is_float_dtype(s):
+                # Only downcast floats whose values survive the round trip
+                small = pd.to_numeric(s, downcast='float')
+                if small.dtype != s.dtype and (small.astype(s.dtype).eq(s) | s.isna()).all():
+                    df[c] = small
+            elif s.dtype == object and len(s) and s.nunique(dropna=True) / len(s) <= max_ratio:
+                df[c] = s.astype('category')
+            if str(df[c].dtype) != old:
+                changed[str(c)] = f"{old} -> {df[c].dtype}"
+        after = df.memory_usage(deep=True).sum()
+        for c, change in changed.items():
+            print(f"    {c}: {change}")
+        saved = (1 - after / before) * 100 if before else 0
+        print(f"  Optimized dtypes of {len(changed)} column(s): memory {before/1024/1024:.2f} MB -> {after/1024/1024:.2f} MB ({saved:.1f}% smaller)")
+
+    elif op_type == 'groupby_agg':
+        by = op['by']
+        by = [by] if isinstance(by, str) else list(by)
+        aggs = op.get('aggs')
+        grouped = df.groupby(by, dropna=op.get('dropna', True))
+        if aggs:
+            df = grouped.agg(aggs)
+        else:
+            df = grouped.size().to_frame('count')
+        # Flatten MultiIndex columns from list aggregations, e.g. sales_sum
+        df = flatten_columns(df)
+        if op.get('reset_index', True):
+            df = df.reset_index()
+        else:
+            df.attrs['keep_index'] = True
+        print(f"  Grouped by {by}: {len(df)} groups, columns {[str(c) for c in df.columns]}")
+
+    elif op_type == 'partition':
+        column = op['column']
+        if column not in df.columns:
+            raise ValueError(f"column '{column}' not found")
+        parts = {}
+        for value, group in df.groupby(column, dropna=False, sort=True):
+            key = part_name(value)
+            if key in parts:
+                parts[key] = pd.concat([parts[key], group])
             else:
-                print(vc.to_string())
-                
-    elif analysis_type == 'groupby':
-        if not group_by:
-            print("Error: group_by parameter required for groupby analysis", file=sys.stderr)
-            sys.exit(1)
-        if group_by not in df.columns:
-            print(f"Error: Column '{group_by}' not found. Available: {list(df.columns)}", file=sys.stderr)
-            sys.exit(1)
-        
-        print(f"=== Group By: {group_by} ===")
-        numeric_cols = df_subset.select_dtypes(include=[np.number]).columns.tolist()
-        if not numeric_cols:
-            print("No numeric columns to aggregate")
-            print(df.groupby(group_by).size().to_string())
+                parts[key] = group
+        if len(parts) > MAX_PARTS:
+            raise ValueError(f"column '{column}' has {len(parts)} distinct values (limit {MAX_PARTS} parts)")
+        print(f"  Partitioned on {column}: {len(parts)} parts")
+        return parts
+
+    elif op_type == 'split':
+        from sklearn.model_selection import train_test_split
+        test_size = op.get('test_size', 0.2)
+        stratify = op.get('stratify')
+        if isinstance(test_size, float) and not 0 < test_size < 1:
+            raise ValueError(f"test_size must be between 0 and 1 or a row count, got {test_size}")
+        if stratify is not None and stratify not in df.columns:
+            raise ValueError(f"stratify column '{stratify}' not found")
+        train, test = train_test_split(
+            df,
+            test_size=test_size,
+            random_state=op.get('random_state'),
+            shuffle=op.get('shuffle', True),
+            stratify=df[stratify] if stratify is not None else None,
+        )
+        print(f"  Split into train ({len(train)} rows) and test ({len(test)} rows)" + (f", stratified on {stratify}" if stratify else ""))
+        return {'train': train, 'test': test}
+
+    elif op_type == 'concat':
+        files = op.get('files', [])
+        axis = op.get('axis', 0)
+        others = [read_data(path) for path in files]
+        if axis in (0, 'index', 'rows'):
+            base = list(df.columns)
+            for path, other in zip(files, others):
+                missing = [c for c in base if c not in other.columns]
+                extra = [c for c in other.columns if c not in base]
+                if missing or extra:
+                    print(f"  Warning: {os.path.basename(path)} columns differ (missing: {missing}, extra: {extra}); unmatched values are filled with NaN")
+            df = pd.concat([df] + others, axis=0, ignore_index=op.get('ignore_index', True), sort=False)
+            print(f"  Concatenated {len(files)} file(s) row-wise: {len(df)} rows")
+        elif axis in (1, 'columns'):
+            for path, other in zip(files, others):
+                if len(other) != len(df):
+                    print(f"  Warning: {os.path.basename(path)} has {len(other)} rows, expected {len(df)}; unmatched rows are filled with NaN")
+            df = pd.concat([df] + others, axis=1, ignore_index=op.get('ignore_index', False))
+            print(f"  Concatenated {len(files)} file(s) column-wise: {df.shape[1]} columns")
         else:
-            grouped = df.groupby(group_by)[numeric_cols].agg(['mean', 'sum', 'count'])
-            print(grouped.to_string())
+            raise ValueError(f"concat axis must be 0 or 1, got {axis!r}")
+
     else:
-        print(f"Error: Unknown analysis type '{analysis_type}'", file=sys.stderr)
-        sys.exit(1)
-        
-except Exception as e:
-    print(f"Error during analysis: {e}", file=sys.stderr)
+        print(f"  Warning: Unknown operation type '{op_type}'")
+
+    return df
+
+# Columns removed by drop_constant/drop_sparse, with the reason
+dropped_columns = []
+
+def record_dropped(column, reason):
+    print(f"    {column}: {reason}")
+    entry = {"column": str(column), "reason": reason}
+    if entry not in dropped_columns:
+        dropped_columns.append(entry)
+
+# Operation keys whose values name columns the operation expects to exist
+COLUMN_KEYS = ('column', 'columns', 'subset', 'stratify', 'by')
+
+def missing_columns(columns, operations):
+    """
+    Check the columns each operation references against the columns it will
+    see, following select/drop/rename. Returns (operation number, type,
+    column, closest existing column or None) for each missing one. Checking
+    stops at a concat, since the concatenated files' columns aren't known
+    until they are read.
+    """
+    import difflib
+    cols = list(columns)
+    missing = []
+    for i, op in enumerate(operations, 1):
+        op_type = op.get('type')
+        if op_type == 'concat':
+            break
+        refs = []
+        for key in COLUMN_KEYS:
+            value = op.get(key)
+            if isinstance(value, str):
+                refs.append(value)
+            elif isinstance(value, list):
+                refs.extend(c for c in value if isinstance(c, str))
+        if op_type == 'rename':
+            refs.extend(op.get('mapping', {}).keys())
+        elif op_type == 'groupby_agg' and isinstance(op.get('aggs'), dict):
+            refs.extend(op['aggs'].keys())
+        names = [str(c) for c in cols]
+        for c in refs:
+            if c not in cols:
+                hint = difflib.get_close_matches(c, names, n=1)
+                missing.append((i, op_type, c, hint[0] if hint else None))
+
+        if op_type == 'select':
+            cols = list(op.get('columns', []))
+        elif op_type in ('drop_constant', 'drop_sparse'):
+            # Which columns go depends on the data
+            break
+        elif op_type == 'drop':
+            cols = [c for c in cols if c not in op.get('columns', [])]
+        elif op_type == 'rename':
+            mapping = op.get('mapping', {})
+            cols = [mapping.get(c, c) for c in cols]
+        elif op_type == 'groupby_agg':
+            by = op.get('by', [])
+            by = [by] if isinstance(by, str) else list(by)
+            aggs = op.get('aggs')
+            if not isinstance(aggs, dict):
+                # Aggregating every column with one function keeps the names
+                out = ['count'] if not aggs else [c for c in cols if c not in by]
+            else:
+                out = []
+                for c, funcs in aggs.items():
+                    if isinstance(funcs, list):
+                        out.extend(f"{c}_{f}" for f in funcs)
+                    else:
+                        out.append(c)
+            cols = (by if op.get('reset_index', True) else []) + out
+    return missing
+
+def check_columns(df, operations):
+    """Exit with every missing column reported before any operation runs."""
+    missing = missing_columns(df.columns, operations)
+    if not missing:
+        return
+    print("Error: operations reference columns that don't exist:", file=sys.stderr)
+    for n, op_type, column, hint in missing:
+        suffix = f" (did you mean '{hint}'?)" if hint else ""
+        print(f"  operation {n} ({op_type}): '{column}'{suffix}", file=sys.stderr)
+    print(f"Available columns: {[str(c) for c in df.columns]}", file=sys.stderr)
     sys.exit(1)
-`, containerPath, analysisType, columnsJSON, groupByStr)
+
+def flatten_columns(df):
+    """Return df with MultiIndex columns joined by '_' (e.g. sales_sum) and
+    all column labels as strings, so every output format can store them."""
+    df = df.copy()
+    if isinstance(df.columns, pd.MultiIndex):
+        df.columns = ['_'.join(str(p) for p in col if str(p) != '') for col in df.columns]
+    else:
+        df.columns = [str(c) for c in df.columns]
+    return df
+
+def save_frame(df, name, output_format, compression=None):
+    """Save df to /output/{name}.{output_format} and return the path. CSV and
+    JSON files saved with compression get the codec's extension appended."""
+    output_file, kwargs = compression_args(f'/output/{name}.{output_format}', output_format, compression)
+    # Set when the index holds data: groupby_agg keys with reset_index off,
+    # or an index_col read option
+    keep_index = df.attrs.get('keep_index', False)
+    if output_format == 'json':
+        df.to_json(output_file, orient='split' if keep_index else 'records', indent=2, **kwargs)
+    elif output_format == 'parquet':
+        df.to_parquet(output_file, index=keep_index, **kwargs)
+    else:
+        df.to_csv(output_file, index=keep_index, **kwargs)
+    return output_file
+
+# Operations that split the frame (e.g. partition) return a dict of
+# part name -> DataFrame; later operations then apply to every part.
+MAX_PARTS = 100
+
+def part_name(value):
+    """Make a filename-safe part name from a group value."""
+    name = re.sub(r'[^A-Za-z0-9._-]+', '_', str(value)).strip('._')
+    return name or 'empty'
+
+def apply_step(data, op):
+    """Apply op to a frame, or to each part of an already split frame."""
+    if not isinstance(data, dict):
+        return apply_operation(data, op)
+    out = {}
+    for name, part in data.items():
+        res = apply_operation(part, op)
+        if isinstance(res, dict):
+            for sub, frame in res.items():
+                out[f"{name}_{sub}"] = frame
+        else:
+            out[name] = res
+    if len(out) > MAX_PARTS:
+        raise ValueError(f"operation produced {len(out)} parts (limit {MAX_PARTS})")
+    return out
+
+def save_frames(data, name, output_format, compression=None):
+    """Save a frame or every part of a split frame; returns the saved paths."""
+    if not isinstance(data, dict):
+        return [save_frame(data, name, output_format, compression)]
+    return [save_frame(part, f"{name}_{part_key}", output_format, compression) for part_key, part in data.items()]
+
+# Rows written to memory per codec by estimate_output_size
+ESTIMATE_SAMPLE_ROWS = 10000
+
+def estimate_output_size(df, output_format):
+    """
+    Estimate the bytes save_frame would write for df, uncompressed and with
+    each codec of output_format, by writing a sample to memory and scaling
+    it to the full row count.
+    """
+    import io
+    n = len(df)
+    sample = df if n <= ESTIMATE_SAMPLE_ROWS else df.sample(ESTIMATE_SAMPLE_ROWS, random_state=0)
+    scale = n / len(sample) if len(sample) else 1
+    keep_index = df.attrs.get('keep_index', False)
+    estimates = {}
+    for codec in ['none'] + list(OUTPUT_COMPRESSION.get(output_format, {})):
+        buf = io.BytesIO()
+        kwargs = {'compression': None if codec == 'none' else codec}
+        try:
+            if output_format == 'json':
+                sample.to_json(buf, orient='split' if keep_index else 'records', indent=2, **kwargs)
+            elif output_format == 'parquet':
+                sample.to_parquet(buf, index=keep_index, **kwargs)
+            else:
+                sample.to_csv(buf, index=keep_index, **kwargs)
+        except Exception:
+            # Codec not available in this image
+            continue
+        estimates[codec] = int(len(buf.getvalue()) * scale)
+    return estimates
+
+# Operations that look at one row at a time, so they give the same result
+# applied to each chunk of a file as to the whole frame
+CHUNK_SAFE_OPERATIONS = ['filter', 'select', 'drop', 'rename', 'dropna', 'fillna', 'astype']
+
+def read_chunks(file_path, options, chunksize):
+    """Iterate over a CSV file in frames of chunksize rows."""
+    if detect_format(file_path) != '.csv':
+        raise ValueError("chunksize only applies to CSV input; read other formats without chunksize")
+    unsupported = [k for k in ('skipfooter', 'index_col', 'normalize', 'drop_unnamed_index') if options.get(k)]
+    if unsupported:
+        raise ValueError(f"read options {unsupported} can't be combined with chunksize")
+    kwargs = dict(options.get('options') or {})
+    for key, arg in (('dtypes', 'dtype'), ('parse_dates', 'parse_dates'), ('usecols', 'usecols'), ('skiprows', 'skiprows')):
+        if options.get(key):
+            kwargs[arg] = options[key]
+    if options.get('na_values'):
+        kwargs['na_values'] = [str(v) for v in options['na_values']]
+    if options.get('keep_default_na') is not None:
+        kwargs['keep_default_na'] = options['keep_default_na']
+    return pd.read_csv(file_path, chunksize=chunksize, **kwargs)
+
+class ChunkWriter:
+    """Append frames to /output/{name}.{output_format} one chunk at a time."""
+
+    def __init__(self, name, output_format, compression):
+        self.path, kwargs = compression_args(f'/output/{name}.{output_format}', output_format, compression)
+        self.format = output_format
+        self.kwargs = kwargs
+        self.chunks = 0
+        self.records = 0
+        self.file = None
+        self.parquet = None
+
+    def write(self, chunk):
+        if self.format == 'parquet':
+            import pyarrow as pa
+            import pyarrow.parquet as pq
+            if self.parquet is None:
+                table = pa.Table.from_pandas(chunk, preserve_index=False)
+                self.parquet = pq.ParquetWriter(self.path, table.schema, **self.kwargs)
+            else:
+                try:
+                    table = pa.Table.from_pandas(chunk, schema=self.parquet.schema, preserve_index=False)
+                except (pa.ArrowInvalid, pa.ArrowTypeError) as e:
+                    raise ValueError(f"chunk {self.chunks + 1} doesn't match the column types of the first chunk ({e}); set dtypes in read_options so every chunk parses the same way")
+            self.parquet.write_table(table)
+        else:
+            if self.file is None:
+                import gzip, bz2, lzma
+                opener = {'gzip': gzip.open, 'bz2': bz2.open, 'xz': lzma.open}.get(self.kwargs.get('compression'), open)
+                self.file = opener(self.path, 'wt')
+                if self.format == 'json':
+                    self.file.write('[')
+            if self.format == 'json':
+                # Stream one records array: each chunk's records without brackets
+                records = chunk.to_json(orient='records')[1:-1]
+                if records:
+                    self.file.write((',' if self.records else '') + records)
+                    self.records += len(chunk)
+            else:
+                chunk.to_csv(self.file, index=False, header=self.chunks == 0)
+        self.chunks += 1
+
+    def close(self):
+        if self.parquet is not None:
+            self.parquet.close()
+        if self.file is not None:
+            if self.format == 'json':
+                self.file.write(']')
+            self.file.close()
+
+def transform_chunks(file_path, operations, options, chunksize, name, output_format, compression):
+    """
+    Apply chunk-safe operations to a CSV file chunksize rows at a time and
+    write each result to the output as it goes, so the whole frame is never
+    in memory. Returns the result summary.
+    """
+    unsafe = [f"{i + 1} ({op.get('type')})" for i, op in enumerate(operations) if op.get('type') not in CHUNK_SAFE_OPERATIONS]
+    if unsafe:
+        raise ValueError(f"operation(s) {', '.join(unsafe)} need the whole frame and can't run with chunksize. "
+                         f"Chunked transforms support {', '.join(CHUNK_SAFE_OPERATIONS)}; run the other operations without chunksize, or aggregate large files with query_data")
+    import contextlib
+    import io
+    writer = ChunkWriter(name, output_format, compression)
+    rows_read = rows_written = 0
+    original_columns = 0
+    columns = []
+    try:
+        for chunk in read_chunks(file_path, options, chunksize):
+            if writer.chunks == 0:
+                check_columns(chunk, operations)
+                original_columns = chunk.shape[1]
+                print(f"Operations (applied to each chunk of {chunksize:,} rows):")
+            rows_read += len(chunk)
+            # Only the first chunk logs what each operation did
+            quiet = contextlib.redirect_stdout(io.StringIO()) if writer.chunks else contextlib.nullcontext()
+            with quiet:
+                for i, op in enumerate(operations):
+                    if writer.chunks == 0:
+                        print(f"Operation {i+1}: {op.get('type')}")
+                    chunk = apply_operation(chunk, op)
+            writer.write(chunk)
+            rows_written += len(chunk)
+            columns = [str(c) for c in chunk.columns]
+    except pd.errors.EmptyDataError:
+        raise ValueError(f"{os.path.basename(file_path)}: file is empty") from None
+    finally:
+        writer.close()
+    if writer.chunks == 0:
+        raise ValueError(f"{os.path.basename(file_path)}: file has headers but no rows")
+
+    print()
+    print(f"Processed {rows_read:,} rows in {writer.chunks:,} chunk(s); {rows_written:,} rows written")
+    print(f"Output saved to: {writer.path} ({file_size_text(writer.path)})")
+    return {
+        "chunked": True,
+        "chunksize": chunksize,
+        "chunks": writer.chunks,
+        "original_shape": {"rows": rows_read, "columns": original_columns},
+        "final_shape": {"rows": rows_written, "columns": len(columns)},
+        "columns": columns,
+        "output_file": writer.path,
+        "output_files": [writer.path],
+        "output_sizes": [os.path.getsize(writer.path)],
+    }
+`
+
+// TransformOptions holds how transform_data saves and reports its result.
+type TransformOptions struct {
+	// The result is saved as /output/{OutputName}.{OutputFormat}; operations
+	// that split the frame save one {OutputName}_{part}.{OutputFormat} file
+	// per part
+	OutputFormat string
+	OutputName   string
+
+	Compression string // codec for compression_args, or "" for the pandas default
+	Chunksize   int    // positive: stream a CSV input through transform_chunks instead of loading it whole
+
+	// A positive ReturnRows prints and returns the whole result instead of a
+	// preview when it has at most that many rows
+	ReturnRows int
+
+	DryRun bool // report the result's memory use and estimated file sizes instead of saving it
 }
 
-// TransformDataScript generates a script to transform data.
-func TransformDataScript(containerPath string, operations []map[string]interface{}, outputFormat string) string {
+// TransformDataScript generates a script that applies operations to a data
+// file and saves the result as described by topts.
+func TransformDataScript(containerPath string, operations []map[string]interface{}, topts TransformOptions, opts ReadOptions) string {
 	opsJSON, _ := jsonMarshal(operations)
 
 	return fmt.Sprintf(`#!/usr/bin/env python3
 import sys
 import os
+import re
 import json
 import pandas as pd
 import numpy as np
@@ -418,24 +1572,34 @@ import numpy as np
 # Suppress warnings
 import warnings
 warnings.filterwarnings('ignore')
-
+%s%s%s
 file_path = %q
 operations = %s
 output_format = %q
+output_name = %q
+compression = %q or None
+chunksize = %d
+return_rows = %d
+dry_run = %s
+read_options = %s
+
+if chunksize:
+    try:
+        result = transform_chunks(file_path, operations, read_options, chunksize, output_name, output_format, compression)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        sys.exit(1)
+    if compression:
+        result["compression"] = compression
+    if return_rows:
+        print("Note: return_data doesn't apply with chunksize; fetch the saved file with get_output")
+        result["data_returned"] = False
+    emit_result(result)
+    sys.exit(0)
 
 # Read file
-ext = os.path.splitext(file_path)[1].lower()
 try:
-    if ext == '.csv':
-        df = pd.read_csv(file_path)
-    elif ext in ['.xlsx', '.xls']:
-        df = pd.read_excel(file_path)
-    elif ext == '.json':
-        df = pd.read_json(file_path)
-    elif ext == '.parquet':
-        df = pd.read_parquet(file_path)
-    else:
-        df = pd.read_csv(file_path)
+    df = read_data(file_path, read_options)
 except Exception as e:
     print(f"Error reading file: {e}", file=sys.stderr)
     sys.exit(1)
@@ -444,141 +1608,106 @@ original_shape = df.shape
 print(f"Original shape: {original_shape[0]} rows × {original_shape[1]} columns")
 print()
 
+# Check referenced columns before changing anything
+check_columns(df, operations)
+
 # Apply operations
+data = df
 for i, op in enumerate(operations):
-    op_type = op.get('type')
-    print(f"Operation {i+1}: {op_type}")
-    
+    print(f"Operation {i+1}: {op.get('type')}")
     try:
-        if op_type == 'filter':
-            column = op['column']
-            operator = op['operator']
-            value = op['value']
-            
-            if operator == '==':
-                df = df[df[column] == value]
-            elif operator == '!=':
-                df = df[df[column] != value]
-            elif operator == '>':
-                df = df[df[column] > value]
-            elif operator == '>=':
-                df = df[df[column] >= value]
-            elif operator == '<':
-                df = df[df[column] < value]
-            elif operator == '<=':
-                df = df[df[column] <= value]
-            elif operator == 'contains':
-                df = df[df[column].astype(str).str.contains(str(value), na=False)]
-            elif operator == 'isin':
-                df = df[df[column].isin(value if isinstance(value, list) else [value])]
-            else:
-                print(f"  Warning: Unknown operator '{operator}'")
-            print(f"  Filtered on {column} {operator} {value}: {len(df)} rows remaining")
-            
-        elif op_type == 'select':
-            columns = op['columns']
-            df = df[columns]
-            print(f"  Selected columns: {columns}")
-            
-        elif op_type == 'drop':
-            columns = op['columns']
-            df = df.drop(columns=columns)
-            print(f"  Dropped columns: {columns}")
-            
-        elif op_type == 'sort':
-            column = op['column']
-            ascending = op.get('ascending', True)
-            df = df.sort_values(by=column, ascending=ascending)
-            print(f"  Sorted by {column} ({'ascending' if ascending else 'descending'})")
-            
-        elif op_type == 'rename':
-            mapping = op['mapping']
-            df = df.rename(columns=mapping)
-            print(f"  Renamed columns: {mapping}")
-            
-        elif op_type == 'dropna':
-            subset = op.get('subset')
-            if subset:
-                df = df.dropna(subset=subset)
-                print(f"  Dropped NA in columns: {subset}, {len(df)} rows remaining")
-            else:
-                df = df.dropna()
-                print(f"  Dropped all rows with NA: {len(df)} rows remaining")
-                
-        elif op_type == 'fillna':
-            column = op.get('column')
-            fill_value = op.get('fill_value', 0)
-            if column:
-                df[column] = df[column].fillna(fill_value)
-                print(f"  Filled NA in {column} with {fill_value}")
-            else:
-                df = df.fillna(fill_value)
-                print(f"  Filled all NA with {fill_value}")
-                
-        elif op_type == 'astype':
-            column = op['column']
-            dtype = op['dtype']
-            df[column] = df[column].astype(dtype)
-            print(f"  Converted {column} to {dtype}")
-            
-        elif op_type == 'head':
-            n = op.get('n', 5)
-            df = df.head(n)
-            print(f"  Took first {n} rows")
-            
-        elif op_type == 'tail':
-            n = op.get('n', 5)
-            df = df.tail(n)
-            print(f"  Took last {n} rows")
-            
-        elif op_type == 'sample':
-            n = op.get('n')
-            frac = op.get('frac')
-            if n:
-                df = df.sample(n=min(n, len(df)))
-                print(f"  Sampled {len(df)} rows")
-            elif frac:
-                df = df.sample(frac=frac)
-                print(f"  Sampled {len(df)} rows ({frac*100}%%)")
-                
-        elif op_type == 'unique':
-            columns = op.get('columns')
-            if columns:
-                df = df.drop_duplicates(subset=columns)
-            else:
-                df = df.drop_duplicates()
-            print(f"  Removed duplicates: {len(df)} rows remaining")
-            
-        else:
-            print(f"  Warning: Unknown operation type '{op_type}'")
-            
+        data = apply_step(data, op)
     except Exception as e:
         print(f"  Error in operation: {e}", file=sys.stderr)
         sys.exit(1)
 
 print()
-print(f"Final shape: {df.shape[0]} rows × {df.shape[1]} columns")
+parts = data if isinstance(data, dict) else {None: data}
+if isinstance(data, dict):
+    print(f"Final parts: {len(parts)}")
+    for name, part in parts.items():
+        print(f"  {name}: {part.shape[0]} rows × {part.shape[1]} columns")
+else:
+    print(f"Final shape: {data.shape[0]} rows × {data.shape[1]} columns")
+
+# Save output, or with dry_run only estimate its size
+estimate = None
+if dry_run:
+    memory_bytes = int(sum(part.memory_usage(deep=True).sum() for part in parts.values()))
+    sizes = {}
+    for part in parts.values():
+        for codec, size in estimate_output_size(part, output_format).items():
+            sizes[codec] = sizes.get(codec, 0) + size
+    # Without compression, parquet files use the pandas default
+    selected = compression or ('snappy' if output_format == 'parquet' else 'none')
+    estimate = {
+        "memory_bytes": memory_bytes,
+        "format": output_format,
+        "compression": selected,
+        "estimated_bytes": sizes.get(selected),
+        "estimated_bytes_by_compression": sizes,
+    }
+    output_files = []
+    print()
+    print("=== Dry run: nothing was saved ===")
+    print(f"In-memory size: {size_text(memory_bytes)}")
+    print(f"Estimated {output_format} file size:")
+    for codec, size in sizes.items():
+        marker = "  <- selected" if codec == selected else ""
+        print(f"  {codec:<8} ~{size_text(size)}{marker}")
+else:
+    try:
+        output_files = save_frames(data, output_name, output_format, compression)
+        print()
+        for output_file in output_files:
+            print(f"Output saved to: {output_file} ({file_size_text(output_file)})")
+    except Exception as e:
+        print(f"Error saving output: {e}", file=sys.stderr)
+        sys.exit(1)
 
-# Save output
-output_file = f'/output/transformed.{output_format}'
-try:
-    if output_format == 'csv':
-        df.to_csv(output_file, index=False)
-    elif output_format == 'json':
-        df.to_json(output_file, orient='records', indent=2)
-    elif output_format == 'parquet':
-        df.to_parquet(output_file, index=False)
+# Print a preview, or the whole result when return_data is set and it is small enough
+total_rows = sum(len(part) for part in parts.values())
+inline = return_rows > 0 and total_rows <= return_rows
+if return_rows and not inline:
+    print(f"\nNote: the result has {total_rows:,} rows, more than the return_data limit of {return_rows:,}; showing a preview. Fetch the saved file with get_output")
+for name, part in parts.items():
+    if inline:
+        title = "Data" if name is None else f"Data: {name}"
+        print(f"\n=== {title} ({len(part)} rows, CSV) ===")
+        print(part.to_csv(index=part.attrs.get('keep_index', False)), end='')
+    elif name is None:
+        print("\n=== Preview (first 10 rows) ===")
+        print(part.head(10).to_string())
     else:
-        df.to_csv(output_file, index=False)
-    print(f"\nOutput saved to: {output_file}")
-except Exception as e:
-    print(f"Error saving output: {e}", file=sys.stderr)
-    sys.exit(1)
-
-# Print preview
-print("\n=== Preview (first 10 rows) ===")
-print(df.head(10).to_string())
-`, containerPath, string(opsJSON), outputFormat)
+        print(f"\n=== Preview: {name} (first 10 rows) ===")
+        print(part.head(10).to_string())
+
+result = {
+    "original_shape": {"rows": original_shape[0], "columns": original_shape[1]},
+    "output_file": output_files[0] if output_files else None,
+    "output_files": output_files,
+    "output_sizes": [os.path.getsize(f) for f in output_files],
+}
+if compression:
+    result["compression"] = compression
+if isinstance(data, dict):
+    result["parts"] = {name: {"rows": part.shape[0], "columns": part.shape[1]} for name, part in parts.items()}
+else:
+    result["final_shape"] = {"rows": data.shape[0], "columns": data.shape[1]}
+    result["columns"] = list(data.columns)
+if dropped_columns:
+    result["dropped_columns"] = dropped_columns
+if return_rows:
+    result["data_returned"] = inline
+if estimate is not None:
+    result["dry_run"] = True
+    result["size_estimate"] = estimate
+if inline:
+    def records(part):
+        return (part.reset_index() if part.attrs.get('keep_index') else part).to_dict(orient='records')
+    result["data"] = {name: records(part) for name, part in parts.items()} if isinstance(data, dict) else records(data)
+emit_result(result)
+`, emitResultHelper, readDataHelper, transformHelper, containerPath, string(opsJSON), topts.OutputFormat, topts.OutputName, topts.Compression, topts.Chunksize, topts.ReturnRows, pyValue(topts.DryRun), pyValue(opts))
 }
 
 // jsonMarshal is a helper to marshal JSON without HTML escaping.
@@ -662,7 +1791,7 @@ func mapToJSON(m map[string]interface{}) string {
 // WrapDuckDBScript generates a Python script that executes a SQL query using DuckDB.
 // It auto-creates views for each mounted file and handles large result sets by
 // saving full results to output files while returning summaries to stdout.
-func WrapDuckDBScript(query string, fileMapping map[string]string, themeCode string) string {
+func WrapDuckDBScript(query string, fileMapping map[string]string, themeCode string, defaultFormat string) string {
 	var sb strings.Builder
 
 	sb.WriteString(`#!/usr/bin/env python3
@@ -676,6 +1805,12 @@ import numpy as np
 # Suppress warnings for cleaner output
 import warnings
 warnings.filterwarnings('ignore')
+if os.environ.get('WARN_MIXED_TYPES') == '1':
+    # Mixed-type columns are worth knowing about; show pandas' warning
+    warnings.simplefilter('default', pd.errors.DtypeWarning)
+
+# Read-only shared data directory (mounted when the server sets DATA_DIR)
+SHARED_DIR = '` + SharedDataPath + `'
 
 # File path mapping (original path -> container path)
 FILE_MAPPING = {
@@ -701,22 +1836,19 @@ FILE_MAPPING = {
 # Output directory for saving results
 OUTPUT_DIR = '/output'
 
-def save_output(obj, filename, format=None):
-    """Save output to file. Supports DataFrame, dict, list, str, bytes, BytesIO."""
+# Format for DataFrames saved without a table extension
+DEFAULT_OUTPUT_FORMAT = '` + defaultFormat + `'
+` + compressionHelper + saveDataFrameHelper + `
+def save_output(obj, filename, format=None, compression=None):
+    """Save output to file. Supports DataFrame, dict, list, str, bytes, BytesIO.
+    compression applies to DataFrames (see OUTPUT_COMPRESSION)."""
     path = os.path.join(OUTPUT_DIR, filename)
     if format is None:
         format = os.path.splitext(filename)[1].lstrip('.').lower()
+    if compression is not None and compression != 'none' and not hasattr(obj, 'to_csv'):
+        raise ValueError("compression is only supported when saving DataFrames")
     if hasattr(obj, 'to_csv'):
-        if format in ['csv', 'txt']:
-            obj.to_csv(path, index=False)
-        elif format == 'json':
-            obj.to_json(path, orient='records', indent=2)
-        elif format in ['parquet', 'pq']:
-            obj.to_parquet(path, index=False)
-        elif format in ['xlsx', 'excel', 'xls']:
-            obj.to_excel(path, index=False, engine='openpyxl')
-        else:
-            obj.to_csv(path, index=False)
+        path = save_dataframe(obj, path, format, compression)
     elif isinstance(obj, (dict, list)):
         with open(path, 'w') as f:
             json.dump(obj, f, indent=2, default=str)
@@ -735,7 +1867,7 @@ def save_output(obj, filename, format=None):
             f.write(content)
     else:
         raise TypeError(f"Unsupported type for save_output: {type(obj)}")
-    print(f"Saved output to: {path}")
+    print(f"Saved output to: {path} ({file_size_text(path)})")
     return path
 
 # Initialize DuckDB connection
@@ -823,13 +1955,17 @@ import numpy as np
 # Suppress warnings
 import warnings
 warnings.filterwarnings('ignore')
-
+%s
 FILE_PATH = %q
 
 # Detect file format and read with DuckDB
-ext = os.path.splitext(FILE_PATH)[1].lower()
+ext = detect_format(FILE_PATH)
 con = duckdb.connect()
 
+if os.path.isfile(FILE_PATH) and os.path.getsize(FILE_PATH) == 0:
+    print(f"Error reading file: {os.path.basename(FILE_PATH)}: file is empty", file=sys.stderr)
+    sys.exit(1)
+
 try:
     if ext == '.csv':
         con.execute(f"CREATE VIEW data AS SELECT * FROM read_csv('{FILE_PATH}', auto_detect=true)")
@@ -840,6 +1976,10 @@ try:
     elif ext in ['.xlsx', '.xls']:
         _df = pd.read_excel(FILE_PATH)
         con.register('data', _df)
+    elif ext in DATA_EXTENSIONS and ext != '.csv':
+        # Formats DuckDB can't read directly go through pandas
+        _df = read_data(FILE_PATH, {'allow_empty': True})
+        con.register('data', _df)
     else:
         # Try CSV as fallback
         con.execute(f"CREATE VIEW data AS SELECT * FROM read_csv('{FILE_PATH}', auto_detect=true)")
@@ -853,6 +1993,9 @@ col_info = con.execute("DESCRIBE data").fetchdf()
 col_names = col_info['column_name'].tolist()
 col_types = col_info['column_type'].tolist()
 n_cols = len(col_names)
+if row_count == 0:
+    print(f"Error reading file: {os.path.basename(FILE_PATH)}: file has headers but no rows (columns: {', '.join(col_names)})", file=sys.stderr)
+    sys.exit(1)
 
 # File size
 try:
@@ -993,5 +2136,5 @@ print("-" * 60)
 sample_df = con.execute("SELECT * FROM data LIMIT 5").fetchdf()
 print(sample_df.to_string())
 print()
-`, containerPath)
+`, readDataHelper, containerPath)
 }
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+// Package executor provides resource usage tracking for script containers.
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// resourceUsage summarizes what a container consumed while it ran.
+type resourceUsage struct {
+	MemoryPeakBytes uint64        // Highest memory usage observed
+	CPUTime         time.Duration // Total CPU time across all cores
+}
+
+// usageWatcher tracks a running container's peak memory and CPU time from
+// the Docker stats stream.
+type usageWatcher struct {
+	mu     sync.Mutex
+	usage  resourceUsage
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// watchUsage starts streaming stats for containerID until the container
+// stops or Stop is called.
+func (e *DockerExecutor) watchUsage(containerID string) *usageWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &usageWatcher{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+		stats, err := e.client.ContainerStats(ctx, containerID, true)
+		if err != nil {
+			return
+		}
+		defer stats.Body.Close()
+
+		dec := json.NewDecoder(stats.Body)
+		for {
+			var s container.StatsResponse
+			if err := dec.Decode(&s); err != nil {
+				return
+			}
+			// max_usage is only reported on cgroup v1 hosts
+			mem := max(s.MemoryStats.Usage, s.MemoryStats.MaxUsage)
+			cpu := time.Duration(s.CPUStats.CPUUsage.TotalUsage) // nanoseconds
+
+			w.mu.Lock()
+			w.usage.MemoryPeakBytes = max(w.usage.MemoryPeakBytes, mem)
+			w.usage.CPUTime = max(w.usage.CPUTime, cpu)
+			w.mu.Unlock()
+		}
+	}()
+
+	return w
+}
+
+// Stop stops watching and returns the usage seen. Samples arrive about once
+// a second, so very short runs may report zero and CPU time is a lower bound.
+func (w *usageWatcher) Stop() resourceUsage {
+	w.cancel()
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.usage
+}
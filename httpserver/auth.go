@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// SetAPIKey requires "Authorization: Bearer <key>" on the storage endpoints.
+// Signed download URLs are accepted without it. An empty key leaves the
+// endpoints open.
+func (s *Server) SetAPIKey(key string) {
+	s.apiKey = []byte(key)
+}
+
+// hasAPIKey reports whether r carries the configured API key. It is always
+// true when no key is configured.
+func (s *Server) hasAPIKey(r *http.Request) bool {
+	if len(s.apiKey) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), s.apiKey) == 1
+}
+
+// requireAPIKey rejects requests to next that don't carry the API key.
+func (s *Server) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.hasAPIKey(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+// executionFile is one output file in an /storage/executions response.
+type executionFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// executionJSON is the /storage/executions form of an execution.
+type executionJSON struct {
+	ExecutionID string          `json:"execution_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	ToolName    string          `json:"tool_name,omitempty"`
+	ScriptHash  string          `json:"script_hash,omitempty"`
+	Inputs      []string        `json:"inputs,omitempty"`
+	Files       []executionFile `json:"files"`
+}
+
+// outputDisabledMessage is the /storage/executions response without OUTPUT_DIR.
+const outputDisabledMessage = "Output persistence is disabled on this server (set OUTPUT_DIR)"
+
+// SetOutputManager enables the /storage/executions endpoints.
+func (s *Server) SetOutputManager(om *executor.OutputManager) {
+	s.outputManager = om
+}
+
+func newExecutionJSON(info executor.ExecutionInfo) executionJSON {
+	files := make([]executionFile, 0, len(info.Files))
+	for _, name := range info.Files {
+		f := executionFile{Name: name, SHA256: info.Checksums[name]}
+		if st, err := os.Stat(filepath.Join(info.OutputPath, name)); err == nil {
+			f.Size = st.Size()
+		}
+		files = append(files, f)
+	}
+	return executionJSON{
+		ExecutionID: info.ExecutionID,
+		CreatedAt:   info.CreatedAt,
+		ExpiresAt:   info.ExpiresAt,
+		ToolName:    info.ToolName,
+		ScriptHash:  info.ScriptHash,
+		Inputs:      info.Inputs,
+		Files:       files,
+	}
+}
+
+// handleExecutions returns execution metadata and output files.
+// GET /storage/executions
+// GET /storage/executions/{exec_id}
+func (s *Server) handleExecutions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.outputManager == nil {
+		http.Error(w, outputDisabledMessage, http.StatusServiceUnavailable)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/storage/executions"), "/")
+	if id != "" {
+		info, err := s.outputManager.GetExecution(id)
+		switch {
+		case errors.Is(err, executor.ErrOutputDisabled):
+			http.Error(w, outputDisabledMessage, http.StatusServiceUnavailable)
+			return
+		case errors.Is(err, executor.ErrNotFound):
+			http.Error(w, "Execution not found or expired", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, "Failed to read execution", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newExecutionJSON(*info))
+		return
+	}
+
+	infos, err := s.outputManager.ListExecutions()
+	if errors.Is(err, executor.ErrOutputDisabled) {
+		http.Error(w, outputDisabledMessage, http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list executions", http.StatusInternalServerError)
+		return
+	}
+	executions := make([]executionJSON, 0, len(infos))
+	for _, info := range infos {
+		executions = append(executions, newExecutionJSON(info))
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].CreatedAt.After(executions[j].CreatedAt)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"executions": executions,
+		"count":      len(executions),
+	})
+}
@@ -11,11 +11,18 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"mime/multipart"
 	"net/http"
+	"net/netip"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
 	"github.com/sagacient/cute-pandas-mcp-server/storage"
+	"github.com/sagacient/cute-pandas-mcp-server/tools"
 
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -25,10 +32,43 @@ type Server struct {
 	mcpServer   *server.MCPServer
 	fileStore   *storage.FileStore
 	httpServer  *server.StreamableHTTPServer
+	sseServer   *server.SSEServer // Set by EnableSSE for legacy SSE clients
 	mux         *http.ServeMux
 	maxUploadMB int64
+
+	// multipartMemory is the in-memory threshold for ParseMultipartForm;
+	// 0 streams uploads directly from the request body
+	multipartMemory int64
+
+	// signingKey enables signed download URLs when non-empty
+	signingKey []byte
+
+	// apiKey, when non-empty, must be sent as a bearer token to the storage
+	// endpoints; see SetAPIKey
+	apiKey []byte
+
+	// trustedProxies are the peers whose X-Forwarded-Proto is honored
+	trustedProxies []netip.Prefix
+
+	// allowedOrigins are the cross-origin Origins that may open a WebSocket
+	allowedOrigins []string
+
+	// outputManager backs /storage/executions; nil without OUTPUT_DIR
+	outputManager *executor.OutputManager
+
+	// Timeouts for the underlying http.Server (0 disables)
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
 }
 
+// defaultMultipartMemory matches net/http's default for ParseMultipartForm.
+const defaultMultipartMemory = 32 << 20
+
+// readHeaderTimeout bounds reading request headers, so slow clients can't
+// hold a connection open before a handler runs.
+const readHeaderTimeout = 10 * time.Second
+
 // NewServer creates a new HTTP server with MCP and storage endpoints.
 func NewServer(mcpServer *server.MCPServer, fileStore *storage.FileStore, maxUploadSize int64) *Server {
 	s := &Server{
@@ -36,16 +76,21 @@ func NewServer(mcpServer *server.MCPServer, fileStore *storage.FileStore, maxUpl
 		fileStore:   fileStore,
 		mux:         http.NewServeMux(),
 		maxUploadMB: maxUploadSize,
+
+		multipartMemory: defaultMultipartMemory,
 	}
 
 	// Create the MCP HTTP server
 	s.httpServer = server.NewStreamableHTTPServer(mcpServer)
 
 	// Register storage endpoints
-	s.mux.HandleFunc("/storage/upload", s.handleUpload)
-	s.mux.HandleFunc("/storage/list", s.handleList)
-	s.mux.HandleFunc("/storage/download/", s.handleDownload)
-	s.mux.HandleFunc("/storage/delete/", s.handleDelete)
+	s.mux.HandleFunc("/storage/upload", s.requireAPIKey(s.handleUpload))
+	s.mux.HandleFunc("/storage/list", s.requireAPIKey(s.handleList))
+	s.mux.HandleFunc("/storage/download/", s.handleDownload) // Checks signed URLs itself
+	s.mux.HandleFunc("/storage/delete/", s.requireAPIKey(s.handleDelete))
+	s.mux.HandleFunc("/storage/sign/", s.requireAPIKey(s.handleSign))
+	s.mux.HandleFunc("/storage/executions", s.requireAPIKey(s.handleExecutions))
+	s.mux.HandleFunc("/storage/executions/", s.requireAPIKey(s.handleExecutions))
 
 	// Health check
 	s.mux.HandleFunc("/health", s.handleHealth)
@@ -53,6 +98,45 @@ func NewServer(mcpServer *server.MCPServer, fileStore *storage.FileStore, maxUpl
 	return s
 }
 
+// EnableSSE serves the legacy SSE transport at /sse (event stream) and
+// /message (client requests) alongside the streamable HTTP endpoint.
+func (s *Server) EnableSSE() {
+	s.sseServer = server.NewSSEServer(s.mcpServer)
+}
+
+// SetMultipartMemory sets how many bytes of an upload are buffered in memory
+// before spilling to a temp file. 0 streams the file part straight to storage.
+func (s *Server) SetMultipartMemory(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	s.multipartMemory = n
+}
+
+// SetAllowedOrigins lists the browser origins (e.g. "https://app.example.com")
+// that may open WebSocket connections from another origin. Cross-origin
+// upgrades are refused by default.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.allowedOrigins = origins
+}
+
+// SetTimeouts sets the read, write, and idle timeouts of the HTTP server.
+// Zero disables a timeout. Long-lived event streams are exempt.
+func (s *Server) SetTimeouts(read, write, idle time.Duration) {
+	s.readTimeout = read
+	s.writeTimeout = write
+	s.idleTimeout = idle
+}
+
+// isStream reports whether r opens a long-lived event stream: the legacy SSE
+// endpoint or the streamable HTTP server's GET notification stream.
+func (s *Server) isStream(r *http.Request) bool {
+	if s.sseServer != nil && r.URL.Path == s.sseServer.CompleteSsePath() {
+		return true
+	}
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 // Start starts the HTTP server on the given address.
 func (s *Server) Start(addr string) error {
 	// Create a combined handler that routes to MCP or storage endpoints
@@ -60,7 +144,7 @@ func (s *Server) Start(addr string) error {
 		// Add CORS headers for browser clients
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -73,13 +157,79 @@ func (s *Server) Start(addr string) error {
 			return
 		}
 
-		// Route everything else to MCP server
-		s.httpServer.ServeHTTP(w, r)
+		// Route the WebSocket transport
+		if r.URL.Path == WebSocketPath {
+			s.handleWebSocket(w, r)
+			return
+		}
+
+		// Event streams stay open indefinitely, so exempt them from the
+		// read and write timeouts
+		if s.isStream(r) {
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(time.Time{})
+			rc.SetWriteDeadline(time.Time{})
+		}
+
+		// Route SSE transport endpoints when enabled
+		if s.sseServer != nil && (r.URL.Path == s.sseServer.CompleteSsePath() || r.URL.Path == s.sseServer.CompleteMessagePath()) {
+			s.sseServer.ServeHTTP(w, r)
+			return
+		}
+
+		// Route everything else to MCP server, letting tool handlers
+		// surface a Retry-After hint when the worker pool is exhausted
+		ctx, holder := tools.WithRetryAfterHolder(r.Context())
+		s.httpServer.ServeHTTP(&retryAfterWriter{ResponseWriter: w, holder: holder}, r.WithContext(ctx))
 	})
 
 	log.Printf("HTTP server starting on %s", addr)
-	log.Printf("Storage endpoints available at /storage/upload, /storage/list, /storage/download/{id}, /storage/delete/{id}")
-	return http.ListenAndServe(addr, handler)
+	if s.sseServer != nil {
+		log.Printf("SSE transport available at %s (messages at %s)", s.sseServer.CompleteSsePath(), s.sseServer.CompleteMessagePath())
+	}
+	log.Printf("WebSocket transport available at %s", WebSocketPath)
+	log.Printf("Storage endpoints available at /storage/upload, /storage/list, /storage/download/{id}, /storage/delete/{id}, /storage/sign/{id}, /storage/executions")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           logRequests(handler),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       s.readTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+	}
+	return srv.ListenAndServe()
+}
+
+// retryAfterWriter sets a Retry-After header from the request's holder before
+// the MCP response headers are written.
+type retryAfterWriter struct {
+	http.ResponseWriter
+	holder      *tools.RetryAfterHolder
+	wroteHeader bool
+}
+
+func (w *retryAfterWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if d := w.holder.Delay(); d > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryAfterWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports streamed (SSE) MCP responses.
+func (w *retryAfterWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 // handleUpload handles file uploads via multipart/form-data.
@@ -93,22 +243,53 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	// Limit request body size (add 1MB for form overhead)
 	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadMB+1024*1024)
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32MB in memory
-		http.Error(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
-		return
-	}
+	var file io.ReadCloser
+	var filename string
+	immutable := r.URL.Query().Get("immutable")
+	if s.multipartMemory > 0 {
+		// Parse multipart form (larger files spill to temp files)
+		if err := r.ParseMultipartForm(s.multipartMemory); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
 
-	// Get the file
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get file: %v", err), http.StatusBadRequest)
-		return
+		// Get the file
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get file: %v", err), http.StatusBadRequest)
+			return
+		}
+		file, filename = f, header.Filename
+		if v := r.FormValue("immutable"); v != "" {
+			immutable = v
+		}
+	} else {
+		// Stream the file part without buffering the form
+		part, fields, err := fileFormPart(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get file: %v", err), http.StatusBadRequest)
+			return
+		}
+		file, filename = part, part.FileName()
+		if v := fields["immutable"]; v != "" {
+			immutable = v
+		}
 	}
 	defer file.Close()
 
+	opts := storage.UploadOptions{}
+	if immutable != "" {
+		b, err := strconv.ParseBool(immutable)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid immutable value: %q", immutable), http.StatusBadRequest)
+			return
+		}
+		opts.Immutable = b
+	}
+
 	// Upload to storage (includes malware scanning if enabled)
-	info, err := s.fileStore.Upload(header.Filename, file)
+	info, err := s.fileStore.UploadWithOptions(filename, file, opts)
 	if err != nil {
 		// Handle specific error types
 		switch e := err.(type) {
@@ -147,6 +328,34 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(info)
 }
 
+// fileFormPart returns the "file" part of a multipart request body along
+// with any small text fields sent before it. Fields after the file part
+// cannot be read without buffering it and are ignored.
+func fileFormPart(r *http.Request) (*multipart.Part, map[string]string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := make(map[string]string)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("no 'file' field in form")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if part.FormName() == "file" {
+			return part, fields, nil
+		}
+		if part.FileName() == "" {
+			v, _ := io.ReadAll(io.LimitReader(part, 1024))
+			fields[part.FormName()] = string(v)
+		}
+		part.Close()
+	}
+}
+
 // handleList returns a list of all uploaded files.
 // GET /storage/list
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
@@ -164,7 +373,9 @@ func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleDownload returns a file by ID.
+// handleDownload returns a file by ID. Requests carrying expires and sig
+// query parameters are verified as signed URLs from /storage/sign/{id};
+// with signing enabled, any other request needs the API key.
 // GET /storage/download/{id}
 func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -179,6 +390,17 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Verify signed URLs
+	if q := r.URL.Query(); q.Has("sig") || q.Has("expires") {
+		if err := s.verifyDownloadSignature(id, q); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid signed URL: %v", err), http.StatusForbidden)
+			return
+		}
+	} else if (len(s.signingKey) > 0 && len(s.apiKey) == 0) || !s.hasAPIKey(r) {
+		http.Error(w, "Download requires a signed URL from /storage/sign/{id} or the API key", http.StatusForbidden)
+		return
+	}
+
 	// Get file info
 	info, ok := s.fileStore.Get(id)
 	if !ok {
@@ -221,6 +443,16 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 
 	// Delete file
 	if err := s.fileStore.Delete(id); err != nil {
+		if e, ok := err.(*storage.ErrImmutable); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":      "File is immutable until it expires",
+				"expires_at": e.ExpiresAt,
+				"status":     http.StatusForbidden,
+			})
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "File not found", http.StatusNotFound)
 			return
@@ -239,10 +471,18 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 // handleHealth returns server health status.
 // GET /health
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	health := map[string]interface{}{
 		"status":      "healthy",
 		"storage_dir": s.fileStore.BaseDir(),
 		"upload_ttl":  s.fileStore.TTL().String(),
-	})
+	}
+	if sc := s.fileStore.Scanner(); sc != nil && sc.IsEnabled() {
+		health["scanner"] = map[string]interface{}{
+			"available":   sc.IsAvailable(),
+			"definitions": sc.Definitions(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
 }
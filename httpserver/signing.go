@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lifetimes for signed download URLs.
+const (
+	defaultSignedURLTTL = 15 * time.Minute
+	maxSignedURLTTL     = 24 * time.Hour
+)
+
+// SetSigningKey enables signed download URLs issued by /storage/sign/{id}.
+func (s *Server) SetSigningKey(key string) {
+	s.signingKey = []byte(key)
+}
+
+// downloadSignature returns the hex HMAC-SHA256 of "<id>.<expires>".
+func (s *Server) downloadSignature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s.%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDownloadSignature checks the expires and sig query parameters of a
+// signed download request for the given file ID.
+func (s *Server) verifyDownloadSignature(id string, q url.Values) error {
+	if len(s.signingKey) == 0 {
+		return fmt.Errorf("signed downloads are not enabled")
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires parameter")
+	}
+	if time.Now().Unix() > expires {
+		return fmt.Errorf("signed URL has expired")
+	}
+	want := s.downloadSignature(id, expires)
+	if !hmac.Equal([]byte(want), []byte(q.Get("sig"))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// handleSign issues a time-limited download URL for a file.
+// GET /storage/sign/{id}?ttl=15m
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(s.signingKey) == 0 {
+		http.Error(w, "Signed downloads are not enabled (set DOWNLOAD_SIGNING_KEY)", http.StatusNotFound)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/storage/sign/")
+	if id == "" {
+		http.Error(w, "File ID required", http.StatusBadRequest)
+		return
+	}
+
+	info, ok := s.fileStore.Get(id)
+	if !ok {
+		http.Error(w, "File not found or expired", http.StatusNotFound)
+		return
+	}
+
+	ttl := defaultSignedURLTTL
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid ttl: %q", v), http.StatusBadRequest)
+			return
+		}
+		ttl = min(d, maxSignedURLTTL)
+	}
+
+	// A signed URL never outlives the file it points to
+	expiresAt := time.Now().Add(ttl)
+	if info.ExpiresAt.Before(expiresAt) {
+		expiresAt = info.ExpiresAt
+	}
+	expires := expiresAt.Unix()
+
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", s.downloadSignature(id, expires))
+	path := "/storage/download/" + url.PathEscape(id) + "?" + q.Encode()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"url":        s.requestScheme(r) + "://" + r.Host + path,
+		"path":       path,
+		"expires_at": time.Unix(expires, 0).UTC(),
+	})
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sagacient/cute-pandas-mcp-server/storage"
+)
+
+const testAPIKey = "test-api-key"
+
+// newSigningServer returns a server with signing and the API key enabled and
+// the ID of one stored file.
+func newSigningServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	fs, err := storage.NewFileStore(t.TempDir(), time.Hour, 1<<20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	info, err := fs.Upload("data.csv", strings.NewReader("a,b\n1,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(server.NewMCPServer("test", "1.0.0"), fs, 1<<20)
+	s.SetSigningKey("test-signing-key")
+	s.SetAPIKey(testAPIKey)
+	return s, info.ID
+}
+
+func TestSignedDownload(t *testing.T) {
+	s, id := newSigningServer(t)
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+	valid := s.downloadSignature(id, future)
+	tampered := []byte(valid)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name   string
+		query  string
+		apiKey string
+		want   int
+	}{
+		{"signed", fmt.Sprintf("?expires=%d&sig=%s", future, valid), "", http.StatusOK},
+		{"expired", fmt.Sprintf("?expires=%d&sig=%s", past, s.downloadSignature(id, past)), "", http.StatusForbidden},
+		{"tampered signature", fmt.Sprintf("?expires=%d&sig=%s", future, tampered), "", http.StatusForbidden},
+		{"extended expiry", fmt.Sprintf("?expires=%d&sig=%s", future+3600, valid), "", http.StatusForbidden},
+		{"signature only", "?sig=" + valid, "", http.StatusForbidden},
+		{"unsigned", "", "", http.StatusForbidden},
+		{"unsigned with wrong key", "", "wrong", http.StatusForbidden},
+		{"unsigned with API key", "", testAPIKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/storage/download/"+id+tt.query, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
+
+func TestSignRequiresAPIKey(t *testing.T) {
+	s, id := newSigningServer(t)
+	for _, key := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/storage/sign/"+id, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("sign with key %q: status = %d, want %d", key, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// An issued URL downloads without the key
+	req := httptest.NewRequest(http.MethodGet, "/storage/sign/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sign: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var signed struct{ Path string }
+	if err := json.NewDecoder(rec.Body).Decode(&signed); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed.Path, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("signed download: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+}
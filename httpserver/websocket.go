@@ -0,0 +1,417 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WebSocketPath is where the WebSocket MCP transport is served.
+const WebSocketPath = "/ws"
+
+// WebSocket keepalive and limits.
+const (
+	wsPingInterval   = 30 * time.Second
+	wsPongWait       = 2 * wsPingInterval // Connection is dropped without a pong in this window
+	wsWriteWait      = 10 * time.Second
+	wsMaxMessageSize = 16 << 20
+)
+
+// wsAcceptGUID is the RFC 6455 key suffix used for Sec-WebSocket-Accept.
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// errWSInvalidUTF8 is returned for a text message that isn't valid UTF-8,
+// which closes the connection with status 1007.
+var errWSInvalidUTF8 = errors.New("text message is not valid UTF-8")
+
+// wsConn is a minimal server-side RFC 6455 connection: it reads masked
+// client frames and writes unmasked server frames.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex // Serializes frame writes
+}
+
+// checkWebSocketUpgrade validates the handshake request before anything is
+// written, returning the HTTP status to refuse it with.
+func (s *Server) checkWebSocketUpgrade(r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return http.StatusBadRequest, fmt.Errorf("not a websocket upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return http.StatusBadRequest, fmt.Errorf("unsupported websocket version")
+	}
+	if r.Header.Get("Sec-WebSocket-Key") == "" {
+		return http.StatusBadRequest, fmt.Errorf("missing Sec-WebSocket-Key")
+	}
+	if !s.originAllowed(r) {
+		return http.StatusForbidden, fmt.Errorf("origin %q is not allowed (WS_ALLOWED_ORIGINS)", r.Header.Get("Origin"))
+	}
+	return 0, nil
+}
+
+// originAllowed reports whether a browser may open a WebSocket from the
+// request's Origin: requests without one (non-browser clients), same-origin
+// requests and origins on the allowlist are accepted.
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket completes the RFC 6455 handshake of a request that passed
+// checkWebSocketUpgrade. The connection is hijacked first, so errors can no
+// longer be reported as an HTTP response.
+func upgradeWebSocket(hj http.Hijacker, r *http.Request) (*wsConn, error) {
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if headerContains(r.Header, "Sec-WebSocket-Protocol", "mcp") {
+		resp += "Sec-WebSocket-Protocol: mcp\r\n"
+	}
+	resp += "\r\n"
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: brw.Reader}, nil
+}
+
+// headerContains reports whether a comma-separated header holds token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame writes a single unfragmented frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// readFrame reads one frame and unmasks its payload. Frames with reserved
+// bits set (no extensions are negotiated), unmasked frames, and fragmented or
+// oversized control frames are rejected.
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	opcode = h[0] & 0x0F
+	if h[0]&0x70 != 0 {
+		err = fmt.Errorf("reserved bits set without a negotiated extension")
+		return
+	}
+	if h[1]&0x80 == 0 {
+		err = fmt.Errorf("client frame is not masked")
+		return
+	}
+	control := opcode&0x8 != 0
+	if control && !fin {
+		err = fmt.Errorf("fragmented control frame")
+		return
+	}
+
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if control && n > 125 {
+		err = fmt.Errorf("control frame payload exceeds 125 bytes")
+		return
+	}
+	if n > wsMaxMessageSize {
+		err = fmt.Errorf("frame exceeds %d bytes", wsMaxMessageSize)
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// readMessage returns the next complete data message, answering pings and
+// reassembling fragments along the way. io.EOF is returned on a close frame,
+// and errWSInvalidUTF8 for a text message that isn't valid UTF-8.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	var msgOpcode byte
+	started := false
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			if len(payload) == 1 {
+				return nil, fmt.Errorf("close frame with a 1-byte payload")
+			}
+			// Echo the status code back to complete the closing handshake
+			if len(payload) >= 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(wsOpClose, payload)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, fmt.Errorf("new message before previous one finished")
+			}
+			started = true
+			msgOpcode = opcode
+		case wsOpContinuation:
+			if !started {
+				return nil, fmt.Errorf("unexpected continuation frame")
+			}
+		default:
+			return nil, fmt.Errorf("unknown opcode %d", opcode)
+		}
+
+		if len(msg)+len(payload) > wsMaxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", wsMaxMessageSize)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if msgOpcode == wsOpText && !utf8.Valid(msg) {
+				return nil, errWSInvalidUTF8
+			}
+			return msg, nil
+		}
+	}
+}
+
+// close sends a close frame with the given status code and closes the socket.
+func (c *wsConn) close(code uint16, reason string) {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	payload = append(payload, reason...)
+	c.writeFrame(wsOpClose, payload)
+	c.conn.Close()
+}
+
+// wsSession is the MCP client session for one WebSocket connection.
+type wsSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+}
+
+func (s *wsSession) SessionID() string { return s.id }
+
+func (s *wsSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *wsSession) Initialize() { s.initialized.Store(true) }
+
+func (s *wsSession) Initialized() bool { return s.initialized.Load() }
+
+// handleWebSocket upgrades the request and serves JSON-RPC messages over the
+// connection, one text frame per message. Requests are handled concurrently
+// so long-running tool calls don't block pings or other requests.
+// GET /ws
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if status, err := s.checkWebSocketUpgrade(r); err != nil {
+		http.Error(w, fmt.Sprintf("WebSocket upgrade failed: %v", err), status)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket upgrade failed: connection does not support hijacking", http.StatusInternalServerError)
+		return
+	}
+	conn, err := upgradeWebSocket(hj, r)
+	if err != nil {
+		log.Printf("WebSocket upgrade from %s failed: %v", r.RemoteAddr, err)
+		return
+	}
+
+	session := &wsSession{
+		id:            uuid.New().String(),
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.mcpServer.RegisterSession(ctx, session); err != nil {
+		cancel()
+		conn.close(1011, "session registration failed")
+		return
+	}
+	ctx = s.mcpServer.WithContext(ctx, session)
+	log.Printf("WebSocket session %s opened from %s", session.id, r.RemoteAddr)
+
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		s.mcpServer.UnregisterSession(context.Background(), session.id)
+		conn.conn.Close()
+		log.Printf("WebSocket session %s closed", session.id)
+	}()
+
+	// Forward server notifications and keep the connection alive
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-session.notifications:
+				if data, err := json.Marshal(n); err == nil {
+					conn.writeFrame(wsOpText, data)
+				}
+			case <-ticker.C:
+				if err := conn.writeFrame(wsOpPing, nil); err != nil {
+					conn.conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed):
+			case errors.As(err, &netErr) && netErr.Timeout():
+				log.Printf("WebSocket session %s: no pong within %v", session.id, wsPongWait)
+				conn.close(1001, "ping timeout")
+			case errors.Is(err, errWSInvalidUTF8):
+				log.Printf("WebSocket session %s: %v", session.id, err)
+				conn.close(1007, "invalid UTF-8")
+			default:
+				log.Printf("WebSocket session %s: %v", session.id, err)
+				conn.close(1002, "protocol error")
+			}
+			return
+		}
+
+		wg.Add(1)
+		go func(raw json.RawMessage) {
+			defer wg.Done()
+			response := s.mcpServer.HandleMessage(ctx, raw)
+			if response == nil {
+				return // Notifications have no response
+			}
+			data, err := json.Marshal(response)
+			if err != nil {
+				log.Printf("WebSocket session %s: failed to encode response: %v", session.id, err)
+				return
+			}
+			conn.writeFrame(wsOpText, data)
+		}(msg)
+	}
+}
@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// clientFrame builds a masked client frame. rsv is OR-ed into the first byte.
+func clientFrame(fin bool, rsv, opcode byte, payload []byte) []byte {
+	b0 := rsv | opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	frame = append(frame, mask[:]...)
+	for i, c := range payload {
+		frame = append(frame, c^mask[i%4])
+	}
+	return frame
+}
+
+func TestCheckWebSocketUpgrade(t *testing.T) {
+	s := &Server{allowedOrigins: []string{"https://app.example.com"}}
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string // Overrides of a valid handshake; "" deletes
+		want   int
+	}{
+		{name: "valid", want: 0},
+		{name: "POST", method: http.MethodPost, want: http.StatusMethodNotAllowed},
+		{name: "no upgrade", header: map[string]string{"Upgrade": ""}, want: http.StatusBadRequest},
+		{name: "connection list", header: map[string]string{"Connection": "keep-alive, Upgrade"}, want: 0},
+		{name: "old version", header: map[string]string{"Sec-WebSocket-Version": "8"}, want: http.StatusBadRequest},
+		{name: "no key", header: map[string]string{"Sec-WebSocket-Key": ""}, want: http.StatusBadRequest},
+		{name: "same origin", header: map[string]string{"Origin": "http://mcp.local"}, want: 0},
+		{name: "allowed origin", header: map[string]string{"Origin": "https://app.example.com"}, want: 0},
+		{name: "cross origin", header: map[string]string{"Origin": "https://evil.example"}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "http://mcp.local"+WebSocketPath, nil)
+			r.Header.Set("Connection", "Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+			r.Header.Set("Sec-WebSocket-Version", "13")
+			r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			for k, v := range tt.header {
+				if v == "" {
+					r.Header.Del(k)
+				} else {
+					r.Header.Set(k, v)
+				}
+			}
+			if got, _ := s.checkWebSocketUpgrade(r); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	s := &Server{mcpServer: server.NewMCPServer("test", "1.0.0")}
+	ts := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The sample key and accept value from RFC 6455 section 1.3
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Protocol: mcp\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "mcp" {
+		t.Errorf("Sec-WebSocket-Protocol = %q, want mcp", got)
+	}
+
+	// A ping is answered with a pong carrying the same payload
+	conn.Write(clientFrame(true, 0, wsOpPing, []byte("hi")))
+	if got := readServerFrame(t, br); !bytes.Equal(got, []byte{0x80 | wsOpPong, 2, 'h', 'i'}) {
+		t.Errorf("pong frame = %x", got)
+	}
+
+	// A close frame is echoed with its status code
+	conn.Write(clientFrame(true, 0, wsOpClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}))
+	if got := readServerFrame(t, br); !bytes.Equal(got, []byte{0x80 | wsOpClose, 2, 0x03, 0xe8}) {
+		t.Errorf("close frame = %x", got)
+	}
+}
+
+// readServerFrame reads one short unmasked server frame.
+func readServerFrame(t *testing.T, br *bufio.Reader) []byte {
+	t.Helper()
+	h := make([]byte, 2)
+	if _, err := io.ReadFull(br, h); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, h[1]&0x7F)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return append(h, payload...)
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("x"), 126)
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		want    string
+		wantErr string // Substring of the error; "" expects success
+	}{
+		{name: "text", frames: [][]byte{clientFrame(true, 0, wsOpText, []byte("hello"))}, want: "hello"},
+		{name: "fragmented", frames: [][]byte{
+			clientFrame(false, 0, wsOpText, []byte("hel")),
+			clientFrame(true, 0, wsOpContinuation, []byte("lo")),
+		}, want: "hello"},
+		{name: "ping between fragments", frames: [][]byte{
+			clientFrame(false, 0, wsOpText, []byte("hel")),
+			clientFrame(true, 0, wsOpPing, nil),
+			clientFrame(true, 0, wsOpContinuation, []byte("lo")),
+		}, want: "hello"},
+		{name: "extended length", frames: [][]byte{clientFrame(true, 0, wsOpText, long)}, want: string(long)},
+		{name: "binary need not be UTF-8", frames: [][]byte{clientFrame(true, 0, wsOpBinary, []byte{0xff, 0xfe})}, want: "\xff\xfe"},
+		{name: "unmasked", frames: [][]byte{{0x80 | wsOpText, 1, 'a'}}, wantErr: "not masked"},
+		{name: "reserved bit", frames: [][]byte{clientFrame(true, 0x40, wsOpText, []byte("a"))}, wantErr: "reserved bits"},
+		{name: "fragmented ping", frames: [][]byte{clientFrame(false, 0, wsOpPing, nil)}, wantErr: "fragmented control frame"},
+		{name: "oversized ping", frames: [][]byte{clientFrame(true, 0, wsOpPing, long)}, wantErr: "exceeds 125 bytes"},
+		{name: "1-byte close", frames: [][]byte{clientFrame(true, 0, wsOpClose, []byte{3})}, wantErr: "1-byte payload"},
+		{name: "invalid UTF-8", frames: [][]byte{clientFrame(true, 0, wsOpText, []byte{'a', 0xc3})}, wantErr: errWSInvalidUTF8.Error()},
+		{name: "UTF-8 split across fragments", frames: [][]byte{
+			clientFrame(false, 0, wsOpText, []byte{'a', 0xc3}),
+			clientFrame(true, 0, wsOpContinuation, []byte{0xa9}),
+		}, want: "aé"},
+		{name: "continuation first", frames: [][]byte{clientFrame(true, 0, wsOpContinuation, []byte("a"))}, wantErr: "unexpected continuation"},
+		{name: "interleaved messages", frames: [][]byte{
+			clientFrame(false, 0, wsOpText, []byte("a")),
+			clientFrame(true, 0, wsOpText, []byte("b")),
+		}, wantErr: "before previous one finished"},
+		{name: "unknown opcode", frames: [][]byte{clientFrame(true, 0, 0x3, nil)}, wantErr: "unknown opcode"},
+		{name: "close", frames: [][]byte{clientFrame(true, 0, wsOpClose, []byte{0x03, 0xe8})}, wantErr: io.EOF.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+			go io.Copy(io.Discard, client) // Pongs and close echoes
+			go client.Write(bytes.Join(tt.frames, nil))
+
+			c := &wsConn{conn: server, br: bufio.NewReader(server)}
+			msg, err := c.readMessage()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if tt.wantErr == errWSInvalidUTF8.Error() && !errors.Is(err, errWSInvalidUTF8) {
+					t.Errorf("error %v is not errWSInvalidUTF8", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(msg) != tt.want {
+				t.Errorf("message = %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
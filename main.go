@@ -11,9 +11,14 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sagacient/cute-pandas-mcp-server/config"
 	"github.com/sagacient/cute-pandas-mcp-server/executor"
@@ -30,10 +35,18 @@ import (
 func main() {
 	// Parse command line flags
 	var transport string
-	flag.StringVar(&transport, "t", "", "Transport type (stdio or http)")
-	flag.StringVar(&transport, "transport", "", "Transport type (stdio or http)")
+	flag.StringVar(&transport, "t", "", "Transport type (stdio, http, or sse)")
+	flag.StringVar(&transport, "transport", "", "Transport type (stdio, http, or sse)")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Path to a .env file loaded before reading the environment")
 	flag.Parse()
 
+	// Load .env file; a missing default file is not an error
+	if n, err := config.LoadEnvFile(*envFile); err == nil {
+		log.Printf("Loaded %d variables from %s", n, *envFile)
+	} else if !os.IsNotExist(err) || *envFile != config.DefaultEnvFile {
+		log.Fatalf("Failed to load env file: %v", err)
+	}
+
 	// Load configuration
 	cfg := config.LoadFromEnv()
 
@@ -43,7 +56,14 @@ func main() {
 	}
 
 	// Create worker pool
+	if err := cfg.ValidateAcquireMode(); err != nil {
+		log.Fatalf("Invalid ACQUIRE_MODE: %v", err)
+	}
 	pool := workerpool.NewPool(cfg.MaxWorkers, cfg.AcquireTimeout)
+	pool.SetMaxPerClient(cfg.MaxPerClient)
+	if cfg.AcquireMode == "queue" {
+		pool.SetMaxWaiting(cfg.QueueSize)
+	}
 
 	// Create Docker executor
 	exec, err := executor.NewDockerExecutor(
@@ -62,6 +82,52 @@ func main() {
 		log.Fatalf("Failed to create Docker executor: %v", err)
 	}
 	defer exec.Close()
+	if om := exec.GetOutputManager(); om != nil {
+		om.SetMaxTTL(cfg.OutputMaxTTL)
+	}
+	if cfg.OutputDir != "" {
+		exec.StartOutputCleanup(cfg.CleanupInterval)
+	}
+	if cfg.TempSweepAge > 0 {
+		if n := exec.SweepTempDirs(cfg.TempSweepAge); n > 0 {
+			log.Printf("Removed %d stale temp dir(s) older than %v", n, cfg.TempSweepAge)
+		}
+	}
+	if err := exec.SetNetworkMode(context.Background(), cfg.ContainerNetworkMode()); err != nil {
+		log.Fatalf("Invalid NETWORK_MODE: %v", err)
+	}
+	if err := cfg.ValidateNetworkPolicies(); err != nil {
+		log.Fatalf("Invalid NETWORK_POLICIES: %v", err)
+	}
+	if len(cfg.NetworkPolicies) > 0 {
+		if err := exec.SetNetworkPolicies(context.Background(), cfg.NetworkPolicies); err != nil {
+			log.Fatalf("Invalid NETWORK_POLICIES: %v", err)
+		}
+	}
+	if err := exec.SetContainerUser(cfg.ContainerUser); err != nil {
+		log.Fatalf("Invalid CONTAINER_USER: %v", err)
+	}
+	if cfg.DefaultOutputFormat != "" {
+		if err := exec.SetDefaultOutputFormat(cfg.DefaultOutputFormat); err != nil {
+			log.Fatalf("Invalid DEFAULT_OUTPUT_FORMAT: %v", err)
+		}
+	}
+	exec.SetWarnMixedTypes(cfg.WarnMixedTypes)
+	exec.SetMaxExtractSize(int64(cfg.MaxExtractMB) << 20)
+	if err := cfg.ValidateImageProfiles(); err != nil {
+		log.Fatalf("Invalid IMAGE_PROFILES: %v", err)
+	}
+	if len(cfg.ImageProfiles) > 0 {
+		exec.SetImageProfiles(cfg.ImageProfiles)
+	}
+	if err := exec.SetSharedDataDir(cfg.DataDir); err != nil {
+		log.Fatalf("Invalid DATA_DIR: %v", err)
+	}
+	if dir := exec.SharedDataDir(); dir != "" {
+		log.Printf("Shared data directory: %s (mounted read-only at %s)", dir, executor.SharedDataPath)
+	}
+
+	exec.SetImages(cfg.Images)
 
 	// Start Docker image build/pull in background (non-blocking)
 	if cfg.BuildLocal {
@@ -72,14 +138,15 @@ func main() {
 	ctx := context.Background()
 	exec.EnsureImageAsync(ctx)
 
-	// Initialize file store and scanner for HTTP mode
+	// Initialize file store and scanner for HTTP/SSE mode
 	var fileStore *storage.FileStore
 	var malwareScanner *scanner.Scanner
-	if cfg.Transport == "http" {
+	if cfg.Transport == "http" || cfg.Transport == "sse" {
 		// Initialize malware scanner
 		malwareScanner = scanner.NewScanner(scanner.Config{
-			Enabled:  cfg.ScanUploads,
-			FailOpen: cfg.ScanOnFail == "allow",
+			Enabled:        cfg.ScanUploads,
+			FailOpen:       cfg.ScanOnFail == "allow",
+			ClamdAddresses: cfg.ClamdAddresses,
 		})
 		if cfg.ScanUploads {
 			if malwareScanner.IsAvailable() {
@@ -90,6 +157,9 @@ func main() {
 		} else {
 			log.Printf("Malware scanning disabled")
 		}
+		if err := malwareScanner.StartDefinitionUpdates(cfg.FreshclamInterval); err != nil {
+			log.Printf("WARNING: Virus definition updates disabled: %v", err)
+		}
 
 		// Initialize file store with scanner
 		var err error
@@ -98,22 +168,56 @@ func main() {
 			log.Fatalf("Failed to create file store: %v", err)
 		}
 		defer fileStore.Close()
-		log.Printf("File storage enabled: dir=%s, ttl=%v, max_size=%d bytes",
-			fileStore.BaseDir(), cfg.UploadTTL, cfg.MaxUploadSize)
+		fileStore.SetCleanupInterval(cfg.CleanupInterval)
+		if err := fileStore.SetQuarantineDir(cfg.QuarantineDir); err != nil {
+			log.Fatalf("Invalid QUARANTINE_DIR: %v", err)
+		}
+		if dir := fileStore.QuarantineDir(); dir != "" {
+			log.Printf("Malware uploads are quarantined in %s", dir)
+		}
+		log.Printf("File storage enabled: dir=%s, ttl=%v, max_size=%d bytes, cleanup every %v",
+			fileStore.BaseDir(), cfg.UploadTTL, cfg.MaxUploadSize, cfg.CleanupInterval)
 	}
 
 	// Create MCP server
 	mcpServer, pandasTools := createMCPServer(cfg, pool, exec)
+	if len(cfg.ToolConcurrency) > 0 {
+		limiter := workerpool.NewLimiter(cfg.ToolConcurrency, cfg.AcquireTimeout)
+		if cfg.AcquireMode == "queue" {
+			limiter.SetMaxWaiting(cfg.QueueSize)
+		}
+		pandasTools.SetLimiter(limiter)
+		log.Printf("Per-tool concurrency limits: %v", cfg.ToolConcurrency)
+	}
 
-	// Set file store on tools if in HTTP mode
+	// Set file store on tools if in HTTP mode, and publish execution
+	// outputs to it so they can be passed back in as upload:// inputs
 	if fileStore != nil {
 		pandasTools.SetFileStore(fileStore)
+		exec.SetOutputExporter(fileStore)
 	}
 
 	// Handle graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reload live-adjustable settings on SIGHUP
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			log.Println("SIGHUP received, reloading configuration")
+			if _, err := config.LoadEnvFile(*envFile); err != nil && !os.IsNotExist(err) {
+				log.Printf("Reload: failed to load env file: %v", err)
+			}
+			next := config.LoadFromEnv()
+			if transport != "" {
+				next.Transport = transport
+			}
+			applyReload(cfg, next, pool, exec, fileStore)
+		}
+	}()
+
 	go func() {
 		<-sigCh
 		log.Println("Shutting down...")
@@ -125,14 +229,32 @@ func main() {
 	}()
 
 	// Start server based on transport type
-	if cfg.Transport == "http" {
+	switch cfg.Transport {
+	case "http", "sse":
 		log.Printf("Starting HTTP server on port %d", cfg.HTTPPort)
 		httpSrv := httpserver.NewServer(mcpServer, fileStore, cfg.MaxUploadSize)
+		httpSrv.SetMultipartMemory(cfg.MultipartMemory)
+		httpSrv.SetOutputManager(exec.GetOutputManager())
+		httpSrv.SetTimeouts(cfg.HTTPReadTimeout, cfg.HTTPWriteTimeout, cfg.HTTPIdleTimeout)
+		if cfg.DownloadSigningKey != "" {
+			if cfg.StorageAPIKey == "" {
+				log.Fatalf("DOWNLOAD_SIGNING_KEY requires STORAGE_API_KEY, otherwise anyone could sign download URLs")
+			}
+			httpSrv.SetSigningKey(cfg.DownloadSigningKey)
+		}
+		httpSrv.SetAPIKey(cfg.StorageAPIKey)
+		if err := httpSrv.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+			log.Fatalf("TRUSTED_PROXIES: %v", err)
+		}
+		httpSrv.SetAllowedOrigins(cfg.WSAllowedOrigins)
+		if cfg.Transport == "sse" {
+			httpSrv.EnableSSE()
+		}
 		addr := fmt.Sprintf(":%d", cfg.HTTPPort)
 		if err := httpSrv.Start(addr); err != nil {
 			log.Fatalf("HTTP server error: %v", err)
 		}
-	} else {
+	default:
 		log.Println("Starting stdio server...")
 		if err := server.ServeStdio(mcpServer); err != nil {
 			log.Fatalf("Stdio server error: %v", err)
@@ -140,6 +262,80 @@ func main() {
 	}
 }
 
+// applyReload applies the settings in next that can change while running
+// (worker pool size, execution timeout, upload and output TTLs) to cur and
+// the running components, and logs changes to anything else as requiring a
+// restart. In-flight executions keep the limits they started with.
+func applyReload(cur, next *config.Config, pool *workerpool.Pool, exec *executor.DockerExecutor, fileStore *storage.FileStore) {
+	if next.MaxWorkers != cur.MaxWorkers {
+		pool.SetMaxWorkers(next.MaxWorkers)
+		log.Printf("Reload: MAX_WORKERS %d -> %d", cur.MaxWorkers, next.MaxWorkers)
+		cur.MaxWorkers = next.MaxWorkers
+	}
+	if next.ExecutionTimeout != cur.ExecutionTimeout {
+		exec.SetExecutionTimeout(next.ExecutionTimeout)
+		log.Printf("Reload: EXECUTION_TIMEOUT %v -> %v", cur.ExecutionTimeout, next.ExecutionTimeout)
+		cur.ExecutionTimeout = next.ExecutionTimeout
+	}
+	if next.UploadTTL != cur.UploadTTL && fileStore != nil {
+		fileStore.SetTTL(next.UploadTTL)
+		log.Printf("Reload: UPLOAD_TTL %v -> %v (applies to new uploads)", cur.UploadTTL, next.UploadTTL)
+		cur.UploadTTL = next.UploadTTL
+	}
+	if next.OutputTTL != cur.OutputTTL {
+		if om := exec.GetOutputManager(); om != nil {
+			om.SetTTL(next.OutputTTL)
+			log.Printf("Reload: OUTPUT_TTL %v -> %v (applies to new executions)", cur.OutputTTL, next.OutputTTL)
+			cur.OutputTTL = next.OutputTTL
+		}
+	}
+
+	// An invalid value is reported instead of being noted as changed
+	if err := next.ValidateAcquireMode(); err != nil {
+		log.Printf("Reload: invalid ACQUIRE_MODE: %v", err)
+		next.AcquireMode = cur.AcquireMode
+	}
+
+	restart := []struct {
+		name    string
+		changed bool
+	}{
+		{"TRANSPORT", next.Transport != cur.Transport},
+		{"HTTP_PORT", next.HTTPPort != cur.HTTPPort},
+		{"TOOL_CONCURRENCY", !maps.Equal(next.ToolConcurrency, cur.ToolConcurrency)},
+		{"ACQUIRE_MODE", next.AcquireMode != cur.AcquireMode},
+		{"QUEUE_SIZE", next.QueueSize != cur.QueueSize},
+		{"HTTP_READ_TIMEOUT", next.HTTPReadTimeout != cur.HTTPReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", next.HTTPWriteTimeout != cur.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", next.HTTPIdleTimeout != cur.HTTPIdleTimeout},
+		{"DOWNLOAD_SIGNING_KEY", next.DownloadSigningKey != cur.DownloadSigningKey},
+		{"STORAGE_API_KEY", next.StorageAPIKey != cur.StorageAPIKey},
+		{"TRUSTED_PROXIES", !slices.Equal(next.TrustedProxies, cur.TrustedProxies)},
+		{"WS_ALLOWED_ORIGINS", !slices.Equal(next.WSAllowedOrigins, cur.WSAllowedOrigins)},
+		{"DOCKER_IMAGE", next.DockerImage != cur.DockerImage},
+		{"CLEANUP_INTERVAL", next.CleanupInterval != cur.CleanupInterval},
+		{"IMAGES", !maps.Equal(next.Images, cur.Images)},
+		{"BUILD_LOCAL", next.BuildLocal != cur.BuildLocal},
+		{"MAX_MEMORY_MB", next.MaxMemoryMB != cur.MaxMemoryMB},
+		{"MAX_CPU", next.MaxCPU != cur.MaxCPU},
+		{"NETWORK_MODE", next.ContainerNetworkMode() != cur.ContainerNetworkMode()},
+		{"NETWORK_POLICIES", !reflect.DeepEqual(next.NetworkPolicies, cur.NetworkPolicies)},
+		{"STORAGE_DIR", next.StorageDir != cur.StorageDir},
+		{"QUARANTINE_DIR", next.QuarantineDir != cur.QuarantineDir},
+		{"OUTPUT_DIR", next.OutputDir != cur.OutputDir},
+		{"TEMP_DIR", next.TempDir != cur.TempDir},
+		{"DATA_DIR", next.DataDir != cur.DataDir},
+		{"CONTAINER_USER", next.ContainerUser != cur.ContainerUser},
+		{"WARN_MIXED_TYPES", next.WarnMixedTypes != cur.WarnMixedTypes},
+		{"MAX_EXTRACT_MB", next.MaxExtractMB != cur.MaxExtractMB},
+	}
+	for _, r := range restart {
+		if r.changed {
+			log.Printf("Reload: %s changed but requires restart; keeping the running value", r.name)
+		}
+	}
+}
+
 func createMCPServer(cfg *config.Config, pool *workerpool.Pool, exec *executor.DockerExecutor) (*server.MCPServer, *tools.PandasTools) {
 	// Create hooks for logging
 	hooks := &server.Hooks{}
@@ -161,25 +357,58 @@ func createMCPServer(cfg *config.Config, pool *workerpool.Pool, exec *executor.D
 		"cute-pandas",
 		"1.0.0",
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
 		server.WithHooks(hooks),
 		server.WithRecovery(),
 	)
 
 	// Create tools handler
-	pandasTools := tools.NewPandasTools(pool, exec)
+	pandasTools := tools.NewPandasTools(pool, exec, tools.Options{
+		PreviewRows:    cfg.PreviewRows,
+		PreviewCols:    cfg.PreviewCols,
+		MaxOperations:  cfg.MaxOperations,
+		MaxScriptBytes: cfg.MaxScriptBytes,
+		MaxInputFiles:  cfg.MaxInputFiles,
+		InputSizeRatio: cfg.InputSizeRatio,
+		MaxCorrColumns: cfg.MaxCorrColumns,
+		MaxValueCounts: cfg.MaxValueCounts,
+		MaxReturnRows:  cfg.MaxReturnRows,
+		TextExtensions: cfg.TextExtensions,
+		FastFailTools:  cfg.FastFailTools,
+		AcquireMode:    cfg.AcquireMode,
+		CallbackHosts:  cfg.CallbackAllowedHosts,
+		CallbackSecret: cfg.CallbackSecret,
+	})
 
 	// Register tools
 	mcpServer.AddTool(tools.RunScriptTool(), pandasTools.RunScriptHandler)
 	mcpServer.AddTool(tools.ReadDataFrameTool(), pandasTools.ReadDataFrameHandler)
+	mcpServer.AddTool(tools.PeekTool(), pandasTools.PeekHandler)
+	mcpServer.AddTool(tools.GetSchemaTool(), pandasTools.GetSchemaHandler)
 	mcpServer.AddTool(tools.AnalyzeDataTool(), pandasTools.AnalyzeDataHandler)
 	mcpServer.AddTool(tools.TransformDataTool(), pandasTools.TransformDataHandler)
 	mcpServer.AddTool(tools.QueryDataTool(), pandasTools.QueryDataHandler)
 	mcpServer.AddTool(tools.ProfileDataTool(), pandasTools.ProfileDataHandler)
+	mcpServer.AddTool(tools.PivotTableTool(), pandasTools.PivotTableHandler)
+	mcpServer.AddTool(tools.CrosstabTool(), pandasTools.CrosstabHandler)
+	mcpServer.AddTool(tools.ColumnCardinalityTool(), pandasTools.ColumnCardinalityHandler)
+	mcpServer.AddTool(tools.InferTypesTool(), pandasTools.InferTypesHandler)
+	mcpServer.AddTool(tools.PipelineTool(), pandasTools.PipelineHandler)
+	mcpServer.AddTool(tools.MergeAsofTool(), pandasTools.MergeAsofHandler)
+	mcpServer.AddTool(tools.ValidateSchemaTool(), pandasTools.ValidateSchemaHandler)
+	mcpServer.AddTool(tools.EstimateImportanceTool(), pandasTools.EstimateImportanceHandler)
 
 	// Output management tools
 	mcpServer.AddTool(tools.ListOutputsTool(), pandasTools.ListOutputsHandler)
+	mcpServer.AddTool(tools.ListRunningTool(), pandasTools.ListRunningHandler)
 	mcpServer.AddTool(tools.GetOutputTool(), pandasTools.GetOutputHandler)
 	mcpServer.AddTool(tools.DeleteOutputsTool(), pandasTools.DeleteOutputsHandler)
+	mcpServer.AddTool(tools.ExtendOutputTTLTool(), pandasTools.ExtendOutputTTLHandler)
+	mcpServer.AddTool(tools.RerunTool(), pandasTools.RerunHandler)
+
+	// Output resources (output://{exec_id} and output://{exec_id}/{filename})
+	mcpServer.AddResourceTemplate(tools.OutputExecutionTemplate(), pandasTools.OutputExecutionResourceHandler)
+	mcpServer.AddResourceTemplate(tools.OutputFileTemplate(), pandasTools.OutputFileResourceHandler)
 
 	// Add a status tool for checking server health
 	mcpServer.AddTool(
@@ -205,21 +434,60 @@ func createMCPServer(cfg *config.Config, pool *workerpool.Pool, exec *executor.D
 				serverStatus = "INITIALIZING"
 			}
 
+			var extraImages string
+			for _, name := range exec.ImageNames()[1:] {
+				ref, ready, err := exec.ImageStatus(name)
+				state := "READY"
+				if err != nil {
+					state = fmt.Sprintf("PULL FAILED: %v", err)
+				} else if !ready {
+					state = "PULLING..."
+				}
+				extraImages += fmt.Sprintf("\n  %s: %s (%s)", name, ref, state)
+			}
+			if extraImages != "" {
+				extraImages = "\nExtra Images:" + extraImages
+			}
+
+			var networkPolicies string
+			policies := exec.NetworkPolicies()
+			for _, name := range slices.Sorted(maps.Keys(policies)) {
+				networkPolicies += fmt.Sprintf("\n  %s: %s (hosts: %s)", name, policies[name].Network, strings.Join(policies[name].Hosts, ", "))
+			}
+			if networkPolicies != "" {
+				networkPolicies = "\nNetwork Policies:" + networkPolicies
+			}
+
+			acquireMode := cfg.AcquireMode
+			if acquireMode == "queue" {
+				acquireMode = fmt.Sprintf("queue (up to %d waiting)", cfg.QueueSize)
+			}
+
 			status := fmt.Sprintf(`Cute Pandas MCP Server Status
 ==============================
 Docker Image:     %s
-Image Status:     %s
+Image Status:     %s%s%s
 Max Workers:      %d
+Acquire Mode:     %s
 Active Workers:   %d
 Available Slots:  %d
 Total Processed:  %d
+Waiting:          %d
+Active Clients:   %d
+Avg Duration:     %s
 Server Status:    %s`,
 				cfg.DockerImage,
 				imageStatus,
+				extraImages,
+				networkPolicies,
 				stats.MaxWorkers,
+				acquireMode,
 				stats.ActiveWorkers,
 				stats.AvailableSlots,
 				stats.TotalProcessed,
+				stats.Waiting,
+				stats.ActiveClients,
+				stats.AvgDuration.Round(time.Millisecond),
 				serverStatus,
 			)
 			return mcp.NewToolResultText(status), nil
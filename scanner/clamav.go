@@ -26,11 +26,13 @@ type ScanResult struct {
 // Scanner provides malware scanning using ClamAV.
 type Scanner struct {
 	enabled     bool
-	failOpen    bool   // If true, allow uploads when scanner fails
-	clamdSocket string // Path to clamd socket (optional)
+	failOpen    bool     // If true, allow uploads when scanner fails
+	clamdSocket string   // Path to clamd socket (optional)
+	clamdAddrs  []string // clamd instances scanned over the clamd protocol, in failover order
 	mu          sync.Mutex
 	available   bool
 	checkedOnce bool
+	defs        DefinitionsInfo // Last freshclam outcome, guarded by mu
 }
 
 // Config holds scanner configuration.
@@ -38,6 +40,11 @@ type Config struct {
 	Enabled     bool   // Enable/disable scanning
 	FailOpen    bool   // If true, allow uploads when scanner unavailable
 	ClamdSocket string // Optional: path to clamd socket
+
+	// Optional: clamd addresses ("host:3310", "tcp://host:3310", or a unix
+	// socket path) to scan on directly, tried in order. When set, the
+	// clamdscan and clamscan commands are not used.
+	ClamdAddresses []string
 }
 
 // NewScanner creates a new ClamAV scanner.
@@ -46,6 +53,7 @@ func NewScanner(cfg Config) *Scanner {
 		enabled:     cfg.Enabled,
 		failOpen:    cfg.FailOpen,
 		clamdSocket: cfg.ClamdSocket,
+		clamdAddrs:  cfg.ClamdAddresses,
 	}
 
 	if s.enabled {
@@ -66,6 +74,22 @@ func (s *Scanner) checkAvailability() {
 	}
 	s.checkedOnce = true
 
+	if len(s.clamdAddrs) > 0 {
+		for _, addr := range s.clamdAddrs {
+			if reply, err := clamdCommand(addr, "PING"); err == nil && reply == "PONG" {
+				s.available = true
+				log.Printf("ClamAV scanner available (clamd at %s)", addr)
+			} else {
+				log.Printf("WARNING: clamd at %s not responding: %v", addr, err)
+			}
+		}
+		if !s.available {
+			log.Printf("WARNING: no clamd instance responding. Scanning will be %s until one is",
+				map[bool]string{true: "skipped (fail-open mode)", false: "rejected (fail-closed mode)"}[s.failOpen])
+		}
+		return
+	}
+
 	// Try clamdscan first (faster, uses daemon)
 	if s.clamdSocket != "" {
 		cmd := exec.Command("clamdscan", "--version")
@@ -102,8 +126,12 @@ func (s *Scanner) IsEnabled() bool {
 	return s.enabled
 }
 
-// IsAvailable returns whether ClamAV is available.
+// IsAvailable returns whether ClamAV is available. With clamd addresses
+// configured, it checks that at least one instance answers now.
 func (s *Scanner) IsAvailable() bool {
+	if len(s.clamdAddrs) > 0 {
+		return s.reachableClamd() != ""
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.available
@@ -119,6 +147,12 @@ func (s *Scanner) Scan(filePath string) ScanResult {
 		return ScanResult{Clean: true, Scanned: false}
 	}
 
+	// clamd instances are tried on every scan, so ones that come back up
+	// are used again
+	if len(s.clamdAddrs) > 0 {
+		return s.scanWithClamd(filePath)
+	}
+
 	s.mu.Lock()
 	available := s.available
 	s.mu.Unlock()
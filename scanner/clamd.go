@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	clamdDialTimeout = 3 * time.Second // Connecting to one clamd instance
+	clamdScanTimeout = 5 * time.Minute // Streaming and scanning one file
+	clamdChunkSize   = 64 << 10        // INSTREAM chunk size
+	clamdMaxReply    = 4 << 10         // Longest reply read from clamd
+)
+
+// clamdNetwork splits a clamd address into a network and address for
+// net.Dial. "unix:///run/clamd.sock" and "/run/clamd.sock" are unix sockets;
+// "tcp://host:3310" and "host:3310" are TCP.
+func clamdNetwork(addr string) (network, address string) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "/"):
+		return "unix", addr
+	}
+	return "tcp", strings.TrimPrefix(addr, "tcp://")
+}
+
+// clamdDial connects to the clamd at addr with a deadline for the exchange.
+func clamdDial(addr string, timeout time.Duration) (net.Conn, error) {
+	network, address := clamdNetwork(addr)
+	conn, err := net.DialTimeout(network, address, clamdDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+// readClamdReply reads a NUL-terminated reply.
+func readClamdReply(conn net.Conn) (string, error) {
+	reply, err := io.ReadAll(io.LimitReader(conn, clamdMaxReply))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes.TrimRight(reply, "\x00"))), nil
+}
+
+// clamdCommand sends a command without arguments (PING, VERSION, ...) to
+// the clamd at addr and returns its reply.
+func clamdCommand(addr, command string) (string, error) {
+	conn, err := clamdDial(addr, clamdDialTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("z" + command + "\x00")); err != nil {
+		return "", err
+	}
+	return readClamdReply(conn)
+}
+
+// clamdInstream streams filePath to the clamd at addr with INSTREAM and
+// returns its reply, e.g. "stream: OK" or "stream: Eicar-Signature FOUND".
+func clamdInstream(addr, filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	conn, err := clamdDial(addr, clamdScanTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := f.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd closes the connection early when a size limit is hit;
+				// its reply explains why
+				if reply, rerr := readClamdReply(conn); rerr == nil && reply != "" {
+					return reply, nil
+				}
+				return "", werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+	return readClamdReply(conn)
+}
+
+// scanWithClamd scans filePath on the configured clamd instances, in order,
+// failing over to the next when one is unreachable or errors. The scanner
+// is only treated as unavailable when every instance failed.
+func (s *Scanner) scanWithClamd(filePath string) ScanResult {
+	var failures []string
+	for _, addr := range s.clamdAddrs {
+		reply, err := clamdInstream(addr, filePath)
+		if err == nil && strings.HasSuffix(reply, "ERROR") {
+			err = fmt.Errorf("%s", reply)
+		}
+		if err != nil {
+			log.Printf("clamd %s failed: %v", addr, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", addr, err))
+			continue
+		}
+
+		if strings.HasSuffix(reply, "FOUND") {
+			threat := strings.TrimSpace(strings.TrimSuffix(reply[strings.LastIndex(reply, ":")+1:], "FOUND"))
+			log.Printf("MALWARE DETECTED in %s: %s (clamd %s)", filePath, threat, addr)
+			return ScanResult{Clean: false, Threat: threat, Scanned: true}
+		}
+		log.Printf("File scanned clean: %s (clamd %s)", filePath, addr)
+		return ScanResult{Clean: true, Scanned: true}
+	}
+
+	if s.failOpen {
+		log.Printf("WARNING: all clamd instances unavailable, allowing file without scan: %s", filePath)
+		return ScanResult{Clean: true, Scanned: false}
+	}
+	return ScanResult{
+		Error:   fmt.Errorf("all clamd instances unavailable: %s", strings.Join(failures, "; ")),
+		Scanned: false,
+	}
+}
+
+// reachableClamd returns the first configured clamd instance that answers
+// PING, or "" if none does.
+func (s *Scanner) reachableClamd() string {
+	for _, addr := range s.clamdAddrs {
+		if reply, err := clamdCommand(addr, "PING"); err == nil && reply == "PONG" {
+			return addr
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefinitionsInfo describes the loaded virus definitions and the outcome of
+// the most recent freshclam run started by this process.
+type DefinitionsInfo struct {
+	Version    string     `json:"version,omitempty"`     // Signature database version
+	Date       string     `json:"date,omitempty"`        // Signature database build date
+	LastUpdate *time.Time `json:"last_update,omitempty"` // When freshclam last ran (nil if never)
+	LastError  string     `json:"last_error,omitempty"`  // Error from the last freshclam run
+}
+
+// StartDefinitionUpdates runs freshclam every interval in the background.
+// Use it for standalone deployments; leave it off when a freshclam daemon
+// or the clamd host already keeps definitions current.
+func (s *Scanner) StartDefinitionUpdates(interval time.Duration) error {
+	if !s.enabled || interval <= 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("freshclam"); err != nil {
+		return fmt.Errorf("freshclam not found: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.updateDefinitions()
+		}
+	}()
+	log.Printf("Virus definition updates enabled (freshclam every %v)", interval)
+	return nil
+}
+
+// updateDefinitions runs freshclam once and records the outcome.
+func (s *Scanner) updateDefinitions() {
+	cmd := exec.Command("freshclam", "--stdout", "--quiet")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	now := time.Now()
+	s.mu.Lock()
+	s.defs.LastUpdate = &now
+	s.defs.LastError = ""
+	if err != nil {
+		s.defs.LastError = strings.TrimSpace(fmt.Sprintf("%v: %s", err, out.String()))
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("freshclam update failed: %v: %s", err, strings.TrimSpace(out.String()))
+		return
+	}
+
+	// Ask a running clamd to load the new database (no-op in standalone mode)
+	_ = exec.Command("clamdscan", "--reload").Run()
+
+	info := s.Definitions()
+	log.Printf("freshclam update succeeded (database version %s, %s)", info.Version, info.Date)
+}
+
+// Definitions returns the current signature database version and date, as
+// reported by "clamscan --version" (or the first reachable clamd instance's
+// VERSION), along with the last update outcome.
+func (s *Scanner) Definitions() DefinitionsInfo {
+	s.mu.Lock()
+	info := s.defs
+	s.mu.Unlock()
+
+	if addr := s.reachableClamd(); addr != "" {
+		if out, err := clamdCommand(addr, "VERSION"); err == nil {
+			info.Version, info.Date = parseVersionLine(out)
+		}
+		return info
+	}
+	out, err := exec.Command("clamscan", "--version").Output()
+	if err == nil {
+		info.Version, info.Date = parseVersionLine(string(out))
+	}
+	return info
+}
+
+// parseVersionLine extracts the database version and date from a line like
+// "ClamAV 1.0.7/27401/Tue Sep 16 08:27:05 2025".
+func parseVersionLine(line string) (version, date string) {
+	parts := strings.SplitN(strings.TrimSpace(line), "/", 3)
+	if len(parts) < 3 {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
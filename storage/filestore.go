@@ -10,6 +10,8 @@ import (
 	"crypto/rand"
 	"github.com/sagacient/cute-pandas-mcp-server/scanner"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,21 +31,38 @@ type FileInfo struct {
 	Size       int64     `json:"size"`
 	UploadedAt time.Time `json:"uploaded_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
-	FileRef    string    `json:"file_ref"` // upload://id reference for tool calls
+	FileRef    string    `json:"file_ref"`            // upload://id reference for tool calls
+	Immutable  bool      `json:"immutable,omitempty"` // Cannot be deleted before ExpiresAt
+}
+
+// UploadOptions controls how an uploaded file is stored.
+type UploadOptions struct {
+	// Immutable files cannot be deleted before they expire.
+	Immutable bool
 }
 
 // FileStore manages uploaded files with automatic TTL-based cleanup.
 type FileStore struct {
 	baseDir string
-	ttl     time.Duration
+	ttl     atomic.Int64 // time.Duration; changed live by SetTTL
 	maxSize int64
 	scanner *scanner.Scanner
 	files   map[string]*FileInfo
 	mu      sync.RWMutex
 	stopCh  chan struct{}
+	stopped sync.Once
 	wg      sync.WaitGroup
+
+	cleanupInterval atomic.Int64 // time.Duration between expiry sweeps
+	intervalCh      chan struct{}
+
+	quarantineDir string // Where malware uploads are moved; "" deletes them
 }
 
+// DefaultCleanupInterval is how often expired uploads are swept unless
+// SetCleanupInterval is called.
+const DefaultCleanupInterval = time.Minute
+
 // NewFileStore creates a new FileStore with the given configuration.
 // It starts a background cleanup goroutine that removes expired files.
 // The scanner parameter can be nil to disable malware scanning.
@@ -60,15 +80,21 @@ func NewFileStore(baseDir string, ttl time.Duration, maxSize int64, sc *scanner.
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory %s: %w", baseDir, err)
 	}
+	if err := os.MkdirAll(filepath.Join(baseDir, metadataDir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create metadata directory in %s: %w", baseDir, err)
+	}
 
 	fs := &FileStore{
 		baseDir: baseDir,
-		ttl:     ttl,
 		maxSize: maxSize,
 		scanner: sc,
 		files:   make(map[string]*FileInfo),
 		stopCh:  make(chan struct{}),
+
+		intervalCh: make(chan struct{}, 1),
 	}
+	fs.ttl.Store(int64(ttl))
+	fs.cleanupInterval.Store(int64(DefaultCleanupInterval))
 
 	// Load existing files from disk (for restart recovery)
 	fs.loadExistingFiles()
@@ -85,8 +111,55 @@ func NewFileStore(baseDir string, ttl time.Duration, maxSize int64, sc *scanner.
 	return fs, nil
 }
 
-// loadExistingFiles scans the storage directory for existing files.
-// Files without metadata are assigned a new TTL from now.
+// metadataDir is the subdirectory of the storage directory holding each
+// upload's FileInfo as <id>.json, so expiry and immutability survive a
+// restart.
+const metadataDir = ".meta"
+
+// metadataPath returns where the FileInfo of upload id is recorded.
+func (fs *FileStore) metadataPath(id string) string {
+	return filepath.Join(fs.baseDir, metadataDir, id+".json")
+}
+
+// writeMetadata records info next to the stored files.
+func (fs *FileStore) writeMetadata(info *FileInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fs.metadataPath(info.ID), data, 0600)
+}
+
+// readMetadata returns the recorded FileInfo of upload id, without its Path.
+func (fs *FileStore) readMetadata(id string) (*FileInfo, error) {
+	data, err := os.ReadFile(fs.metadataPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var info FileInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	if info.ID != id {
+		return nil, fmt.Errorf("metadata is for %q", info.ID)
+	}
+	return &info, nil
+}
+
+// removeFile deletes a stored file and its metadata.
+func (fs *FileStore) removeFile(info *FileInfo) error {
+	if err := os.Remove(info.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(fs.metadataPath(info.ID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove metadata of %s: %v", info.ID, err)
+	}
+	return nil
+}
+
+// loadExistingFiles scans the storage directory for existing files. Files
+// keep the expiry and immutability recorded when they were uploaded; files
+// without metadata are assigned a new TTL from now.
 func (fs *FileStore) loadExistingFiles() {
 	entries, err := os.ReadDir(fs.baseDir)
 	if err != nil {
@@ -114,15 +187,21 @@ func (fs *FileStore) loadExistingFiles() {
 		id := parts[0]
 		originalName := parts[1]
 
-		fileInfo := &FileInfo{
-			ID:         id,
-			Name:       originalName,
-			Path:       filepath.Join(fs.baseDir, name),
-			Size:       info.Size(),
-			UploadedAt: info.ModTime(),
-			ExpiresAt:  time.Now().Add(fs.ttl), // Reset TTL on restart
-			FileRef:    "upload://" + id,
+		fileInfo, err := fs.readMetadata(id)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Warning: ignoring unreadable metadata of %s: %v", name, err)
+			}
+			fileInfo = &FileInfo{
+				ID:         id,
+				Name:       originalName,
+				UploadedAt: info.ModTime(),
+				ExpiresAt:  time.Now().Add(fs.TTL()), // No recorded expiry
+				FileRef:    "upload://" + id,
+			}
 		}
+		fileInfo.Path = filepath.Join(fs.baseDir, name)
+		fileInfo.Size = info.Size()
 
 		fs.files[id] = fileInfo
 		log.Printf("Loaded existing file: %s (expires at %v)", name, fileInfo.ExpiresAt)
@@ -133,19 +212,39 @@ func (fs *FileStore) loadExistingFiles() {
 func (fs *FileStore) cleanupLoop() {
 	defer fs.wg.Done()
 
-	ticker := time.NewTicker(1 * time.Minute)
+	ticker := time.NewTicker(fs.CleanupInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-fs.stopCh:
 			return
+		case <-fs.intervalCh:
+			ticker.Reset(fs.CleanupInterval())
 		case <-ticker.C:
 			fs.cleanup()
 		}
 	}
 }
 
+// CleanupInterval returns how often expired files are swept.
+func (fs *FileStore) CleanupInterval() time.Duration {
+	return time.Duration(fs.cleanupInterval.Load())
+}
+
+// SetCleanupInterval changes how often expired files are swept. It takes
+// effect immediately; non-positive intervals are ignored.
+func (fs *FileStore) SetCleanupInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	fs.cleanupInterval.Store(int64(d))
+	select {
+	case fs.intervalCh <- struct{}{}:
+	default:
+	}
+}
+
 // cleanup removes expired files.
 func (fs *FileStore) cleanup() {
 	fs.mu.Lock()
@@ -154,7 +253,7 @@ func (fs *FileStore) cleanup() {
 	now := time.Now()
 	for id, info := range fs.files {
 		if now.After(info.ExpiresAt) {
-			if err := os.Remove(info.Path); err != nil {
+			if err := fs.removeFile(info); err != nil {
 				log.Printf("Warning: failed to remove expired file %s: %v", info.Path, err)
 			} else {
 				log.Printf("Cleaned up expired file: %s (was uploaded at %v)", info.Name, info.UploadedAt)
@@ -164,9 +263,10 @@ func (fs *FileStore) cleanup() {
 	}
 }
 
-// Close stops the cleanup goroutine and releases resources.
+// Close stops the cleanup goroutine and releases resources. It is safe to
+// call more than once.
 func (fs *FileStore) Close() error {
-	close(fs.stopCh)
+	fs.stopped.Do(func() { close(fs.stopCh) })
 	fs.wg.Wait()
 	return nil
 }
@@ -180,6 +280,16 @@ func (e *ErrMalwareDetected) Error() string {
 	return fmt.Sprintf("malware detected: %s", e.Threat)
 }
 
+// ErrImmutable is returned when deleting an immutable file before it expires.
+type ErrImmutable struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+func (e *ErrImmutable) Error() string {
+	return fmt.Sprintf("file %s is immutable until %s", e.ID, e.ExpiresAt.Format(time.RFC3339))
+}
+
 // ErrScannerUnavailable is returned when the scanner is unavailable and fail-open is disabled.
 type ErrScannerUnavailable struct{}
 
@@ -190,6 +300,11 @@ func (e *ErrScannerUnavailable) Error() string {
 // Upload saves a file from the reader and returns its metadata.
 // If scanning is enabled, the file is scanned for malware before being stored.
 func (fs *FileStore) Upload(filename string, r io.Reader) (*FileInfo, error) {
+	return fs.UploadWithOptions(filename, r, UploadOptions{})
+}
+
+// UploadWithOptions is like Upload but applies the given storage options.
+func (fs *FileStore) UploadWithOptions(filename string, r io.Reader, opts UploadOptions) (*FileInfo, error) {
 	// Generate unique ID
 	id, err := generateID()
 	if err != nil {
@@ -235,7 +350,23 @@ func (fs *FileStore) Upload(filename string, r io.Reader) (*FileInfo, error) {
 			return nil, &ErrScannerUnavailable{}
 		}
 		if !result.Clean {
-			os.Remove(filePath)
+			if fs.quarantineDir != "" {
+				err := fs.quarantine(filePath, QuarantineRecord{
+					ID:            id,
+					OriginalName:  filename,
+					Threat:        result.Threat,
+					Size:          size,
+					QuarantinedAt: time.Now(),
+				})
+				if err != nil {
+					log.Printf("Warning: failed to quarantine %s, deleting it: %v", filename, err)
+					os.Remove(filePath)
+				} else {
+					log.Printf("SECURITY: Quarantined malware upload - file=%s, threat=%s, path=%s", filename, result.Threat, filepath.Join(fs.quarantineDir, storedName))
+				}
+			} else {
+				os.Remove(filePath)
+			}
 			log.Printf("SECURITY: Rejected malware upload - file=%s, threat=%s", filename, result.Threat)
 			return nil, &ErrMalwareDetected{Threat: result.Threat}
 		}
@@ -248,18 +379,41 @@ func (fs *FileStore) Upload(filename string, r io.Reader) (*FileInfo, error) {
 		Path:       filePath,
 		Size:       size,
 		UploadedAt: now,
-		ExpiresAt:  now.Add(fs.ttl),
+		ExpiresAt:  now.Add(fs.TTL()),
 		FileRef:    "upload://" + id,
+		Immutable:  opts.Immutable,
+	}
+	if err := fs.writeMetadata(info); err != nil {
+		// Without it an immutable file would become deletable on restart
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to record file metadata: %w", err)
 	}
 
 	fs.mu.Lock()
 	fs.files[id] = info
 	fs.mu.Unlock()
 
-	log.Printf("Uploaded file: %s (id=%s, size=%d, expires=%v)", filename, id, size, info.ExpiresAt)
+	log.Printf("Uploaded file: %s (id=%s, size=%d, expires=%v, immutable=%v)", filename, id, size, info.ExpiresAt, info.Immutable)
 	return info, nil
 }
 
+// ExportOutput stores a copy of an execution output file and returns its
+// upload:// reference, so the output can be passed to later tool calls.
+// The copy goes through Upload, so size limits and scanning apply.
+func (fs *FileStore) ExportOutput(filename, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open output: %w", err)
+	}
+	defer f.Close()
+
+	info, err := fs.Upload(filename, f)
+	if err != nil {
+		return "", err
+	}
+	return info.FileRef, nil
+}
+
 // GetPath returns the filesystem path for an upload ID.
 // Returns empty string and false if not found.
 func (fs *FileStore) GetPath(id string) (string, bool) {
@@ -306,7 +460,8 @@ func (fs *FileStore) List() []*FileInfo {
 	return result
 }
 
-// Delete removes a file by ID.
+// Delete removes a file by ID. Immutable files are refused with
+// ErrImmutable until they expire; the TTL cleanup removes them afterwards.
 func (fs *FileStore) Delete(id string) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -315,8 +470,11 @@ func (fs *FileStore) Delete(id string) error {
 	if !ok {
 		return fmt.Errorf("file not found: %s", id)
 	}
+	if info.Immutable && time.Now().Before(info.ExpiresAt) {
+		return &ErrImmutable{ID: id, ExpiresAt: info.ExpiresAt}
+	}
 
-	if err := os.Remove(info.Path); err != nil && !os.IsNotExist(err) {
+	if err := fs.removeFile(info); err != nil {
 		return fmt.Errorf("failed to remove file: %w", err)
 	}
 
@@ -340,6 +498,11 @@ func (fs *FileStore) ResolveUploadURI(uri string) (string, error) {
 	return path, nil
 }
 
+// Scanner returns the malware scanner used for uploads, or nil.
+func (fs *FileStore) Scanner() *scanner.Scanner {
+	return fs.scanner
+}
+
 // BaseDir returns the storage directory path.
 func (fs *FileStore) BaseDir() string {
 	return fs.baseDir
@@ -347,7 +510,13 @@ func (fs *FileStore) BaseDir() string {
 
 // TTL returns the configured TTL duration.
 func (fs *FileStore) TTL() time.Duration {
-	return fs.ttl
+	return time.Duration(fs.ttl.Load())
+}
+
+// SetTTL changes the TTL given to new uploads. Existing files keep their
+// current expiry.
+func (fs *FileStore) SetTTL(d time.Duration) {
+	fs.ttl.Store(int64(d))
 }
 
 // generateID creates a cryptographically random ID.
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteAcrossReload(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileStore(dir, time.Hour, 1<<20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := fs.UploadWithOptions("ledger.csv", strings.NewReader("a\n1\n"), UploadOptions{Immutable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := fs.Upload("scratch.csv", strings.NewReader("a\n2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+
+	// A restart with a different TTL must not move the recorded expiry
+	fs, err = NewFileStore(dir, 5*time.Hour, 1<<20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	reloaded, ok := fs.Get(locked.ID)
+	if !ok {
+		t.Fatalf("immutable file %s not reloaded", locked.ID)
+	}
+	if !reloaded.Immutable {
+		t.Error("immutable flag lost on reload")
+	}
+	if !reloaded.ExpiresAt.Equal(locked.ExpiresAt) {
+		t.Errorf("expiry changed on reload: got %v, want %v", reloaded.ExpiresAt, locked.ExpiresAt)
+	}
+
+	var immutableErr *ErrImmutable
+	if err := fs.Delete(locked.ID); !errors.As(err, &immutableErr) {
+		t.Errorf("Delete of reloaded immutable file: got %v, want ErrImmutable", err)
+	}
+	if _, err := os.Stat(reloaded.Path); err != nil {
+		t.Errorf("immutable file removed: %v", err)
+	}
+
+	if err := fs.Delete(plain.ID); err != nil {
+		t.Errorf("Delete of reloaded mutable file: %v", err)
+	}
+	if _, err := os.Stat(fs.metadataPath(plain.ID)); !os.IsNotExist(err) {
+		t.Errorf("metadata of deleted file kept: stat error %v", err)
+	}
+}
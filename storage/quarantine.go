@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// QuarantineRecord describes a quarantined upload. It is written next to the
+// file as <stored name>.json.
+type QuarantineRecord struct {
+	ID            string    `json:"id"`
+	OriginalName  string    `json:"original_name"`
+	Threat        string    `json:"threat"`
+	Size          int64     `json:"size"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+// SetQuarantineDir makes uploads with detected malware move to dir, with a
+// QuarantineRecord, instead of being deleted. dir must be outside the
+// storage directory so quarantined files are never served. "" deletes them.
+func (fs *FileStore) SetQuarantineDir(dir string) error {
+	if dir == "" {
+		fs.quarantineDir = ""
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid quarantine directory %s: %w", dir, err)
+	}
+	base, err := filepath.Abs(fs.baseDir)
+	if err != nil {
+		return fmt.Errorf("invalid storage directory %s: %w", fs.baseDir, err)
+	}
+	if abs == base || strings.HasPrefix(abs, base+string(filepath.Separator)) {
+		return fmt.Errorf("quarantine directory %s must be outside the storage directory %s", abs, base)
+	}
+	if err := os.MkdirAll(abs, 0700); err != nil {
+		return fmt.Errorf("failed to create quarantine directory %s: %w", abs, err)
+	}
+	fs.quarantineDir = abs
+	return nil
+}
+
+// QuarantineDir returns the quarantine directory, or "" if malware uploads
+// are deleted.
+func (fs *FileStore) QuarantineDir() string {
+	return fs.quarantineDir
+}
+
+// quarantine moves the upload at path into the quarantine directory and
+// records why. The file is made read-only for the owner.
+func (fs *FileStore) quarantine(path string, record QuarantineRecord) error {
+	dest := filepath.Join(fs.quarantineDir, filepath.Base(path))
+	if err := moveFile(path, dest); err != nil {
+		return err
+	}
+	os.Chmod(dest, 0400)
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest+".json", data, 0600)
+}
+
+// moveFile renames src to dst, copying across filesystems when needed.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
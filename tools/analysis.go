@@ -0,0 +1,685 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+// Package tools provides dedicated analysis tools built on the pandas executor.
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+// runFileScript resolves a single input file, generates a script for its
+// container path, executes it, and formats the result.
+func (t *PandasTools) runFileScript(ctx context.Context, request mcp.CallToolRequest, filePath string, buildScript func(containerPath string) string) *mcp.CallToolResult {
+	return t.runFilesScript(ctx, request, []string{filePath}, func(containerPaths []string) string {
+		return buildScript(containerPaths[0])
+	})
+}
+
+// runFilesScript resolves several input files, generates a script for their
+// container paths (in the same order), executes it, and formats the result.
+func (t *PandasTools) runFilesScript(ctx context.Context, request mcp.CallToolRequest, filePaths []string, buildScript func(containerPaths []string) string) *mcp.CallToolResult {
+	// Resolve upload:// URIs if needed
+	files, err := t.resolveFilePaths(filePaths)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+	if err := t.checkInputSize(request, files); err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+
+	// Build file mapping
+	fileMapping := executor.BuildFileMapping(files)
+	containerPaths := make([]string, len(files))
+	for i, f := range files {
+		containerPaths[i] = fileMapping[f]
+	}
+	script := buildScript(containerPaths)
+
+	// Execute
+	result, err := t.executor.ExecuteScript(ctx, script, files, t.execOptions(request, 0, filePaths...))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err))
+	}
+
+	return executionToolResult(result)
+}
+
+// PivotTableTool returns the pivot_table tool definition.
+func PivotTableTool() mcp.Tool {
+	return mcp.NewTool("pivot_table",
+		mcp.WithDescription("Build a pivot table from a dataset (pandas pivot_table). Prints the table and saves a flattened copy to the execution output directory. Warns when the index/columns have high cardinality and would produce an enormous table."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithArray("index",
+			mcp.Required(),
+			mcp.Description("Column(s) to use as row keys"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("columns",
+			mcp.Description("Column(s) whose values become column headers (optional)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("values",
+			mcp.Description("Column(s) to aggregate (optional, defaults to all numeric columns)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("aggfunc",
+			mcp.Description("Aggregation function (default: mean)"),
+			mcp.Enum("mean", "sum", "count", "min", "max", "median", "std", "nunique", "first", "last"),
+		),
+		mcp.WithString("output_format",
+			mcp.Description("Output format: csv, json, or parquet (default: the server's DEFAULT_OUTPUT_FORMAT, csv unless configured)"),
+			mcp.Enum("csv", "json", "parquet"),
+		),
+	)
+}
+
+// PivotTableHandler handles the pivot_table tool.
+func (t *PandasTools) PivotTableHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	index, err := toStringSlice(request.GetArguments()["index"])
+	if err != nil || len(index) == 0 {
+		return mcp.NewToolResultError("invalid parameter 'index': at least one column is required"), nil
+	}
+
+	var columns, values []string
+	if colsArg := request.GetArguments()["columns"]; colsArg != nil {
+		if columns, err = toStringSlice(colsArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'columns': %v", err)), nil
+		}
+	}
+	if valsArg := request.GetArguments()["values"]; valsArg != nil {
+		if values, err = toStringSlice(valsArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'values': %v", err)), nil
+		}
+	}
+
+	aggfunc := request.GetString("aggfunc", "mean")
+	outputFormat := request.GetString("output_format", t.executor.DefaultOutputFormat())
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.PivotTableScript(containerPath, index, columns, values, aggfunc, outputFormat)
+	}), nil
+}
+
+// CrosstabTool returns the crosstab tool definition.
+func CrosstabTool() mcp.Tool {
+	return mcp.NewTool("crosstab",
+		mcp.WithDescription("Cross-tabulate categorical columns (pd.crosstab): counts of each index value against each column value, optionally normalized, with row/column totals, and an optional chi-square test of independence (statistic, p-value, degrees of freedom). Saves the table to the execution output directory."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithArray("index",
+			mcp.Required(),
+			mcp.Description("Categorical column(s) whose values become rows"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("columns",
+			mcp.Required(),
+			mcp.Description("Categorical column(s) whose values become columns"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("normalize",
+			mcp.Description("Show proportions instead of counts: all (of the grand total), index (within each row), or columns (within each column)"),
+			mcp.Enum("all", "index", "columns"),
+		),
+		mcp.WithBoolean("margins",
+			mcp.Description("Add row and column totals (default: false)"),
+		),
+		mcp.WithBoolean("chi_square",
+			mcp.Description("Run a chi-square test of independence on the raw counts (default: false)"),
+		),
+		mcp.WithString("output_format",
+			mcp.Description("Output format for the saved table: csv, json, or parquet (default: the server's DEFAULT_OUTPUT_FORMAT, csv unless configured)"),
+			mcp.Enum("csv", "json", "parquet"),
+		),
+	)
+}
+
+// CrosstabHandler handles the crosstab tool.
+func (t *PandasTools) CrosstabHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	index, err := toStringSlice(request.GetArguments()["index"])
+	if err != nil || len(index) == 0 {
+		return mcp.NewToolResultError("invalid parameter 'index': at least one column is required"), nil
+	}
+	columns, err := toStringSlice(request.GetArguments()["columns"])
+	if err != nil || len(columns) == 0 {
+		return mcp.NewToolResultError("invalid parameter 'columns': at least one column is required"), nil
+	}
+
+	normalize := request.GetString("normalize", "")
+	margins := request.GetBool("margins", false)
+	chiSquare := request.GetBool("chi_square", false)
+	outputFormat := request.GetString("output_format", t.executor.DefaultOutputFormat())
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.CrosstabScript(containerPath, index, columns, normalize, margins, chiSquare, outputFormat)
+	}), nil
+}
+
+// ColumnCardinalityTool returns the column_cardinality tool definition.
+func ColumnCardinalityTool() mcp.Tool {
+	return mcp.NewTool("column_cardinality",
+		mcp.WithDescription("Report the number of distinct values per column, the ratio of unique values to non-null rows, and flag likely identifier columns (ratio ~1.0), categoricals (few distinct values), and constants. Much cheaper than value_counts across every column; use it to choose group-by keys."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithArray("columns",
+			mcp.Description("Specific columns to report (optional, defaults to all)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+}
+
+// ColumnCardinalityHandler handles the column_cardinality tool.
+func (t *PandasTools) ColumnCardinalityHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	var columns []string
+	if colsArg := request.GetArguments()["columns"]; colsArg != nil {
+		if columns, err = toStringSlice(colsArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'columns': %v", err)), nil
+		}
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.ColumnCardinalityScript(containerPath, columns)
+	}), nil
+}
+
+// InferTypesTool returns the infer_types tool definition.
+func InferTypesTool() mcp.Tool {
+	return mcp.NewTool("infer_types",
+		mcp.WithDescription("Guess the semantic type of each column (datetime, numeric-as-string, currency, percentage, boolean, email, url, identifier, categorical, freetext) by sampling values, with a confidence score and a suggested conversion. Returns JSON including transform_data operations that can be applied directly."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithArray("columns",
+			mcp.Description("Specific columns to inspect (optional, defaults to all)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithNumber("sample_size",
+			mcp.Description("Maximum number of non-null values sampled per column (default: 1000)"),
+		),
+	)
+}
+
+// InferTypesHandler handles the infer_types tool.
+func (t *PandasTools) InferTypesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	var columns []string
+	if colsArg := request.GetArguments()["columns"]; colsArg != nil {
+		if columns, err = toStringSlice(colsArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'columns': %v", err)), nil
+		}
+	}
+
+	sampleSize := int(request.GetFloat("sample_size", 1000))
+	if sampleSize < 1 {
+		sampleSize = 1000
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.InferTypesScript(containerPath, columns, sampleSize)
+	}), nil
+}
+
+// PipelineTool returns the pipeline tool definition.
+func PipelineTool() mcp.Tool {
+	return mcp.NewTool("pipeline",
+		mcp.WithDescription("Run an ordered list of transform and analysis steps against one in-memory DataFrame in a single execution, so the file is read once and intermediate results are reused. Returns the combined results of every step and optionally saves the final frame."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithArray("steps",
+			mcp.Required(),
+			mcp.Description(`Ordered list of steps. Each step is either:
+- a transform_data operation, e.g. {type: "filter", column: "col", operator: ">", value: 10}
+- an analysis: {type: "analyze", analysis_type: "describe|info|corr|corr_sig|value_counts|groupby|histogram|decompose", columns: [...], group_by: "col", bins: 10, ...} taking the analyze_data parameters (all but analysis_type optional)`),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+		mcp.WithString("output_format",
+			mcp.Description("Save the final DataFrame in this format (optional; omit to skip saving)"),
+			mcp.Enum("csv", "json", "parquet"),
+		),
+	)
+}
+
+// PipelineHandler handles the pipeline tool.
+func (t *PandasTools) PipelineHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	steps, err := toOperations(request.GetArguments()["steps"], t.opts.MaxOperations)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'steps': %v", err)), nil
+	}
+	if len(steps) == 0 {
+		return mcp.NewToolResultError("invalid parameter 'steps': at least one step is required"), nil
+	}
+	// Analysis steps get the same output caps as analyze_data
+	for _, step := range steps {
+		if step["type"] == "analyze" {
+			step["max_corr_columns"] = t.opts.MaxCorrColumns
+			step["max_value_counts"] = t.opts.MaxValueCounts
+		}
+	}
+
+	outputFormat := request.GetString("output_format", "")
+
+	extraFiles, err := operationInputs(steps)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'steps': %v", err)), nil
+	}
+
+	return t.runFilesScript(ctx, request, append([]string{filePath}, extraFiles...), func(containerPaths []string) string {
+		bindOperationInputs(steps, containerPaths[1:])
+		return executor.PipelineScript(containerPaths[0], steps, outputFormat)
+	}), nil
+}
+
+// MergeAsofTool returns the merge_asof tool definition.
+func MergeAsofTool() mcp.Tool {
+	return mcp.NewTool("merge_asof",
+		mcp.WithDescription("Join two datasets by nearest key (pd.merge_asof), typically matching events to the closest earlier reference timestamp. Both files are sorted on the key automatically; text keys are parsed as datetimes. Saves the merged result to the execution output directory."),
+		mcp.WithString("left_file",
+			mcp.Required(),
+			mcp.Description("Path to the left data file (every row is kept)"),
+		),
+		mcp.WithString("right_file",
+			mcp.Required(),
+			mcp.Description("Path to the right data file (matched to the nearest key)"),
+		),
+		mcp.WithString("on",
+			mcp.Required(),
+			mcp.Description("Key column present in both files, usually a timestamp"),
+		),
+		mcp.WithArray("by",
+			mcp.Description("Columns that must match exactly before the nearest-key search (optional)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("direction",
+			mcp.Description("Which right row to take: backward (last key <= left key), forward (first key >= left key), or nearest (default: backward)"),
+			mcp.Enum("backward", "forward", "nearest"),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Maximum key distance for a match, e.g. \"5min\" or \"2 days\" for datetimes, or a number for numeric keys (optional)"),
+		),
+		mcp.WithString("output_format",
+			mcp.Description("Output format: csv, json, or parquet (default: the server's DEFAULT_OUTPUT_FORMAT, csv unless configured)"),
+			mcp.Enum("csv", "json", "parquet"),
+		),
+	)
+}
+
+// MergeAsofHandler handles the merge_asof tool.
+func (t *PandasTools) MergeAsofHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	leftFile, err := request.RequireString("left_file")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'left_file': %v", err)), nil
+	}
+	rightFile, err := request.RequireString("right_file")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'right_file': %v", err)), nil
+	}
+	on, err := request.RequireString("on")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'on': %v", err)), nil
+	}
+
+	var by []string
+	if byArg := request.GetArguments()["by"]; byArg != nil {
+		if by, err = toStringSlice(byArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'by': %v", err)), nil
+		}
+	}
+
+	direction := request.GetString("direction", "backward")
+	tolerance := request.GetString("tolerance", "")
+	outputFormat := request.GetString("output_format", t.executor.DefaultOutputFormat())
+
+	return t.runFilesScript(ctx, request, []string{leftFile, rightFile}, func(containerPaths []string) string {
+		return executor.MergeAsofScript(containerPaths[0], containerPaths[1], on, by, direction, tolerance, outputFormat)
+	}), nil
+}
+
+// ValidateSchemaTool returns the validate_schema tool definition.
+func ValidateSchemaTool() mcp.Tool {
+	return mcp.NewTool("validate_schema",
+		mcp.WithDescription("Check a dataset against a schema of expected columns, dtypes and constraints (not null, min/max, allowed values, unique, regex pattern) and report pass/fail per rule with the number of offending rows. The result has a single boolean 'passed' for gating later steps; a failed validation is not a tool error."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithObject("schema",
+			mcp.Required(),
+			mcp.Description(`Schema to validate against:
+{columns: {"<name>": {dtype: "int|float|numeric|string|bool|datetime|category", required: true, nullable: true, min: 0, max: 100, allowed: [...], unique: false, pattern: "regex"}}, strict: false, min_rows: 1}
+Every column rule is optional. required (default true) fails when the column is missing; nullable: false fails on nulls; strict fails on columns not listed; min_rows fails on too few rows.`),
+		),
+	)
+}
+
+// schemaColumnRules are the rules a validate_schema column spec may set.
+var schemaColumnRules = map[string]bool{
+	"dtype": true, "required": true, "nullable": true, "min": true, "max": true,
+	"allowed": true, "unique": true, "pattern": true,
+}
+
+// schemaDtypes are the dtype names validate_schema understands.
+var schemaDtypes = map[string]bool{
+	"int": true, "integer": true, "float": true, "numeric": true, "string": true,
+	"bool": true, "boolean": true, "datetime": true, "category": true,
+}
+
+// toSchema checks the shape of a validate_schema spec so typos are reported
+// before a container is started.
+func toSchema(v interface{}) (map[string]interface{}, error) {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", v)
+	}
+	for key := range schema {
+		switch key {
+		case "columns", "strict", "min_rows":
+		default:
+			return nil, fmt.Errorf("unknown key %q (expected columns, strict, min_rows)", key)
+		}
+	}
+	columns, ok := schema["columns"].(map[string]interface{})
+	if !ok || len(columns) == 0 {
+		return nil, fmt.Errorf("'columns' must be an object of column name to rules")
+	}
+	for name, spec := range columns {
+		rules, ok := spec.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("column %q: rules must be an object, got %T", name, spec)
+		}
+		for rule, value := range rules {
+			if !schemaColumnRules[rule] {
+				return nil, fmt.Errorf("column %q: unknown rule %q", name, rule)
+			}
+			switch rule {
+			case "dtype":
+				if s, ok := value.(string); !ok || !schemaDtypes[strings.ToLower(s)] {
+					return nil, fmt.Errorf("column %q: unsupported dtype %v", name, value)
+				}
+			case "allowed":
+				if _, ok := value.([]interface{}); !ok {
+					return nil, fmt.Errorf("column %q: 'allowed' must be an array", name)
+				}
+			case "pattern":
+				if _, ok := value.(string); !ok {
+					return nil, fmt.Errorf("column %q: 'pattern' must be a string", name)
+				}
+			case "required", "nullable", "unique":
+				if _, ok := value.(bool); !ok {
+					return nil, fmt.Errorf("column %q: '%s' must be a boolean", name, rule)
+				}
+			}
+		}
+	}
+	if v, ok := schema["min_rows"]; ok {
+		if n, ok := v.(float64); !ok || n < 0 {
+			return nil, fmt.Errorf("'min_rows' must be a non-negative number")
+		}
+	}
+	if v, ok := schema["strict"]; ok {
+		if _, ok := v.(bool); !ok {
+			return nil, fmt.Errorf("'strict' must be a boolean")
+		}
+	}
+	return schema, nil
+}
+
+// ValidateSchemaHandler handles the validate_schema tool.
+func (t *PandasTools) ValidateSchemaHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	schema, err := toSchema(request.GetArguments()["schema"])
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'schema': %v", err)), nil
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.ValidateSchemaScript(containerPath, schema)
+	}), nil
+}
+
+// PeekTool returns the peek tool definition.
+func PeekTool() mcp.Tool {
+	return mcp.NewTool("peek",
+		mcp.WithDescription("Take a fast first look at a (possibly very large) data file: reads only the first N rows to return column names, inferred dtypes, a preview, and the file size on disk, plus the exact or estimated row count where cheap to obtain. Use before read_dataframe when a full load may be slow."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithNumber("rows",
+			mcp.Description("Number of rows to read (default: 20)"),
+		),
+	)
+}
+
+// PeekHandler handles the peek tool.
+func (t *PandasTools) PeekHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	rows := int(request.GetFloat("rows", 20))
+	if rows < 1 {
+		rows = 20
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.PeekScript(containerPath, rows)
+	}), nil
+}
+
+// GetSchemaTool returns the get_schema tool definition.
+func GetSchemaTool() mcp.Tool {
+	return mcp.NewTool("get_schema",
+		mcp.WithDescription("List a data file's column names and dtypes without a preview or memory calculation, plus the row count when it is cheap to get (Parquet metadata, or files small enough to be read whole). Parquet dtypes come from the file metadata; text formats are sampled. The quickest answer to \"what columns does this file have\"."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithNumber("sample_rows",
+			mcp.Description("Rows read to infer dtypes for CSV, JSON Lines, Excel, Stata and SAS files (default: 1000)"),
+		),
+	)
+}
+
+// GetSchemaHandler handles the get_schema tool.
+func (t *PandasTools) GetSchemaHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+
+	sampleRows := int(request.GetFloat("sample_rows", 1000))
+	if sampleRows < 1 {
+		sampleRows = 1000
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.GetSchemaScript(containerPath, sampleRows)
+	}), nil
+}
+
+// EstimateImportanceTool returns the estimate_importance tool definition.
+func EstimateImportanceTool() mcp.Tool {
+	return mcp.NewTool("estimate_importance",
+		mcp.WithDescription("Train a quick random forest (scikit-learn) to predict a target column from the other columns and return the features ranked by importance, with a holdout score to show how predictive they are. Text and categorical features are category-encoded; identifier-like columns are skipped. For triage, not model building."),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the data file"),
+		),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("Column to predict"),
+		),
+		mcp.WithArray("features",
+			mcp.Description("Columns to use as features (optional, defaults to all other columns)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("task",
+			mcp.Description("Model type (default: auto, which picks classification for text, boolean and low-cardinality integer targets and regression otherwise)"),
+			mcp.Enum("auto", "classification", "regression"),
+		),
+		mcp.WithNumber("max_rows",
+			mcp.Description("Maximum rows used, sampled when the file has more (default: 50000)"),
+		),
+	)
+}
+
+// EstimateImportanceHandler handles the estimate_importance tool.
+func (t *PandasTools) EstimateImportanceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Try to acquire a worker slot
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	// Extract arguments
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'file_path': %v", err)), nil
+	}
+	target, err := request.RequireString("target")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'target': %v", err)), nil
+	}
+
+	var features []string
+	if featuresArg := request.GetArguments()["features"]; featuresArg != nil {
+		if features, err = toStringSlice(featuresArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'features': %v", err)), nil
+		}
+	}
+
+	task := request.GetString("task", "auto")
+	switch task {
+	case "auto", "classification", "regression":
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'task': %q (use auto, classification, or regression)", task)), nil
+	}
+
+	maxRows := int(request.GetFloat("max_rows", 50000))
+	if maxRows < 1 {
+		maxRows = 50000
+	}
+
+	return t.runFileScript(ctx, request, filePath, func(containerPath string) string {
+		return executor.EstimateImportanceScript(containerPath, target, features, task, maxRows)
+	}), nil
+}
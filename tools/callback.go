@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package tools
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+const (
+	callbackAttempts = 3
+	callbackTimeout  = 10 * time.Second
+)
+
+// callbackClient posts completion callbacks. Redirects are not followed so a
+// permitted host can't bounce the request to one outside the allowlist.
+var callbackClient = &http.Client{
+	Timeout: callbackTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// validateCallbackURL checks that raw is an http(s) URL whose host is on the
+// configured allowlist. Entries starting with "." match any subdomain.
+func (t *PandasTools) validateCallbackURL(raw string) error {
+	if len(t.opts.CallbackHosts) == 0 {
+		return fmt.Errorf("callbacks are disabled on this server (CALLBACK_ALLOWED_HOSTS is not set)")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http or https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range t.opts.CallbackHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not in CALLBACK_ALLOWED_HOSTS", host)
+}
+
+// sendCallback POSTs an execution summary to callbackURL in the background,
+// retrying with backoff on network errors and 5xx responses. It is called on
+// every terminal path of execID: result is nil when the run failed with
+// execErr before producing one. When a secret is configured the body is
+// signed: X-Signature is "sha256=" + hex HMAC-SHA256 of "<X-Timestamp>.<body>".
+func (t *PandasTools) sendCallback(callbackURL, execID string, result *executor.ExecutionResult, execErr error) {
+	summary := map[string]interface{}{
+		"exec_id": execID,
+		"status":  "success",
+	}
+	if result == nil {
+		summary["status"] = "error"
+		summary["error"] = fmt.Sprintf("execution error: %v", execErr)
+	} else {
+		switch {
+		case result.TimedOut:
+			summary["status"] = "timeout"
+		case result.Error != "" || result.ExitCode != 0:
+			summary["status"] = "error"
+		}
+		summary["exit_code"] = result.ExitCode
+		summary["error"] = result.Error
+		summary["duration_ms"] = result.Duration.Milliseconds()
+		summary["output_files"] = result.OutputFiles
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("Callback for %s not sent: %v", execID, err)
+		return
+	}
+
+	go func() {
+		backoff := time.Second
+		for attempt := 1; attempt <= callbackAttempts; attempt++ {
+			err := t.postCallback(callbackURL, body)
+			if err == nil {
+				return
+			}
+			log.Printf("Callback for %s failed (attempt %d/%d): %v", execID, attempt, callbackAttempts, err)
+			if attempt < callbackAttempts {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+func (t *PandasTools) postCallback(callbackURL string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.opts.CallbackSecret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(t.opts.CallbackSecret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		req.Header.Set("X-Timestamp", ts)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		// Client errors won't succeed on retry
+		log.Printf("Callback to %s rejected: %s", callbackURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+// withReadOptions adds the shared file-reading parameters to a tool definition.
+func withReadOptions(tool mcp.Tool) mcp.Tool {
+	for _, opt := range []mcp.ToolOption{
+		mcp.WithNumber("table_index",
+			mcp.Description("For HTML files, which table on the page to read (0-based, default: 0)"),
+		),
+		mcp.WithObject("dtypes",
+			mcp.Description(`Column -> dtype overrides applied when reading, e.g. {"zip": "str", "count": "Int64"}. Use "str" to keep identifiers with leading zeros intact.`),
+		),
+		mcp.WithArray("parse_dates",
+			mcp.Description("Columns to parse as dates"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("na_values",
+			mcp.Description(`Extra values to read as missing (NaN), e.g. ["N/A", "-", "99999"]`),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("keep_default_na",
+			mcp.Description(`CSV/Excel: also treat pandas' default markers ("NA", "null", empty cells, ...) as missing (default: true). Set false to keep them as text, so only na_values are missing.`),
+		),
+		mcp.WithNumber("skiprows",
+			mcp.Description("CSV/Excel: number of lines to skip at the start of the file, before the header (e.g. report titles)"),
+		),
+		mcp.WithNumber("skipfooter",
+			mcp.Description("CSV/Excel: number of lines to skip at the end of the file (e.g. footer totals)"),
+		),
+		mcp.WithArray("usecols",
+			mcp.Description("Only read these columns, in this order. CSV, Excel, and Parquet readers skip the other columns entirely, saving memory and time on wide files. Columns that don't exist are skipped with a warning."),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithAny("index_col",
+			mcp.Description(`Column to use as the row index: a string is a column name (even if it is all digits, e.g. "2024"), a number a 0-based position among the columns read (e.g. "id" or 0). Saved transform outputs write the index back as a column.`),
+		),
+		mcp.WithBoolean("drop_unnamed_index",
+			mcp.Description(`Drop a leading "Unnamed: 0" column, the index written by df.to_csv(index=True) (default: false; read_dataframe notes such columns)`),
+		),
+		mcp.WithBoolean("allow_empty",
+			mcp.Description("Read a file that has a header but no data rows as an empty frame instead of returning an error (default: false)"),
+		),
+		mcp.WithBoolean("warn_mixed_types",
+			mcp.Description("Warn about columns whose values have mixed types (e.g. numbers and strings), which pandas otherwise reads silently as object columns; the warning names each column and its types (default: the server's WARN_MIXED_TYPES setting)"),
+		),
+		mcp.WithObject("read_options",
+			mcp.Description(`Extra keyword arguments for the pandas reader, e.g. {"sep": ";", "decimal": ",", "skiprows": 2}. Allowed keys: sep, delimiter, header, skiprows, nrows, na_values, keep_default_na, thousands, decimal, encoding, comment, quotechar, skipinitialspace, sheet_name, lines, orient.`),
+		),
+	} {
+		opt(&tool)
+	}
+	return tool
+}
+
+// parseReadOptions extracts the shared file-reading parameters from a request.
+func parseReadOptions(request mcp.CallToolRequest) (executor.ReadOptions, error) {
+	var opts executor.ReadOptions
+
+	opts.TableIndex = int(request.GetFloat("table_index", 0))
+	if opts.TableIndex < 0 {
+		return opts, fmt.Errorf("invalid parameter 'table_index': must be >= 0")
+	}
+
+	if dtypesArg := request.GetArguments()["dtypes"]; dtypesArg != nil {
+		m, ok := dtypesArg.(map[string]interface{})
+		if !ok {
+			return opts, fmt.Errorf("invalid parameter 'dtypes': expected an object mapping column names to dtypes")
+		}
+		opts.Dtypes = make(map[string]string, len(m))
+		for col, v := range m {
+			dtype, ok := v.(string)
+			if !ok || dtype == "" {
+				return opts, fmt.Errorf("invalid parameter 'dtypes': dtype for column %q must be a non-empty string", col)
+			}
+			opts.Dtypes[col] = dtype
+		}
+	}
+
+	if datesArg := request.GetArguments()["parse_dates"]; datesArg != nil {
+		dates, err := toStringSlice(datesArg)
+		if err != nil {
+			return opts, fmt.Errorf("invalid parameter 'parse_dates': %v", err)
+		}
+		opts.ParseDates = dates
+	}
+
+	if naArg := request.GetArguments()["na_values"]; naArg != nil {
+		naValues, err := toNAValues(naArg)
+		if err != nil {
+			return opts, fmt.Errorf("invalid parameter 'na_values': %v", err)
+		}
+		opts.NAValues = naValues
+	}
+	if v, ok := request.GetArguments()["keep_default_na"].(bool); ok {
+		opts.KeepDefaultNA = &v
+	}
+
+	opts.SkipRows = int(request.GetFloat("skiprows", 0))
+	opts.SkipFooter = int(request.GetFloat("skipfooter", 0))
+	if opts.SkipRows < 0 || opts.SkipFooter < 0 {
+		return opts, fmt.Errorf("invalid parameter 'skiprows'/'skipfooter': must be >= 0")
+	}
+
+	if usecolsArg := request.GetArguments()["usecols"]; usecolsArg != nil {
+		usecols, err := toStringSlice(usecolsArg)
+		if err != nil {
+			return opts, fmt.Errorf("invalid parameter 'usecols': %v", err)
+		}
+		opts.Usecols = usecols
+	}
+
+	switch v := request.GetArguments()["index_col"].(type) {
+	case nil:
+	case float64:
+		// Only JSON numbers are positions; strings are always names
+		if v < 0 || v != float64(int(v)) {
+			return opts, fmt.Errorf("invalid parameter 'index_col': position must be a non-negative integer, got %v", v)
+		}
+		opts.IndexCol = int(v)
+	case string:
+		if v != "" {
+			opts.IndexCol = v
+		}
+	default:
+		return opts, fmt.Errorf("invalid parameter 'index_col': expected a column name or position, got %T", v)
+	}
+	opts.DropUnnamedIndex = request.GetBool("drop_unnamed_index", false)
+
+	opts.AllowEmpty = request.GetBool("allow_empty", false)
+	if v, ok := request.GetArguments()["warn_mixed_types"].(bool); ok {
+		opts.WarnMixedTypes = &v
+	}
+
+	if optsArg := request.GetArguments()["read_options"]; optsArg != nil {
+		m, ok := optsArg.(map[string]interface{})
+		if !ok {
+			return opts, fmt.Errorf("invalid parameter 'read_options': expected an object")
+		}
+		var unsupported []string
+		for key := range m {
+			if !executor.AllowedReadOptions[key] {
+				unsupported = append(unsupported, key)
+			}
+		}
+		if len(unsupported) > 0 {
+			sort.Strings(unsupported)
+			return opts, fmt.Errorf("invalid parameter 'read_options': unsupported key(s) %s", strings.Join(unsupported, ", "))
+		}
+		// Neither silently wins over the other
+		for _, key := range topLevelReadOptions {
+			if _, ok := m[key]; ok && request.GetArguments()[key] != nil {
+				return opts, fmt.Errorf("invalid parameter 'read_options': %s is also set as a top-level parameter; set it in only one place", key)
+			}
+		}
+		opts.Options = m
+	}
+
+	return opts, nil
+}
+
+// topLevelReadOptions are the read_options keys that also have a top-level
+// parameter of the same name.
+var topLevelReadOptions = []string{"skiprows", "na_values", "keep_default_na"}
+
+// toNAValues converts na_values to strings. Numbers are accepted too, since
+// sentinels like 99999 are often passed unquoted.
+func toNAValues(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return toStringSlice(v)
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		switch val := item.(type) {
+		case string:
+			values[i] = val
+		case float64:
+			values[i] = strconv.FormatFloat(val, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("item at index %d must be a string or number, got %T", i, item)
+		}
+	}
+	return values, nil
+}
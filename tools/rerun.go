@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolHandler is the signature of the PandasTools tool handlers.
+type toolHandler func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+// rerunHandlers returns the handlers of the tools whose executions can be
+// repeated with rerun, keyed by tool name.
+func (t *PandasTools) rerunHandlers() map[string]toolHandler {
+	return map[string]toolHandler{
+		"run_pandas_script":   t.RunScriptHandler,
+		"read_dataframe":      t.ReadDataFrameHandler,
+		"peek":                t.PeekHandler,
+		"get_schema":          t.GetSchemaHandler,
+		"analyze_data":        t.AnalyzeDataHandler,
+		"transform_data":      t.TransformDataHandler,
+		"query_data":          t.QueryDataHandler,
+		"profile_data":        t.ProfileDataHandler,
+		"pivot_table":         t.PivotTableHandler,
+		"crosstab":            t.CrosstabHandler,
+		"column_cardinality":  t.ColumnCardinalityHandler,
+		"infer_types":         t.InferTypesHandler,
+		"pipeline":            t.PipelineHandler,
+		"merge_asof":          t.MergeAsofHandler,
+		"validate_schema":     t.ValidateSchemaHandler,
+		"estimate_importance": t.EstimateImportanceHandler,
+	}
+}
+
+// RerunTool returns the rerun tool definition.
+func RerunTool() mcp.Tool {
+	return mcp.NewTool("rerun",
+		mcp.WithDescription("Run a previous execution again as a new execution, reusing the arguments it was called with (script, operations, input files, ...) so they don't have to be sent again. Optional overrides replace individual arguments. Requires OUTPUT_DIR; input files must still exist (upload:// files expire with UPLOAD_TTL)."),
+		mcp.WithString("exec_id",
+			mcp.Required(),
+			mcp.Description("The execution ID to run again."),
+		),
+		mcp.WithObject("overrides",
+			mcp.Description("Arguments to change, as an object of argument name to new value (e.g. {\"analysis_type\": \"corr\"} or {\"script\": \"...\"}). A null value removes the argument so the tool's default applies."),
+		),
+	)
+}
+
+// RerunHandler handles the rerun tool.
+func (t *PandasTools) RerunHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	om, err := t.outputManager()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	execID, err := request.RequireString("exec_id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("exec_id is required: %v", err)), nil
+	}
+
+	overrides := map[string]any{}
+	if v, ok := request.GetArguments()["overrides"]; ok && v != nil {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'overrides': expected an object, got %T", v)), nil
+		}
+		overrides = m
+	}
+
+	info, err := om.GetExecution(execID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get execution: %v", err)), nil
+	}
+	handler, ok := t.rerunHandlers()[info.ToolName]
+	if !ok || info.Arguments == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("execution %s can't be re-run: its tool call arguments weren't recorded", execID)), nil
+	}
+
+	args := maps.Clone(info.Arguments)
+	for name, value := range overrides {
+		if value == nil {
+			delete(args, name)
+		} else {
+			args[name] = value
+		}
+	}
+
+	rerun := mcp.CallToolRequest{}
+	rerun.Params.Name = info.ToolName
+	rerun.Params.Arguments = args
+	result, err := handler(ctx, rerun)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	header := fmt.Sprintf("Re-run of execution %s (%s)", execID, info.ToolName)
+	if len(overrides) > 0 {
+		header += "\nOverrides: " + strings.Join(slices.Sorted(maps.Keys(overrides)), ", ")
+	}
+	result.Content = append([]mcp.Content{mcp.NewTextContent(header + "\n")}, result.Content...)
+	return result, nil
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+)
+
+// maxResourceBytes caps output files returned through resources/read; larger
+// files can be read in parts with get_output.
+const maxResourceBytes = 16 << 20
+
+// outputURI returns the resource URI of an execution or one of its files.
+func outputURI(execID, filename string) string {
+	if filename == "" {
+		return "output://" + url.PathEscape(execID)
+	}
+	return "output://" + url.PathEscape(execID) + "/" + url.PathEscape(filename)
+}
+
+// OutputExecutionTemplate returns the resource template for one execution.
+// There is deliberately no resource listing every execution: like
+// list_outputs, reading one requires the exec_id returned by the run.
+func OutputExecutionTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate("output://{exec_id}", "Execution",
+		mcp.WithTemplateDescription("Metadata and output:// file URIs of one execution"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+}
+
+// OutputFileTemplate returns the resource template for one output file.
+func OutputFileTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate("output://{exec_id}/{filename}", "Execution output file",
+		mcp.WithTemplateDescription("Contents of a file saved by an execution; text files are returned as text, others base64-encoded"),
+	)
+}
+
+// outputMIMEType returns the MIME type of an output file from its extension,
+// falling back to text/plain or application/octet-stream.
+func outputMIMEType(filename string, text bool) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(filename)); mimeType != "" {
+		return mimeType
+	}
+	if text {
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// binaryContent returns a binary output file as an MCP content block: image
+// and audio content for those types, an embedded blob resource otherwise.
+func binaryContent(execID, filename string, data []byte) mcp.Content {
+	mimeType := outputMIMEType(filename, false)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return mcp.NewImageContent(encoded, mimeType)
+	case strings.HasPrefix(mimeType, "audio/"):
+		return mcp.NewAudioContent(encoded, mimeType)
+	}
+	return mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+		URI:      outputURI(execID, filename),
+		MIMEType: mimeType,
+		Blob:     encoded,
+	})
+}
+
+// outputResourceFile describes one file in an execution resource.
+type outputResourceFile struct {
+	Name   string `json:"name"`
+	URI    string `json:"uri"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// outputResourceExecution is the JSON form of an execution resource.
+type outputResourceExecution struct {
+	ExecutionID string               `json:"execution_id"`
+	URI         string               `json:"uri"`
+	ToolName    string               `json:"tool_name,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	ExpiresAt   time.Time            `json:"expires_at"`
+	Files       []outputResourceFile `json:"files"`
+}
+
+func newOutputResourceExecution(info executor.ExecutionInfo) outputResourceExecution {
+	files := make([]outputResourceFile, 0, len(info.Files))
+	for _, f := range info.Files {
+		files = append(files, outputResourceFile{
+			Name:   f,
+			URI:    outputURI(info.ExecutionID, f),
+			SHA256: info.Checksums[f],
+		})
+	}
+	return outputResourceExecution{
+		ExecutionID: info.ExecutionID,
+		URI:         outputURI(info.ExecutionID, ""),
+		ToolName:    info.ToolName,
+		CreatedAt:   info.CreatedAt,
+		ExpiresAt:   info.ExpiresAt,
+		Files:       files,
+	}
+}
+
+// resourceArg returns a URI template variable from a resource read request.
+func resourceArg(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// outputManager returns the executor's output manager, or an error when
+// output persistence is off.
+func (t *PandasTools) outputManager() (*executor.OutputManager, error) {
+	om := t.executor.GetOutputManager()
+	if om == nil {
+		return nil, fmt.Errorf("output management not configured; set OUTPUT_DIR to enable output persistence")
+	}
+	return om, nil
+}
+
+// OutputExecutionResourceHandler serves output://{exec_id}.
+func (t *PandasTools) OutputExecutionResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	om, err := t.outputManager()
+	if err != nil {
+		return nil, err
+	}
+	info, err := om.GetExecution(resourceArg(request, "exec_id"))
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(newOutputResourceExecution(*info), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}, nil
+}
+
+// OutputFileResourceHandler serves output://{exec_id}/{filename}.
+func (t *PandasTools) OutputFileResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	om, err := t.outputManager()
+	if err != nil {
+		return nil, err
+	}
+	execID := resourceArg(request, "exec_id")
+	filename := resourceArg(request, "filename")
+	info, err := om.GetExecution(execID)
+	if err != nil {
+		return nil, err
+	}
+	if st, err := os.Stat(filepath.Join(info.OutputPath, filepath.Base(filename))); err == nil && st.Size() > maxResourceBytes {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d byte resource limit; use get_output with head or tail", filename, st.Size(), maxResourceBytes)
+	}
+
+	data, err := om.GetFile(execID, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.isTextFile(filepath.Join(info.OutputPath, filepath.Base(filename))) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: outputMIMEType(filename, true),
+			Text:     string(data),
+		}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: outputMIMEType(filename, false),
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}}, nil
+}
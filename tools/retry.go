@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sagacient/cute-pandas-mcp-server/executor"
+	"github.com/sagacient/cute-pandas-mcp-server/workerpool"
+)
+
+type retryAfterKey struct{}
+
+// RetryAfterHolder carries a retry delay suggested by a tool handler back to
+// the transport, which can surface it as an HTTP Retry-After header.
+type RetryAfterHolder struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+// WithRetryAfterHolder returns a context that tool handlers can record a
+// retry delay into, along with the holder to read it back from.
+func WithRetryAfterHolder(ctx context.Context) (context.Context, *RetryAfterHolder) {
+	h := &RetryAfterHolder{}
+	return context.WithValue(ctx, retryAfterKey{}, h), h
+}
+
+// Delay returns the recorded retry delay, or zero if none was set.
+func (h *RetryAfterHolder) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+// setRetryAfter records d in the context's holder, if there is one.
+func setRetryAfter(ctx context.Context, d time.Duration) {
+	if h, ok := ctx.Value(retryAfterKey{}).(*RetryAfterHolder); ok {
+		h.mu.Lock()
+		h.delay = d
+		h.mu.Unlock()
+	}
+}
+
+// clientID identifies the caller for per-client worker accounting: a hash of
+// the Authorization header (API key), else the MCP session ID. Self-reported
+// headers like X-Client-ID are ignored so a client can't pick a fresh identity
+// to escape MAX_WORKERS_PER_CLIENT.
+func clientID(ctx context.Context, request mcp.CallToolRequest) string {
+	if request.Header != nil {
+		if auth := request.Header.Get("Authorization"); auth != "" {
+			sum := sha256.Sum256([]byte(auth))
+			return "key-" + hex.EncodeToString(sum[:8])
+		}
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// imageRetryAfter is suggested while an image is still being built or pulled.
+const imageRetryAfter = 30 * time.Second
+
+// checkReady returns an error result if the image the tool call would run
+// in (its "image" argument, or the default) is not ready. The result's
+// structured content carries error "image_not_ready", the image name and
+// its state ("building" or "failed") so clients can branch on it.
+func (t *PandasTools) checkReady(ctx context.Context, request mcp.CallToolRequest) *mcp.CallToolResult {
+	err := t.executor.CheckImageReady(request.GetString("image", ""))
+	if err == nil {
+		return nil
+	}
+	var notReady *executor.ErrImageNotReady
+	if !errors.As(err, &notReady) {
+		return nil // Unknown image names are reported by the handler's parameter validation
+	}
+
+	result := mcp.NewToolResultError(err.Error())
+	content := map[string]any{
+		"error": "image_not_ready",
+		"image": notReady.Image,
+		"state": notReady.State,
+	}
+	if notReady.State == executor.ImageStateBuilding {
+		seconds := int(imageRetryAfter.Seconds())
+		content["retry_after_seconds"] = seconds
+		setRetryAfter(ctx, imageRetryAfter)
+	}
+	result.StructuredContent = content
+	return result
+}
+
+// limitKeys returns the Limiter keys a tool call counts against: the tool
+// name and, for analyze_data, "analyze_data:<analysis_type>".
+func limitKeys(request mcp.CallToolRequest) []string {
+	keys := []string{request.Params.Name}
+	if kind := request.GetString("analysis_type", ""); kind != "" {
+		keys = append([]string{request.Params.Name + ":" + kind}, keys...)
+	}
+	return keys
+}
+
+// acquireLimits takes a Limiter slot for every limited key of the call, in a
+// fixed order, and returns the function that releases them.
+func (t *PandasTools) acquireLimits(ctx context.Context, request mcp.CallToolRequest, fastFail bool) (func(), error) {
+	var held []string
+	release := func() {
+		for _, key := range held {
+			t.limiter.Release(key)
+		}
+	}
+	if t.limiter == nil {
+		return release, nil
+	}
+	for _, key := range limitKeys(request) {
+		if !t.limiter.Limited(key) {
+			continue
+		}
+		var err error
+		if fastFail {
+			err = t.limiter.TryAcquire(key)
+		} else {
+			err = t.limiter.Acquire(ctx, key)
+		}
+		if err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, key)
+	}
+	return release, nil
+}
+
+// acquireWorker acquires a worker slot for a tool call, waiting up to the
+// pool's acquire timeout in total unless the tool is configured to fail fast. The
+// selected image must be ready first (see checkReady), and per-tool or
+// per-analysis-type limits (see SetLimiter) are taken before the pool slot.
+// On success it returns the function that releases the slots. On failure it
+// returns an error result; when the pool or a limit is busy the result
+// carries a suggested retry delay, which is also recorded for the HTTP
+// transport.
+func (t *PandasTools) acquireWorker(ctx context.Context, request mcp.CallToolRequest) (func(), *mcp.CallToolResult) {
+	if result := t.checkReady(ctx, request); result != nil {
+		return nil, result
+	}
+
+	client := clientID(ctx, request)
+	fastFail := t.opts.AcquireMode == "reject" || slices.Contains(t.opts.FastFailTools, request.Params.Name)
+
+	// The limits and the pool slot share one acquire timeout rather than
+	// each waiting for the whole of it
+	acquireCtx, cancel := context.WithTimeout(ctx, t.pool.AcquireTimeout())
+	defer cancel()
+
+	releaseLimits, err := t.acquireLimits(acquireCtx, request, fastFail)
+	if err == nil {
+		if fastFail {
+			if !t.pool.TryAcquireFor(client) {
+				err = workerpool.ErrPoolExhausted
+			}
+		} else {
+			err = t.pool.AcquireFor(acquireCtx, client)
+		}
+		if err == nil {
+			return func() {
+				t.pool.ReleaseFor(client)
+				releaseLimits()
+			}, nil
+		}
+		releaseLimits()
+	}
+	if !errors.Is(err, workerpool.ErrPoolExhausted) && !errors.Is(err, workerpool.ErrQueueFull) && !errors.Is(err, workerpool.ErrClientLimit) && !errors.Is(err, workerpool.ErrKeyLimit) {
+		return nil, mcp.NewToolResultError(err.Error())
+	}
+
+	delay := t.pool.RetryAfter()
+	setRetryAfter(ctx, delay)
+
+	seconds := int(math.Ceil(delay.Seconds()))
+	result := mcp.NewToolResultError(fmt.Sprintf("%v (suggested retry after %ds)", err, seconds))
+	result.StructuredContent = map[string]any{"retry_after_seconds": seconds}
+	return nil, result
+}
@@ -7,11 +7,22 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/sagacient/cute-pandas-mcp-server/executor"
@@ -19,18 +30,68 @@ import (
 	"github.com/sagacient/cute-pandas-mcp-server/workerpool"
 )
 
+// Options holds tool behavior settings.
+type Options struct {
+	PreviewRows int // Default read_dataframe preview rows
+	PreviewCols int // Maximum columns shown in previews
+
+	// MaxOperations caps transform_data operations and pipeline steps per
+	// request (0 = unlimited).
+	MaxOperations int
+
+	// MaxScriptBytes and MaxInputFiles bound run_pandas_script's script
+	// and files arguments (0 = unlimited).
+	MaxScriptBytes int
+	MaxInputFiles  int
+
+	// MaxCorrColumns and MaxValueCounts cap analyze_data corr columns and
+	// value_counts values per column (0 = script defaults).
+	MaxCorrColumns int
+	MaxValueCounts int
+
+	// MaxReturnRows is the largest transform_data result included in
+	// the response with return_data.
+	MaxReturnRows int
+
+	// TextExtensions adds output file extensions get_output returns as text
+	// (".tsv"), or with a leading "-" makes a default one binary ("-.html").
+	TextExtensions []string
+
+	// InputSizeRatio rejects pandas tools' inputs larger than this multiple
+	// of the container memory limit (0 = no check).
+	InputSizeRatio float64
+
+	// FastFailTools names tools that use TryAcquire and fail immediately
+	// when all workers are busy, instead of waiting for a slot.
+	FastFailTools []string
+
+	// AcquireMode is the server's ACQUIRE_MODE; "reject" makes every tool
+	// fail fast like FastFailTools. "queue" is enforced by the pool.
+	AcquireMode string
+
+	// CallbackHosts allowlists hosts for run_pandas_script callback_url
+	// (empty disables callbacks); CallbackSecret signs callback payloads.
+	CallbackHosts  []string
+	CallbackSecret string
+}
+
 // PandasTools holds the tools and their dependencies.
 type PandasTools struct {
 	pool      *workerpool.Pool
 	executor  *executor.DockerExecutor
-	fileStore *storage.FileStore // Optional, for HTTP mode upload:// resolution
+	fileStore *storage.FileStore  // Optional, for HTTP mode upload:// resolution
+	limiter   *workerpool.Limiter // Optional per-tool/analysis-type concurrency limits
+	opts      Options
+	textExts  map[string]bool // Output extensions returned as text (true) or binary (false)
 }
 
 // NewPandasTools creates a new PandasTools instance.
-func NewPandasTools(pool *workerpool.Pool, exec *executor.DockerExecutor) *PandasTools {
+func NewPandasTools(pool *workerpool.Pool, exec *executor.DockerExecutor, opts Options) *PandasTools {
 	return &PandasTools{
 		pool:     pool,
 		executor: exec,
+		opts:     opts,
+		textExts: textExtensions(opts.TextExtensions),
 	}
 }
 
@@ -40,8 +101,23 @@ func (t *PandasTools) SetFileStore(fs *storage.FileStore) {
 	t.fileStore = fs
 }
 
-// resolveFilePath resolves a file path, handling upload:// URIs.
+// SetLimiter sets per-tool concurrency limits, keyed by tool name (e.g.
+// "profile_data") or "analyze_data:<analysis_type>" (e.g. "analyze_data:corr").
+// They apply on top of the worker pool.
+func (t *PandasTools) SetLimiter(l *workerpool.Limiter) {
+	t.limiter = l
+}
+
+// resolveFilePath resolves a file path, handling upload:// URIs and local
+// file:// URIs (percent-encoded, e.g. file:///data/my%20file.csv).
 func (t *PandasTools) resolveFilePath(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "file://"); ok {
+		host, p, _ := strings.Cut(rest, "/")
+		if host != "" && host != "localhost" {
+			return "", fmt.Errorf("file:// URIs must refer to this host, got %q", host)
+		}
+		return url.PathUnescape("/" + p)
+	}
 	if !strings.HasPrefix(path, "upload://") {
 		return path, nil
 	}
@@ -66,13 +142,70 @@ func (t *PandasTools) resolveFilePaths(paths []string) ([]string, error) {
 	return resolved, nil
 }
 
+// wholeFileTools load their inputs entirely into pandas, so their inputs are
+// checked against the container memory limit before a container is started.
+// Tools built on DuckDB, peek, and run_pandas_script can stream large files.
+var wholeFileTools = map[string]bool{
+	"read_dataframe":      true,
+	"analyze_data":        true,
+	"transform_data":      true,
+	"pivot_table":         true,
+	"crosstab":            true,
+	"column_cardinality":  true,
+	"infer_types":         true,
+	"pipeline":            true,
+	"merge_asof":          true,
+	"validate_schema":     true,
+	"estimate_importance": true,
+}
+
+// checkInputSize rejects inputs totalling more than InputSizeRatio times the
+// memory limit of the container the call runs in (its "image" profile, or the
+// default), which would otherwise be OOM-killed mid-run.
+func (t *PandasTools) checkInputSize(request mcp.CallToolRequest, files []string) error {
+	ratio := t.opts.InputSizeRatio
+	if ratio <= 0 || !wholeFileTools[request.Params.Name] {
+		return nil
+	}
+	// A chunked transform_data never holds the whole input
+	if request.Params.Name == "transform_data" && request.GetFloat("chunksize", 0) > 0 {
+		return nil
+	}
+	limit := t.executor.MemoryLimit(request.GetString("image", ""))
+	if limit <= 0 {
+		return nil
+	}
+	var total int64
+	for _, f := range files {
+		if st, err := os.Stat(f); err == nil {
+			total += st.Size()
+		}
+	}
+	if float64(total) <= ratio*float64(limit) {
+		return nil
+	}
+	return fmt.Errorf("input too large for the container memory limit: %.1f MB of input files, limit %d MB (inputs over %g times the limit are rejected, set by INPUT_SIZE_RATIO). Process the data in chunks (transform_data's chunksize for CSV inputs) or with DuckDB via run_pandas_script or query_data, or raise MAX_MEMORY_MB (or the image profile's memory)",
+		float64(total)/(1024*1024), limit/(1024*1024), ratio)
+}
+
+// execOptions describes a tool call for execution provenance. inputs are the
+// file references as the client passed them (before upload:// resolution).
+func (t *PandasTools) execOptions(request mcp.CallToolRequest, timeout time.Duration, inputs ...string) executor.ExecOptions {
+	return executor.ExecOptions{
+		Timeout:   timeout,
+		ToolName:  request.Params.Name,
+		Inputs:    inputs,
+		Arguments: request.GetArguments(),
+	}
+}
+
 // RunScriptTool returns the run_pandas_script tool definition.
 func RunScriptTool() mcp.Tool {
 	return mcp.NewTool("run_pandas_script",
 		mcp.WithDescription("Execute Python scripts for data analysis, transformation, and visualization. Available engines: duckdb (use duckdb.sql() for SQL queries, joins, aggregations on large data - data stays on disk), polars (import polars as pl for fast DataFrame ops), pandas (sklearn/matplotlib compatibility). Also available: matplotlib, seaborn, scipy, scikit-learn, statsmodels, xgboost, spacy, nltk, geopandas, reportlab, python-pptx, python-docx, Pillow, opencv, and more. For pure SQL queries prefer query_data tool. For dataset profiling prefer profile_data tool. Use save_output() to persist results."),
 		mcp.WithString("script",
 			mcp.Required(),
-			mcp.Description("Python code to execute. Helper functions: resolve_path(path) to access mounted files, save_output(obj, filename) to save data tables (csv/json/xlsx), charts (png/pdf/svg), PDFs, BytesIO objects, or text/JSON. save_base64(base64_str, filename) to save base64-encoded data. Format is auto-detected from filename extension. Examples: save_output(df, 'data.csv'), save_output(plt, 'chart.png'), save_output(bytesio_obj, 'report.pdf')."),
+			mcp.Description("Python code to execute. Helper functions: resolve_path(path) to access mounted files, save_output(obj, filename, compression=None) to save data tables (csv/json/xlsx; compression='gzip' etc. for DataFrames), charts (png/pdf/svg), PDFs, BytesIO objects, or text/JSON. save_base64(base64_str, filename) to save base64-encoded data. save_figure(fig_or_None, filename) to save a matplotlib figure (None = current figure; the non-interactive Agg backend is preselected). list_inputs() to enumerate mounted files (original path, container path, size). emit_result(obj) to return a machine-readable JSON result separately from printed output. If the server sets DATA_DIR, shared reference files are readable (read-only) under SHARED_DIR ('/shared'). Format is auto-detected from filename extension. Examples: save_output(df, 'data.csv'), save_output(plt, 'chart.png'), save_output(bytesio_obj, 'report.pdf')."),
 		),
 		mcp.WithArray("files",
 			mcp.Required(),
@@ -80,7 +213,33 @@ func RunScriptTool() mcp.Tool {
 			mcp.Items(map[string]interface{}{"type": "string"}),
 		),
 		mcp.WithNumber("timeout",
-			mcp.Description("Maximum execution time in seconds (default: 60)"),
+			mcp.Description("Maximum execution time in seconds (default: the server's EXECUTION_TIMEOUT, or the selected image's profile timeout, which also caps this value)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Print the mounted inputs (original path -> container path) before the script runs (default: false)"),
+		),
+		mcp.WithString("image",
+			mcp.Description("Name of a configured image to run in (default: \"default\"). server_status lists the available images."),
+		),
+		mcp.WithString("callback_url",
+			mcp.Description("Run in the background: the call returns the exec_id immediately, and a JSON completion summary (exec_id, status, exit_code, output_files) is POSTed to this URL when the run finishes. The host must be allowlisted by the server."),
+		),
+		mcp.WithArray("network_hosts",
+			mcp.Description("Hosts the script needs to reach (e.g. [\"api.internal\"]). Scripts run without network by default; when every host is allowed by one of the server's NETWORK_POLICIES, the container is attached to that policy's restricted network, and otherwise the call is rejected. server_status lists the policies."),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("args",
+			mcp.Description("Command-line arguments for the script, available as sys.argv[1:] (e.g. [\"--year\", \"2024\"] for argparse), so one script can be reused with different parameters"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("mount_by_name",
+			mcp.Description("Mount each file at /data/{filename} instead of /data/input_{i}/{filename}, so scripts can open inputs by name (default: false). Files whose name is shared with another input keep the indexed path; resolve_path() works either way."),
+		),
+		mcp.WithBoolean("extract_archives",
+			mcp.Description("Unpack .zip, .tar, .tar.gz and .tgz inputs before the script runs (default: false). Members resolve with resolve_path('<archive path>/<member>') or by file name, and list_inputs() lists them. The total extracted size is limited by the server's MAX_EXTRACT_MB."),
+		),
+		mcp.WithString("stdin",
+			mcp.Description("Data piped to the script's standard input, e.g. CSV text read with pd.read_csv(sys.stdin). For small inline data; larger data belongs in files. Scripts that don't read stdin are unaffected."),
 		),
 	)
 }
@@ -88,22 +247,35 @@ func RunScriptTool() mcp.Tool {
 // RunScriptHandler handles the run_pandas_script tool.
 func (t *PandasTools) RunScriptHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	// A run with callback_url keeps the slot until it finishes in the background
+	background := false
+	defer func() {
+		if !background {
+			release()
+		}
+	}()
 
 	// Extract arguments
 	script, err := request.RequireString("script")
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'script': %v", err)), nil
 	}
+	if max := t.opts.MaxScriptBytes; max > 0 && len(script) > max {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'script': %d bytes exceeds the %d byte limit (MAX_SCRIPT_BYTES); move data into input files instead of embedding it", len(script), max)), nil
+	}
 
 	filesArg := request.GetArguments()["files"]
 	files, err := toStringSlice(filesArg)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'files': %v", err)), nil
 	}
+	if max := t.opts.MaxInputFiles; max > 0 && len(files) > max {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'files': %d files exceeds the limit of %d (MAX_INPUT_FILES)", len(files), max)), nil
+	}
 
 	// Resolve upload:// URIs to actual paths
 	resolvedFiles, err := t.resolveFilePaths(files)
@@ -111,50 +283,132 @@ func (t *PandasTools) RunScriptHandler(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	timeout := time.Duration(request.GetFloat("timeout", 60)) * time.Second
+	timeout := time.Duration(request.GetFloat("timeout", 0)) * time.Second
+	debug := request.GetBool("debug", false)
+
+	image := request.GetString("image", "")
+	if !t.executor.HasImage(image) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'image': unknown image %q (available: %s)", image, strings.Join(t.executor.ImageNames(), ", "))), nil
+	}
+
+	callbackURL := request.GetString("callback_url", "")
+	if callbackURL != "" {
+		if err := t.validateCallbackURL(callbackURL); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'callback_url': %v", err)), nil
+		}
+	}
+
+	var networkPolicy string
+	if hostsArg := request.GetArguments()["network_hosts"]; hostsArg != nil {
+		hosts, err := toStringSlice(hostsArg)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'network_hosts': %v", err)), nil
+		}
+		if len(hosts) > 0 {
+			if networkPolicy, err = t.executor.NetworkPolicyFor(hosts); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'network_hosts': %v", err)), nil
+			}
+		}
+	}
+
+	var args []string
+	if argsArg := request.GetArguments()["args"]; argsArg != nil {
+		if args, err = toStringSlice(argsArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'args': %v", err)), nil
+		}
+	}
+
+	stdin := request.GetString("stdin", "")
+	if max := t.opts.MaxScriptBytes; max > 0 && len(stdin) > max {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'stdin': %d bytes exceeds the %d byte limit (MAX_SCRIPT_BYTES); pass larger data as an input file", len(stdin), max)), nil
+	}
 
 	// Build file mapping using original paths as keys for user reference
+	mountByName := request.GetBool("mount_by_name", false)
 	fileMapping := make(map[string]string)
-	for i, originalPath := range files {
-		containerPath := fmt.Sprintf("/data/input_%d/%s", i, getBaseName(resolvedFiles[i]))
-		fileMapping[originalPath] = containerPath
+	for i, containerPath := range executor.InputMountPaths(resolvedFiles, mountByName) {
+		fileMapping[files[i]] = containerPath
+	}
+	extractArchives := request.GetBool("extract_archives", false)
+	archives := make(map[string]string)
+	if extractArchives {
+		for i, f := range resolvedFiles {
+			if executor.IsArchive(f) {
+				archives[files[i]] = executor.ArchiveMountPath(i)
+			}
+		}
 	}
 
 	// Wrap the script with helpers (includes chart theme if configured)
-	wrappedScript := executor.WrapScript(script, fileMapping, t.executor.ChartThemeCode())
+	wrappedScript := executor.WrapScript(script, fileMapping, archives, t.executor.ChartThemeCode(), debug, t.executor.DefaultOutputFormat())
 
 	// Execute with resolved paths
-	result, err := t.executor.ExecuteScript(ctx, wrappedScript, resolvedFiles, timeout)
+	opts := t.execOptions(request, timeout, files...)
+	opts.Image = image
+	opts.NetworkPolicy = networkPolicy
+	opts.Args = args
+	opts.Stdin = stdin
+	opts.MountByName = mountByName
+	opts.ExtractArchives = extractArchives
+	if callbackURL != "" {
+		// Return the exec ID at once and report the result to the callback.
+		// The run outlives the request, so it only ends at the execution
+		// timeout
+		opts.ExecutionID = executor.GenerateExecutionID()
+		runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.executor.ExecutionTimeout(image, timeout))
+		background = true
+		go func() {
+			defer release()
+			defer cancel()
+			result, err := t.executor.ExecuteScript(runCtx, wrappedScript, resolvedFiles, opts)
+			t.sendCallback(callbackURL, opts.ExecutionID, result, err)
+		}()
+		return mcp.NewToolResultText(fmt.Sprintf("Execution %s started; its result will be POSTed to %s when it finishes. Saved outputs are available as output://%s once it completes.", opts.ExecutionID, callbackURL, opts.ExecutionID)), nil
+	}
+	result, err := t.executor.ExecuteScript(ctx, wrappedScript, resolvedFiles, opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
 	}
 
 	// Format output
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	return executionToolResult(result), nil
 }
 
 // ReadDataFrameTool returns the read_dataframe tool definition.
 func ReadDataFrameTool() mcp.Tool {
-	return mcp.NewTool("read_dataframe",
+	return withReadOptions(mcp.NewTool("read_dataframe",
 		mcp.WithDescription("Read a data file and return summary information including shape, columns, data types, memory usage, and a preview of the data. For comprehensive profiling (statistics, correlations, outliers), use profile_data instead. For SQL queries, use query_data."),
 		mcp.WithString("file_path",
 			mcp.Required(),
-			mcp.Description("Path to the data file (CSV, Excel, JSON, or Parquet)"),
+			mcp.Description("Path to the data file (CSV, Excel, JSON, Parquet, Stata .dta, SAS .sas7bdat/.xpt, SPSS .sav, or HTML tables)"),
 		),
 		mcp.WithNumber("preview_rows",
-			mcp.Description("Number of rows to preview (default: 5)"),
+			mcp.Description("Number of rows to preview (default: server setting, usually 5)"),
 		),
-	)
+		mcp.WithNumber("preview_cols",
+			mcp.Description("Maximum number of columns to show in the preview (default: server setting, usually 20); remaining columns are summarized"),
+		),
+		mcp.WithBoolean("normalize",
+			mcp.Description("For JSON files, flatten nested objects into columns with pd.json_normalize (changes the resulting schema, e.g. 'address.city')"),
+		),
+		mcp.WithArray("record_path",
+			mcp.Description("With normalize: path to the list of records to expand, e.g. [\"orders\", \"items\"]"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("meta",
+			mcp.Description("With normalize and record_path: parent fields to repeat on each record. Each entry is a field name or a path array"),
+		),
+	))
 }
 
 // ReadDataFrameHandler handles the read_dataframe tool.
 func (t *PandasTools) ReadDataFrameHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	defer release()
 
 	// Extract arguments
 	filePath, err := request.RequireString("file_path")
@@ -168,33 +422,62 @@ func (t *PandasTools) ReadDataFrameHandler(ctx context.Context, request mcp.Call
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	previewRows := int(request.GetFloat("preview_rows", 5))
+	previewRows := int(request.GetFloat("preview_rows", float64(t.opts.PreviewRows)))
 	if previewRows < 1 {
-		previewRows = 5
+		previewRows = t.opts.PreviewRows
+	}
+
+	previewCols := int(request.GetFloat("preview_cols", float64(t.opts.PreviewCols)))
+	if previewCols < 1 {
+		previewCols = t.opts.PreviewCols
+	}
+
+	readOpts, err := parseReadOptions(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	readOpts.Normalize = request.GetBool("normalize", false)
+	if pathArg := request.GetArguments()["record_path"]; pathArg != nil {
+		if readOpts.RecordPath, err = toStringSlice(pathArg); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'record_path': %v", err)), nil
+		}
+	}
+	if metaArg := request.GetArguments()["meta"]; metaArg != nil {
+		meta, ok := metaArg.([]interface{})
+		if !ok {
+			return mcp.NewToolResultError("invalid parameter 'meta': expected an array"), nil
+		}
+		readOpts.Meta = meta
+	}
+	if !readOpts.Normalize && (len(readOpts.RecordPath) > 0 || len(readOpts.Meta) > 0) {
+		return mcp.NewToolResultError("parameters 'record_path' and 'meta' require normalize: true"), nil
 	}
 
 	// Build file mapping
 	files := []string{resolvedPath}
+	if err := t.checkInputSize(request, files); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	fileMapping := executor.BuildFileMapping(files)
 	containerPath := fileMapping[resolvedPath]
 
 	// Generate script
-	script := executor.ReadDataFrameScript(containerPath, previewRows)
+	script := executor.ReadDataFrameScript(containerPath, previewRows, previewCols, readOpts)
 
 	// Execute
-	result, err := t.executor.ExecuteScript(ctx, script, files, 0)
+	result, err := t.executor.ExecuteScript(ctx, script, files, t.execOptions(request, 0, filePath))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
 	}
 
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	return executionToolResult(result), nil
 }
 
 // AnalyzeDataTool returns the analyze_data tool definition.
 func AnalyzeDataTool() mcp.Tool {
-	return mcp.NewTool("analyze_data",
-		mcp.WithDescription("Perform statistical analysis on a dataset. Supports describe, info, correlation, value counts, and groupby operations. For large datasets or SQL-style analysis, consider query_data. For full profiling, use profile_data."),
+	return withReadOptions(mcp.NewTool("analyze_data",
+		mcp.WithDescription("Perform statistical analysis on a dataset. Supports describe, info, correlation (optionally with p-values), value counts, groupby, text histogram, rolling window, and seasonal decomposition operations. For large datasets or SQL-style analysis, consider query_data. For full profiling, use profile_data."),
 		mcp.WithString("file_path",
 			mcp.Required(),
 			mcp.Description("Path to the data file"),
@@ -202,7 +485,7 @@ func AnalyzeDataTool() mcp.Tool {
 		mcp.WithString("analysis_type",
 			mcp.Required(),
 			mcp.Description("Type of analysis to perform"),
-			mcp.Enum("describe", "info", "corr", "value_counts", "groupby"),
+			mcp.Enum("describe", "info", "corr", "corr_sig", "value_counts", "groupby", "histogram", "decompose", "rolling"),
 		),
 		mcp.WithArray("columns",
 			mcp.Description("Specific columns to analyze (optional, defaults to all)"),
@@ -211,16 +494,50 @@ func AnalyzeDataTool() mcp.Tool {
 		mcp.WithString("group_by",
 			mcp.Description("Column to group by (required for groupby analysis)"),
 		),
-	)
+		mcp.WithNumber("bins",
+			mcp.Description("Number of bins for histogram analysis (1-100, default: 10)"),
+		),
+		mcp.WithString("method",
+			mcp.Description("Correlation method for corr_sig analysis (default: pearson)"),
+			mcp.Enum("pearson", "spearman"),
+		),
+		mcp.WithNumber("alpha",
+			mcp.Description("Significance threshold for corr_sig analysis; pairs with a p-value below it are flagged significant (default: 0.05)"),
+		),
+		mcp.WithString("time_column",
+			mcp.Description("Datetime column ordering the series (required for decompose analysis; for rolling analysis, orders the rows and allows time-based windows)"),
+		),
+		mcp.WithString("value_column",
+			mcp.Description("Numeric column to decompose (required for decompose analysis)"),
+		),
+		mcp.WithNumber("period",
+			mcp.Description("Observations per seasonal cycle for decompose analysis, e.g. 7 for daily data with weekly seasonality or 12 for monthly data (required; the series needs at least two cycles)"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Decomposition model for decompose analysis (default: additive; multiplicative needs positive values)"),
+			mcp.Enum("additive", "multiplicative"),
+		),
+		mcp.WithBoolean("plot",
+			mcp.Description("For decompose analysis, also save a plot of the components as decompose.png (default: false)"),
+		),
+		mcp.WithString("window",
+			mcp.Description("Window for rolling analysis: a number of rows (e.g. \"7\"), or with time_column a time span such as \"7D\" or \"12h\" (required)"),
+		),
+		mcp.WithString("agg",
+			mcp.Description("Aggregation for rolling analysis (default: mean)"),
+			mcp.Enum("mean", "sum", "std", "min", "max"),
+		),
+	))
 }
 
 // AnalyzeDataHandler handles the analyze_data tool.
 func (t *PandasTools) AnalyzeDataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	defer release()
 
 	// Extract arguments
 	filePath, err := request.RequireString("file_path")
@@ -246,27 +563,61 @@ func (t *PandasTools) AnalyzeDataHandler(ctx context.Context, request mcp.CallTo
 
 	groupBy := request.GetString("group_by", "")
 
+	readOpts, err := parseReadOptions(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Build file mapping
 	files := []string{resolvedPath}
+	if err := t.checkInputSize(request, files); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	fileMapping := executor.BuildFileMapping(files)
 	containerPath := fileMapping[resolvedPath]
 
+	params := executor.AnalysisParams{
+		Bins:           int(request.GetFloat("bins", 0)),
+		Method:         request.GetString("method", ""),
+		Alpha:          request.GetFloat("alpha", 0),
+		TimeColumn:     request.GetString("time_column", ""),
+		ValueColumn:    request.GetString("value_column", ""),
+		Period:         int(request.GetFloat("period", 0)),
+		Model:          request.GetString("model", ""),
+		Plot:           request.GetBool("plot", false),
+		Window:         windowArg(request.GetArguments()["window"]),
+		Agg:            request.GetString("agg", ""),
+		MaxCorrColumns: t.opts.MaxCorrColumns,
+		MaxValueCounts: t.opts.MaxValueCounts,
+	}
+
 	// Generate script
-	script := executor.AnalyzeDataScript(containerPath, analysisType, columns, groupBy)
+	script := executor.AnalyzeDataScript(containerPath, analysisType, columns, groupBy, params, readOpts)
 
 	// Execute
-	result, err := t.executor.ExecuteScript(ctx, script, files, 0)
+	result, err := t.executor.ExecuteScript(ctx, script, files, t.execOptions(request, 0, filePath))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
 	}
 
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	return executionToolResult(result), nil
+}
+
+// windowArg returns the rolling window argument as a string, accepting a row
+// count passed as a number.
+func windowArg(v interface{}) string {
+	switch val := v.(type) {
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case string:
+		return val
+	}
+	return ""
 }
 
 // TransformDataTool returns the transform_data tool definition.
 func TransformDataTool() mcp.Tool {
-	return mcp.NewTool("transform_data",
+	return withReadOptions(mcp.NewTool("transform_data",
 		mcp.WithDescription("Apply declarative transformations to a dataset and return the result. Supports filter, select, drop, sort, rename, dropna, fillna, and more operations. For SQL-style transforms or large datasets, consider query_data or run_pandas_script with duckdb/polars."),
 		mcp.WithString("input_file",
 			mcp.Required(),
@@ -285,24 +636,49 @@ Supported operations:
 - fillna: {type: "fillna", column: "col", fill_value: 0} (column optional)
 - head: {type: "head", n: 10}
 - tail: {type: "tail", n: 10}
-- sample: {type: "sample", n: 100} or {type: "sample", frac: 0.1}
-- unique: {type: "unique", columns: ["col1"]} (columns optional)`),
+- slice: {type: "slice", start: 100, stop: 200, step: 1} (rows by position, like df.iloc[start:stop:step]; negative positions count from the end; start, stop and step optional)
+- sample: {type: "sample", n: 100} or {type: "sample", frac: 0.1} (optional random_state: 42 for reproducible samples, replace: true to sample with replacement)
+- unique: {type: "unique", columns: ["col1"]} (columns optional)
+- drop_constant: {type: "drop_constant"} (drops columns with a single distinct value; optional columns limits which are considered)
+- drop_sparse: {type: "drop_sparse", threshold: 0.9} (drops columns whose null fraction exceeds threshold, default 0.9; optional columns limits which are considered)
+- optimize_dtypes: {type: "optimize_dtypes", category_threshold: 0.5} (downcasts numeric columns to the smallest type that holds their values and converts text columns whose distinct-value ratio is at most category_threshold to category; reports memory before and after; optional columns limits which are considered)
+- groupby_agg: {type: "groupby_agg", by: ["region"], aggs: {"sales": ["sum", "mean"], "id": "count"}, reset_index: true} (list aggregations are flattened to sales_sum, sales_mean; group keys become regular columns unless reset_index is false; without aggs, counts rows per group)
+- concat: {type: "concat", files: ["upload://...", "/path/b.csv"], axis: 0, ignore_index: true} (stacks the files onto the current frame; axis 1 joins column-wise)
+- partition: {type: "partition", column: "region"} (one output file per distinct value; later operations apply to each part)
+- split: {type: "split", test_size: 0.2, random_state: 42, stratify: "label"} (train/test split into *_train and *_test files; random_state and stratify optional)`),
 			mcp.Items(map[string]interface{}{"type": "object"}),
 		),
 		mcp.WithString("output_format",
-			mcp.Description("Output format: csv, json, or parquet (default: csv)"),
+			mcp.Description("Output format: csv, json, or parquet (default: the server's DEFAULT_OUTPUT_FORMAT, csv unless configured)"),
 			mcp.Enum("csv", "json", "parquet"),
 		),
-	)
+		mcp.WithString("output_filename",
+			mcp.Description("Base name of the output file, without extension (default: transformed). Operations that split the data write {output_filename}_{part}.{format} per part."),
+		),
+		mcp.WithString("compression",
+			mcp.Description("Compress the output: gzip, bz2, or xz for csv/json (adds .gz/.bz2/.xz to the file name), or snappy, gzip, zstd, brotli, or lz4 for parquet. none writes uncompressed files (default: uncompressed csv/json, snappy parquet)"),
+			mcp.Enum("none", "gzip", "bz2", "xz", "snappy", "zstd", "brotli", "lz4"),
+		),
+		mcp.WithBoolean("return_data",
+			mcp.Description("Also return the complete result in the response, printed as CSV and as records in the JSON result, when it has at most the server's MAX_RETURN_ROWS rows (default 1000). Larger results fall back to the saved file and a preview, with a note. The file is saved either way (default: false)"),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Run the operations but don't save the result; instead report its in-memory size and the estimated file size in output_format, uncompressed and with each compression codec, to decide on a format or compression before writing (default: false)"),
+		),
+		mcp.WithNumber("chunksize",
+			mcp.Description("Stream a CSV input this many rows at a time instead of loading it whole, for files larger than memory. Each chunk is transformed and appended to the output. Only row-wise operations are allowed (filter, select, drop, rename, dropna, fillna, astype); others such as sort, unique, or groupby_agg are rejected."),
+		),
+	))
 }
 
 // TransformDataHandler handles the transform_data tool.
 func (t *PandasTools) TransformDataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	defer release()
 
 	// Extract arguments
 	inputFile, err := request.RequireString("input_file")
@@ -310,40 +686,90 @@ func (t *PandasTools) TransformDataHandler(ctx context.Context, request mcp.Call
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'input_file': %v", err)), nil
 	}
 
-	// Resolve upload:// URI if needed
-	resolvedPath, err := t.resolveFilePath(inputFile)
+	opsArg := request.GetArguments()["operations"]
+	operations, err := toOperations(opsArg, t.opts.MaxOperations)
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'operations': %v", err)), nil
 	}
 
-	opsArg := request.GetArguments()["operations"]
-	operations, err := toOperations(opsArg)
+	outputFormat := request.GetString("output_format", t.executor.DefaultOutputFormat())
+
+	compression := request.GetString("compression", "")
+	ext, err := compressionExtension(outputFormat, compression)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'operations': %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'compression': %v", err)), nil
 	}
 
-	outputFormat := request.GetString("output_format", "csv")
+	outputName, err := outputBaseName(strings.TrimSuffix(request.GetString("output_filename", "transformed"), ext), outputFormat)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'output_filename': %v", err)), nil
+	}
 
-	// Build file mapping
-	files := []string{resolvedPath}
-	fileMapping := executor.BuildFileMapping(files)
-	containerPath := fileMapping[resolvedPath]
+	chunksize := request.GetFloat("chunksize", 0)
+	if chunksize < 0 || chunksize != float64(int(chunksize)) {
+		return mcp.NewToolResultError("invalid parameter 'chunksize': must be a positive whole number of rows"), nil
+	}
 
-	// Generate script
-	script := executor.TransformDataScript(containerPath, operations, outputFormat)
+	dryRun := request.GetBool("dry_run", false)
+	if dryRun && chunksize > 0 {
+		return mcp.NewToolResultError("invalid parameter 'dry_run': can't be combined with chunksize, which writes the output as it reads"), nil
+	}
 
-	// Execute
-	result, err := t.executor.ExecuteScript(ctx, script, files, 0)
+	returnRows := 0
+	if request.GetBool("return_data", false) {
+		returnRows = t.opts.MaxReturnRows
+	}
+
+	readOpts, err := parseReadOptions(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	// Files referenced by concat operations are mounted after the input file
+	extraFiles, err := operationInputs(operations)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'operations': %v", err)), nil
+	}
+
+	return t.runFilesScript(ctx, request, append([]string{inputFile}, extraFiles...), func(containerPaths []string) string {
+		bindOperationInputs(operations, containerPaths[1:])
+		return executor.TransformDataScript(containerPaths[0], operations, executor.TransformOptions{
+			OutputFormat: outputFormat,
+			OutputName:   outputName,
+			Compression:  compression,
+			Chunksize:    int(chunksize),
+			ReturnRows:   returnRows,
+			DryRun:       dryRun,
+		}, readOpts)
+	}), nil
 }
 
 // Helper functions
 
+// executionToolResult builds the tool result for an execution. When the script
+// called emit_result(), the payload is attached as a separate structured block
+// so clients don't have to parse it out of the log text.
+func executionToolResult(result *executor.ExecutionResult) *mcp.CallToolResult {
+	toolResult := mcp.NewToolResultText(formatExecutionResult(result))
+	if result.Result == nil {
+		return toolResult
+	}
+
+	// structuredContent must be a JSON object, so wrap lists and scalars
+	structured, ok := result.Result.(map[string]interface{})
+	if !ok {
+		structured = map[string]interface{}{"result": result.Result}
+	}
+	resultJSON, err := json.Marshal(structured)
+	if err != nil {
+		return toolResult
+	}
+
+	toolResult.Content = append(toolResult.Content, mcp.NewTextContent(string(resultJSON)))
+	toolResult.StructuredContent = structured
+	return toolResult
+}
+
 func formatExecutionResult(result *executor.ExecutionResult) string {
 	output := ""
 
@@ -365,7 +791,46 @@ func formatExecutionResult(result *executor.ExecutionResult) string {
 		output += "=== Error ===\n" + result.Error
 	}
 
-	output += fmt.Sprintf("\n\n[Execution completed in %v with exit code %d]", result.Duration.Round(time.Millisecond), result.ExitCode)
+	if len(result.Warnings) > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += "=== Warnings ===\n" + strings.Join(result.Warnings, "\n")
+	}
+
+	if len(result.Discarded) > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += fmt.Sprintf("=== Outputs Not Saved ===\nOutput persistence disabled; files were not saved (set OUTPUT_DIR to enable): %s\n", strings.Join(result.Discarded, ", "))
+		if len(result.OutputRefs) > 0 {
+			output += "Copies exported to file storage are listed below.\n"
+		}
+	}
+
+	if len(result.OutputRefs) > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		names := make([]string, 0, len(result.OutputRefs))
+		for name := range result.OutputRefs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		output += "=== Output References (usable as file inputs) ===\n"
+		for _, name := range names {
+			output += fmt.Sprintf("  %s -> %s\n", name, result.OutputRefs[name])
+		}
+	}
+
+	summary := fmt.Sprintf("Execution completed in %v with exit code %d", result.Duration.Round(time.Millisecond), result.ExitCode)
+	if result.CPUSeconds > 0 {
+		summary += fmt.Sprintf(", CPU time %.2fs", result.CPUSeconds)
+	}
+	if result.MemoryPeakBytes > 0 {
+		summary += fmt.Sprintf(", peak memory %.1fMB", float64(result.MemoryPeakBytes)/(1024*1024))
+	}
+	output += "\n\n[" + summary + "]"
 
 	// Append execution metadata as parseable JSON for downstream clients
 	// This enables secure file serving and proper URL generation
@@ -375,6 +840,27 @@ func formatExecutionResult(result *executor.ExecutionResult) string {
 			"output_files": result.OutputFiles,
 			"output_path":  result.OutputPath,
 		}
+		if result.OutputPath != "" {
+			metadata["resource_uri"] = outputURI(result.ExecutionID, "")
+		}
+		if len(result.OutputRefs) > 0 {
+			metadata["output_refs"] = result.OutputRefs
+		}
+		if len(result.Checksums) > 0 {
+			metadata["checksums"] = result.Checksums
+		}
+		if len(result.Discarded) > 0 {
+			metadata["discarded_files"] = result.Discarded
+		}
+		if result.MemoryPeakBytes > 0 {
+			metadata["memory_peak_bytes"] = result.MemoryPeakBytes
+		}
+		if result.CPUSeconds > 0 {
+			metadata["cpu_seconds"] = result.CPUSeconds
+		}
+		if result.OOMKilled {
+			metadata["oom_killed"] = true
+		}
 		metadataJSON, err := json.Marshal(metadata)
 		if err == nil {
 			output += "\n\n[EXECUTION_METADATA]" + string(metadataJSON) + "[/EXECUTION_METADATA]"
@@ -407,22 +893,36 @@ func toStringSlice(v interface{}) ([]string, error) {
 	}
 }
 
-func toOperations(v interface{}) ([]map[string]interface{}, error) {
+// toOperations converts a list of operation objects, rejecting more than max
+// entries (0 = unlimited) before any conversion.
+func toOperations(v interface{}, max int) ([]map[string]interface{}, error) {
 	if v == nil {
 		return nil, fmt.Errorf("value is nil")
 	}
 
 	switch val := v.(type) {
 	case []map[string]interface{}:
-		return val, nil
+		if max > 0 && len(val) > max {
+			return nil, fmt.Errorf("too many operations: %d (limit %d, set by MAX_OPERATIONS)", len(val), max)
+		}
+		result := make([]map[string]interface{}, len(val))
+		for i, m := range val {
+			result[i] = maps.Clone(m)
+		}
+		return result, nil
 	case []interface{}:
+		if max > 0 && len(val) > max {
+			return nil, fmt.Errorf("too many operations: %d (limit %d, set by MAX_OPERATIONS)", len(val), max)
+		}
 		result := make([]map[string]interface{}, len(val))
 		for i, item := range val {
 			m, ok := item.(map[string]interface{})
 			if !ok {
 				return nil, fmt.Errorf("item at index %d is not an object", i)
 			}
-			result[i] = m
+			// Copied so that binding container paths leaves the recorded
+			// request arguments as the client sent them
+			result[i] = maps.Clone(m)
 		}
 		return result, nil
 	default:
@@ -430,15 +930,85 @@ func toOperations(v interface{}) ([]map[string]interface{}, error) {
 	}
 }
 
-// getBaseName returns the base name of a file path.
-func getBaseName(path string) string {
-	// Handle both forward and backslashes
-	for i := len(path) - 1; i >= 0; i-- {
-		if path[i] == '/' || path[i] == '\\' {
-			return path[i+1:]
+// outputBaseName validates a user-supplied output file name and strips a
+// trailing extension matching the output format.
+func outputBaseName(name, format string) (string, error) {
+	name = strings.TrimSuffix(name, "."+format)
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\\x00") {
+		return "", fmt.Errorf("must be a plain file name")
+	}
+	if len(name) > 100 {
+		return "", fmt.Errorf("must be at most 100 characters")
+	}
+	return name, nil
+}
+
+// outputCompression lists the compression codecs supported per output
+// format, with the extension they add to the file name. It mirrors
+// OUTPUT_COMPRESSION in the script helpers.
+var outputCompression = map[string]map[string]string{
+	"csv":     {"gzip": ".gz", "bz2": ".bz2", "xz": ".xz"},
+	"json":    {"gzip": ".gz", "bz2": ".bz2", "xz": ".xz"},
+	"parquet": {"snappy": "", "gzip": "", "zstd": "", "brotli": "", "lz4": ""},
+}
+
+// compressionExtension checks that codec can compress format output and
+// returns the extension it adds. "" and "none" are always valid.
+func compressionExtension(format, codec string) (string, error) {
+	if codec == "" || codec == "none" {
+		return "", nil
+	}
+	ext, ok := outputCompression[format][codec]
+	if !ok {
+		var supported []string
+		for name := range outputCompression[format] {
+			supported = append(supported, name)
+		}
+		sort.Strings(supported)
+		return "", fmt.Errorf("%s is not supported for %s output (supported: none, %s)", codec, format, strings.Join(supported, ", "))
+	}
+	return ext, nil
+}
+
+// operationInputs returns the files referenced by concat operations, in
+// operation order, so they can be mounted alongside the main input.
+func operationInputs(operations []map[string]interface{}) ([]string, error) {
+	var files []string
+	for i, op := range operations {
+		if op["type"] != "concat" {
+			continue
+		}
+		list, ok := op["files"].([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("operation %d (concat): 'files' must be a non-empty array of file paths", i+1)
+		}
+		for _, item := range list {
+			f, ok := item.(string)
+			if !ok || f == "" {
+				return nil, fmt.Errorf("operation %d (concat): 'files' must contain only file paths", i+1)
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// bindOperationInputs replaces the files of each concat operation with their
+// container paths, consumed in the order returned by operationInputs.
+func bindOperationInputs(operations []map[string]interface{}, containerPaths []string) {
+	next := 0
+	for _, op := range operations {
+		if op["type"] != "concat" {
+			continue
+		}
+		list, _ := op["files"].([]interface{})
+		paths := make([]string, len(list))
+		for i := range list {
+			paths[i] = containerPaths[next]
+			next++
 		}
+		op["files"] = paths
 	}
-	return path
 }
 
 // QueryDataTool returns the query_data tool definition.
@@ -455,7 +1025,7 @@ func QueryDataTool() mcp.Tool {
 			mcp.Items(map[string]interface{}{"type": "string"}),
 		),
 		mcp.WithNumber("timeout",
-			mcp.Description("Maximum execution time in seconds (default: 60)"),
+			mcp.Description("Maximum execution time in seconds (default: the server's EXECUTION_TIMEOUT, or the default image's profile timeout, which also caps this value)"),
 		),
 	)
 }
@@ -463,10 +1033,11 @@ func QueryDataTool() mcp.Tool {
 // QueryDataHandler handles the query_data tool.
 func (t *PandasTools) QueryDataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	defer release()
 
 	// Extract arguments
 	query, err := request.RequireString("query")
@@ -486,26 +1057,24 @@ func (t *PandasTools) QueryDataHandler(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	timeout := time.Duration(request.GetFloat("timeout", 60)) * time.Second
+	timeout := time.Duration(request.GetFloat("timeout", 0)) * time.Second
 
 	// Build file mapping using original paths as keys
 	fileMapping := make(map[string]string)
-	for i, originalPath := range files {
-		containerPath := fmt.Sprintf("/data/input_%d/%s", i, getBaseName(resolvedFiles[i]))
-		fileMapping[originalPath] = containerPath
+	for i, containerPath := range executor.InputMountPaths(resolvedFiles, false) {
+		fileMapping[files[i]] = containerPath
 	}
 
 	// Generate DuckDB script
-	wrappedScript := executor.WrapDuckDBScript(query, fileMapping, t.executor.ChartThemeCode())
+	wrappedScript := executor.WrapDuckDBScript(query, fileMapping, t.executor.ChartThemeCode(), t.executor.DefaultOutputFormat())
 
 	// Execute with resolved paths
-	result, err := t.executor.ExecuteScript(ctx, wrappedScript, resolvedFiles, timeout)
+	result, err := t.executor.ExecuteScript(ctx, wrappedScript, resolvedFiles, t.execOptions(request, timeout, files...))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
 	}
 
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	return executionToolResult(result), nil
 }
 
 // ProfileDataTool returns the profile_data tool definition.
@@ -522,10 +1091,11 @@ func ProfileDataTool() mcp.Tool {
 // ProfileDataHandler handles the profile_data tool.
 func (t *PandasTools) ProfileDataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Try to acquire a worker slot
-	if err := t.pool.Acquire(ctx); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	release, errResult := t.acquireWorker(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
-	defer t.pool.Release()
+	defer release()
 
 	// Extract arguments
 	filePath, err := request.RequireString("file_path")
@@ -548,13 +1118,12 @@ func (t *PandasTools) ProfileDataHandler(ctx context.Context, request mcp.CallTo
 	script := executor.ProfileDataScript(containerPath)
 
 	// Execute
-	result, err := t.executor.ExecuteScript(ctx, script, files, 0)
+	result, err := t.executor.ExecuteScript(ctx, script, files, t.execOptions(request, 0, filePath))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("execution error: %v", err)), nil
 	}
 
-	output := formatExecutionResult(result)
-	return mcp.NewToolResultText(output), nil
+	return executionToolResult(result), nil
 }
 
 // ListOutputsTool returns the list_outputs tool definition.
@@ -581,17 +1150,62 @@ func (t *PandasTools) ListOutputsHandler(ctx context.Context, request mcp.CallTo
 	}
 
 	// List files for specific execution
-	files, err := outputManager.ListFiles(execID)
+	info, err := outputManager.GetExecution(execID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to list files: %v", err)), nil
 	}
+	files := info.Files
 
 	output := fmt.Sprintf("Files in execution %s:\n", execID)
+	if info.ToolName != "" {
+		output = fmt.Sprintf("Execution %s\n  Tool:    %s\n", execID, info.ToolName)
+		if len(info.Inputs) > 0 {
+			output += fmt.Sprintf("  Inputs:  %s\n", strings.Join(info.Inputs, ", "))
+		}
+		output += fmt.Sprintf("  Script:  sha256:%s\n", info.ScriptHash)
+		output += fmt.Sprintf("  Created: %s (expires %s)\n\nFiles:\n", info.CreatedAt.Format(time.RFC3339), info.ExpiresAt.Format(time.RFC3339))
+	}
 	if len(files) == 0 {
 		output += "  (no files)\n"
 	} else {
 		for _, f := range files {
-			output += fmt.Sprintf("  - %s\n", f)
+			if sum, ok := info.Checksums[f]; ok {
+				output += fmt.Sprintf("  - %s (sha256:%s)\n", f, sum)
+			} else {
+				output += fmt.Sprintf("  - %s\n", f)
+			}
+		}
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
+// ListRunningTool returns the list_running tool definition.
+func ListRunningTool() mcp.Tool {
+	return mcp.NewTool("list_running",
+		mcp.WithDescription("List executions that are currently running: execution ID, tool, start time, elapsed time, and input files. Use list_outputs for finished executions."),
+	)
+}
+
+// ListRunningHandler handles the list_running tool.
+func (t *PandasTools) ListRunningHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	running := t.executor.Running()
+	if len(running) == 0 {
+		return mcp.NewToolResultText("No executions are running."), nil
+	}
+
+	now := time.Now()
+	output := fmt.Sprintf("Running executions (%d):\n", len(running))
+	for _, r := range running {
+		output += fmt.Sprintf("\n%s\n", r.ExecutionID)
+		if r.ToolName != "" {
+			output += fmt.Sprintf("  Tool:    %s\n", r.ToolName)
+		}
+		if r.Image != executor.DefaultImageName {
+			output += fmt.Sprintf("  Image:   %s\n", r.Image)
+		}
+		output += fmt.Sprintf("  Started: %s (%s ago)\n", r.StartedAt.Format(time.RFC3339), now.Sub(r.StartedAt).Round(time.Second))
+		if len(r.Inputs) > 0 {
+			output += fmt.Sprintf("  Inputs:  %s\n", strings.Join(r.Inputs, ", "))
 		}
 	}
 	return mcp.NewToolResultText(output), nil
@@ -600,7 +1214,7 @@ func (t *PandasTools) ListOutputsHandler(ctx context.Context, request mcp.CallTo
 // GetOutputTool returns the get_output tool definition.
 func GetOutputTool() mcp.Tool {
 	return mcp.NewTool("get_output",
-		mcp.WithDescription("Get the contents of an output file from an execution."),
+		mcp.WithDescription("Get the contents of an output file from an execution. Text files are returned as text; binary files (parquet, images, PDFs, ...) as MCP image, audio, or embedded blob content with their MIME type, after a summary with the size and SHA-256. Binary files over 16MB are only summarized."),
 		mcp.WithString("exec_id",
 			mcp.Required(),
 			mcp.Description("The execution ID containing the file."),
@@ -609,6 +1223,15 @@ func GetOutputTool() mcp.Tool {
 			mcp.Required(),
 			mcp.Description("The name of the file to retrieve."),
 		),
+		mcp.WithNumber("head",
+			mcp.Description("Return only the first N bytes of a text file (ignored for binary files)."),
+		),
+		mcp.WithNumber("tail",
+			mcp.Description("Return only the last N bytes of a text file (ignored for binary files). Cannot be combined with head."),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Return at most N bytes of a text file; longer files are cut off with a note (ignored for binary files)."),
+		),
 	)
 }
 
@@ -629,19 +1252,77 @@ func (t *PandasTools) GetOutputHandler(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'filename': %v", err)), nil
 	}
 
-	data, err := outputManager.GetFile(execID, filename)
+	info, err := outputManager.GetExecution(execID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get file: %v", err)), nil
 	}
+	path := filepath.Join(info.OutputPath, filepath.Base(filename))
+
+	// Return as text if it's text-like, otherwise as binary content
+	if t.isTextFile(path) {
+		head := int64(request.GetFloat("head", 0))
+		tail := int64(request.GetFloat("tail", 0))
+		maxBytes := int64(request.GetFloat("max_bytes", 0))
+		if head < 0 || tail < 0 || maxBytes < 0 {
+			return mcp.NewToolResultError("head, tail and max_bytes must be positive"), nil
+		}
+		if head > 0 && tail > 0 {
+			return mcp.NewToolResultError("head and tail cannot be combined"), nil
+		}
+		if maxBytes > 0 {
+			switch {
+			case tail > 0:
+				tail = min(tail, maxBytes)
+			case head > 0:
+				head = min(head, maxBytes)
+			default:
+				head = maxBytes
+			}
+		}
 
-	// Return as text if it's text-like, otherwise indicate binary
-	if isTextFile(filename) {
-		return mcp.NewToolResultText(string(data)), nil
+		data, size, err := outputManager.GetFileRange(execID, filename, head, tail)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get file: %v", err)), nil
+		}
+		if int64(len(data)) == size {
+			return mcp.NewToolResultText(string(data)), nil
+		}
+		if tail > 0 {
+			// Skip a partial UTF-8 sequence at the cut
+			for len(data) > 0 && !utf8.RuneStart(data[0]) {
+				data = data[1:]
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("[showing last %d of %d bytes]\n%s", len(data), size, data)), nil
+		}
+		for r := 1; r <= utf8.UTFMax && r <= len(data); r++ {
+			if utf8.RuneStart(data[len(data)-r]) {
+				if !utf8.FullRune(data[len(data)-r:]) {
+					data = data[:len(data)-r]
+				}
+				break
+			}
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("%s\n[showing first %d of %d bytes; use tail to see the end]", data, len(data), size)), nil
+	}
+
+	// Binary files too large to inline are described without reading them
+	if st, err := os.Stat(path); err == nil && st.Size() > maxResourceBytes {
+		return mcp.NewToolResultText(fmt.Sprintf("Binary file: %s (%d bytes)\nExecution: %s\nFilename: %s\nSHA-256: %s\nContent not included: over the %d byte limit",
+			filename, st.Size(), execID, filename, info.Checksums[filepath.Base(filename)], maxResourceBytes)), nil
+	}
+
+	data, err := outputManager.GetFile(execID, filename)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get file: %v", err)), nil
 	}
 
-	// For binary files, return base64 encoded or just metadata
-	return mcp.NewToolResultText(fmt.Sprintf("Binary file: %s (%d bytes)\nExecution: %s\nFilename: %s",
-		filename, len(data), execID, filename)), nil
+	// Binary files come back as image, audio or blob content
+	sum := sha256.Sum256(data)
+	summary := fmt.Sprintf("Binary file: %s (%d bytes)\nExecution: %s\nFilename: %s\nSHA-256: %s",
+		filename, len(data), execID, filename, hex.EncodeToString(sum[:]))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent(summary), binaryContent(execID, filename, data)},
+	}, nil
 }
 
 // DeleteOutputsTool returns the delete_outputs tool definition.
@@ -674,14 +1355,110 @@ func (t *PandasTools) DeleteOutputsHandler(ctx context.Context, request mcp.Call
 	return mcp.NewToolResultText(fmt.Sprintf("Successfully deleted execution %s", execID)), nil
 }
 
-// isTextFile returns true if the file extension suggests a text file.
-func isTextFile(filename string) bool {
-	textExtensions := []string{".txt", ".csv", ".json", ".xml", ".html", ".md", ".py", ".log", ".yaml", ".yml"}
-	lower := strings.ToLower(filename)
-	for _, ext := range textExtensions {
-		if strings.HasSuffix(lower, ext) {
-			return true
+// ExtendOutputTTLTool returns the extend_output_ttl tool definition.
+func ExtendOutputTTLTool() mcp.Tool {
+	return mcp.NewTool("extend_output_ttl",
+		mcp.WithDescription("Keep an execution's outputs around longer by pushing back their expiry. The total lifetime is capped by the server's OUTPUT_MAX_TTL."),
+		mcp.WithString("exec_id",
+			mcp.Required(),
+			mcp.Description("The execution ID whose outputs should be kept."),
+		),
+		mcp.WithString("duration",
+			mcp.Required(),
+			mcp.Description("How much longer to keep the outputs, as a Go duration (e.g. \"12h\", \"90m\")."),
+		),
+	)
+}
+
+// ExtendOutputTTLHandler handles the extend_output_ttl tool.
+func (t *PandasTools) ExtendOutputTTLHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	outputManager := t.executor.GetOutputManager()
+	if outputManager == nil {
+		return mcp.NewToolResultError("Output management not configured. Set OUTPUT_DIR to enable output persistence."), nil
+	}
+
+	execID, err := request.RequireString("exec_id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'exec_id': %v", err)), nil
+	}
+
+	durationStr, err := request.RequireString("duration")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'duration': %v", err)), nil
+	}
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil || duration <= 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameter 'duration': %q is not a positive duration (e.g. \"12h\")", durationStr)), nil
+	}
+
+	expires, capped, err := outputManager.ExtendTTL(execID, duration)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to extend TTL: %v", err)), nil
+	}
+
+	output := fmt.Sprintf("Execution %s now expires at %s", execID, expires.Format(time.RFC3339))
+	if capped {
+		output += " (capped at the server's maximum output lifetime)"
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
+// defaultTextExtensions are the output file extensions returned as text.
+var defaultTextExtensions = []string{".txt", ".csv", ".json", ".xml", ".html", ".md", ".py", ".log", ".yaml", ".yml"}
+
+// textExtensions applies TEXT_EXTENSIONS entries to the defaults: "-.ext"
+// makes .ext binary, anything else adds it. Removed extensions map to false.
+func textExtensions(changes []string) map[string]bool {
+	exts := make(map[string]bool, len(defaultTextExtensions)+len(changes))
+	for _, ext := range defaultTextExtensions {
+		exts[ext] = true
+	}
+	for _, c := range changes {
+		remove := strings.HasPrefix(c, "-")
+		ext := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(c, "-"), "+"))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts[ext] = !remove
+	}
+	return exts
+}
+
+// textSniffBytes is how much of a file with an unknown extension is checked
+// to decide whether it is text.
+const textSniffBytes = 8 << 10
+
+// isTextFile reports whether the output file at path should be returned as
+// text: by extension, or for unknown extensions when it starts with valid
+// UTF-8 and no NUL bytes.
+func (t *PandasTools) isTextFile(path string) bool {
+	if text, known := t.textExts[strings.ToLower(filepath.Ext(path))]; known {
+		return text
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, textSniffBytes)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return false
+	}
+	if n == textSniffBytes {
+		// Drop a UTF-8 sequence cut off by the sniff window
+		for i := 1; i <= utf8.UTFMax && i <= len(buf); i++ {
+			if utf8.RuneStart(buf[len(buf)-i]) {
+				if !utf8.FullRune(buf[len(buf)-i:]) {
+					buf = buf[:len(buf)-i]
+				}
+				break
+			}
 		}
 	}
-	return false
+	return utf8.Valid(buf)
 }
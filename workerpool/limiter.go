@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrKeyLimit is returned when a key stayed at its concurrency limit for the
+// whole acquire timeout.
+var ErrKeyLimit = errors.New("too many concurrent executions of this kind")
+
+// Limiter caps concurrent work per key (e.g. a tool or analysis type),
+// independently of the Pool's global slots. Keys without a limit are not
+// restricted.
+type Limiter struct {
+	limits         map[string]int
+	acquireTimeout time.Duration
+	maxWaiting     int // Callers allowed to wait in Acquire (0 = unlimited)
+	mu             sync.Mutex
+	active         map[string]int
+	waiting        int           // Callers blocked in Acquire
+	released       chan struct{} // Closed and replaced whenever a slot is released
+}
+
+// NewLimiter creates a limiter with the given per-key limits.
+func NewLimiter(limits map[string]int, acquireTimeout time.Duration) *Limiter {
+	return &Limiter{
+		limits:         limits,
+		acquireTimeout: acquireTimeout,
+		active:         make(map[string]int),
+		released:       make(chan struct{}),
+	}
+}
+
+// Limited reports whether key has a concurrency limit.
+func (l *Limiter) Limited(key string) bool {
+	return l.limits[key] > 0
+}
+
+// SetMaxWaiting bounds how many callers may wait for a slot at once, across
+// all keys; further callers get ErrQueueFull without waiting. Zero removes
+// the bound.
+func (l *Limiter) SetMaxWaiting(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxWaiting = n
+}
+
+// Acquire takes a slot for key, waiting up to the acquire timeout. It
+// returns an error wrapping ErrKeyLimit if key stayed at its limit, and
+// ErrQueueFull at once if SetMaxWaiting callers are already waiting.
+func (l *Limiter) Acquire(ctx context.Context, key string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, l.acquireTimeout)
+	defer cancel()
+
+	l.mu.Lock()
+	if l.tryAcquireLocked(key) {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.maxWaiting > 0 && l.waiting >= l.maxWaiting {
+		l.mu.Unlock()
+		return ErrQueueFull
+	}
+	l.waiting++
+	defer func() {
+		l.mu.Lock()
+		l.waiting--
+		l.mu.Unlock()
+	}()
+
+	for {
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-timeoutCtx.Done():
+			if !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				return timeoutCtx.Err()
+			}
+			return l.limitError(key)
+		}
+		l.mu.Lock()
+		if l.tryAcquireLocked(key) {
+			l.mu.Unlock()
+			return nil
+		}
+	}
+}
+
+// TryAcquire takes a slot for key without waiting.
+func (l *Limiter) TryAcquire(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.tryAcquireLocked(key) {
+		return l.limitError(key)
+	}
+	return nil
+}
+
+// Release returns a slot taken for key.
+func (l *Limiter) Release(key string) {
+	if !l.Limited(key) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key] > 0 {
+		l.active[key]--
+	}
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// Active returns the slots currently held per limited key.
+func (l *Limiter) Active() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	active := make(map[string]int, len(l.limits))
+	for key := range l.limits {
+		active[key] = l.active[key]
+	}
+	return active
+}
+
+func (l *Limiter) tryAcquireLocked(key string) bool {
+	limit := l.limits[key]
+	if limit <= 0 {
+		return true
+	}
+	if l.active[key] >= limit {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+func (l *Limiter) limitError(key string) error {
+	return fmt.Errorf("%w: %s is limited to %d at a time", ErrKeyLimit, key, l.limits[key])
+}
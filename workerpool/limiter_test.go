@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// limiterWaiting returns the number of callers blocked in l.Acquire.
+func limiterWaiting(l *Limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiting
+}
+
+func TestLimiterKeys(t *testing.T) {
+	l := NewLimiter(map[string]int{"profile_data": 1, "analyze_data:corr": 2}, shortWait)
+	steps := []struct {
+		key  string
+		want error
+	}{
+		{"profile_data", nil},
+		{"profile_data", ErrKeyLimit},
+		{"analyze_data:corr", nil},
+		{"analyze_data:corr", nil},
+		{"analyze_data:corr", ErrKeyLimit},
+		{"peek", nil}, // No limit
+		{"peek", nil},
+	}
+	for i, s := range steps {
+		if err := l.Acquire(context.Background(), s.key); !errors.Is(err, s.want) {
+			t.Fatalf("step %d (%s): got %v, want %v", i, s.key, err, s.want)
+		}
+	}
+	want := map[string]int{"profile_data": 1, "analyze_data:corr": 2}
+	for key, n := range l.Active() {
+		if n != want[key] {
+			t.Errorf("Active[%s] = %d, want %d", key, n, want[key])
+		}
+	}
+
+	l.Release("profile_data")
+	if err := l.TryAcquire("profile_data"); err != nil {
+		t.Errorf("TryAcquire after release: %v", err)
+	}
+}
+
+func TestLimiterQueueFull(t *testing.T) {
+	l := NewLimiter(map[string]int{"a": 1, "b": 1}, time.Second)
+	l.SetMaxWaiting(1)
+	for _, key := range []string{"a", "b"} {
+		if err := l.TryAcquire(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waiter := make(chan error, 1)
+	go func() { waiter <- l.Acquire(context.Background(), "a") }()
+	eventually(t, "the waiter to queue", func() bool { return limiterWaiting(l) == 1 })
+
+	// The bound is shared by all keys
+	if err := l.Acquire(context.Background(), "b"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("second waiter: got %v, want ErrQueueFull", err)
+	}
+	// Free keys are not queued, so they aren't refused
+	if err := l.Acquire(context.Background(), "unlimited"); err != nil {
+		t.Fatalf("unlimited key: %v", err)
+	}
+
+	l.Release("a")
+	if err := <-waiter; err != nil {
+		t.Fatalf("queued waiter: %v", err)
+	}
+	if got := limiterWaiting(l); got != 0 {
+		t.Errorf("waiting = %d after the waiter acquired, want 0", got)
+	}
+}
+
+func TestLimiterCancelWhileWaiting(t *testing.T) {
+	l := NewLimiter(map[string]int{"a": 1}, time.Minute)
+	if err := l.TryAcquire("a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := make(chan error, 1)
+	go func() { waiter <- l.Acquire(ctx, "a") }()
+	eventually(t, "the waiter to queue", func() bool { return limiterWaiting(l) == 1 })
+
+	cancel()
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancellation did not end the wait")
+	}
+	if got, active := limiterWaiting(l), l.Active()["a"]; got != 0 || active != 1 {
+		t.Errorf("after cancel: %d waiting, %d active; want 0 and 1", got, active)
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	const limit, callers, rounds = 3, 24, 25
+	l := NewLimiter(map[string]int{"k": limit}, 10*time.Second)
+
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if err := l.Acquire(context.Background(), "k"); err != nil {
+					t.Errorf("Acquire: %v", err)
+					return
+				}
+				n := active.Add(1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(time.Microsecond)
+				active.Add(-1)
+				l.Release("k")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > limit {
+		t.Errorf("%d slots held at once, want at most %d", got, limit)
+	}
+	if got := l.Active()["k"]; got != 0 {
+		t.Errorf("Active = %d after all releases, want 0", got)
+	}
+}
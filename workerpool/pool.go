@@ -3,7 +3,9 @@
 //
 // See CONTRIBUTORS.md for full contributor list.
 
-// Package workerpool provides a semaphore-based worker pool for limiting concurrent operations.
+// Package workerpool limits concurrent operations: Pool caps the total number
+// of worker slots (optionally per client and with a bounded wait queue), and
+// Limiter caps concurrent work per key.
 package workerpool
 
 import (
@@ -16,96 +18,265 @@ import (
 // ErrPoolExhausted is returned when the worker pool is full and cannot accept new work.
 var ErrPoolExhausted = errors.New("server is busy. All worker slots are occupied. Please try again later")
 
-// Pool manages a fixed number of worker slots using a semaphore pattern.
+// ErrClientLimit is returned when a client already holds its maximum number
+// of concurrent worker slots.
+var ErrClientLimit = errors.New("too many concurrent executions for this client. Wait for a running execution to finish and try again")
+
+// ErrQueueFull is returned when the pool is full and the maximum number of
+// callers are already waiting for a slot.
+var ErrQueueFull = errors.New("server is busy. All worker slots are occupied and the request queue is full. Please try again later")
+
+// Pool manages a fixed number of worker slots, optionally capping how many a
+// single client may hold at once so one client cannot monopolize the pool.
 type Pool struct {
 	maxWorkers     int
+	maxPerClient   int // 0 = unlimited
+	maxWaiting     int // Callers allowed to wait in Acquire (0 = unlimited)
 	acquireTimeout time.Duration
-	sem            chan struct{}
-	mu             sync.RWMutex
+	mu             sync.Mutex
+	released       chan struct{} // Closed and replaced whenever a slot is released
 	activeCount    int
+	perClient      map[string]int
 	totalProcessed int64
+	waiting        int           // Callers blocked in Acquire
+	acquiredAt     []time.Time   // Start times of held slots, oldest first
+	totalHeld      time.Duration // Cumulative time slots were held
 }
 
+// defaultRetryAfter is suggested before any execution has completed.
+const defaultRetryAfter = 5 * time.Second
+
 // NewPool creates a new worker pool with the specified maximum workers and acquire timeout.
 func NewPool(maxWorkers int, acquireTimeout time.Duration) *Pool {
 	return &Pool{
 		maxWorkers:     maxWorkers,
 		acquireTimeout: acquireTimeout,
-		sem:            make(chan struct{}, maxWorkers),
+		released:       make(chan struct{}),
+		perClient:      make(map[string]int),
+	}
+}
+
+// AcquireTimeout returns how long Acquire waits for a slot.
+func (p *Pool) AcquireTimeout() time.Duration {
+	return p.acquireTimeout
+}
+
+// SetMaxPerClient caps the number of slots a single client may hold at once.
+// Zero disables the cap.
+func (p *Pool) SetMaxPerClient(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxPerClient = n
+}
+
+// SetMaxWaiting bounds how many callers may wait for a slot at once; further
+// callers get ErrQueueFull without waiting. Zero removes the bound.
+func (p *Pool) SetMaxWaiting(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxWaiting = n
+}
+
+// SetMaxWorkers resizes the pool. Held slots are unaffected: shrinking takes
+// effect as running work releases its slots, and growing wakes waiters.
+func (p *Pool) SetMaxWorkers(n int) {
+	if n < 1 {
+		return
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxWorkers = n
+	close(p.released)
+	p.released = make(chan struct{})
 }
 
 // Acquire attempts to acquire a worker slot from the pool.
 // Returns ErrPoolExhausted if a slot cannot be acquired within the timeout.
 func (p *Pool) Acquire(ctx context.Context) error {
+	return p.AcquireFor(ctx, "")
+}
+
+// AcquireFor attempts to acquire a worker slot on behalf of client, waiting up
+// to the acquire timeout. An empty client is not subject to the per-client cap.
+// Returns ErrClientLimit if the client stayed at its cap, or ErrPoolExhausted
+// if the pool stayed full, for the whole timeout, and ErrQueueFull at once if
+// SetMaxWaiting callers are already waiting.
+func (p *Pool) AcquireFor(ctx context.Context, client string) error {
 	// Create a timeout context if one isn't already set
 	timeoutCtx, cancel := context.WithTimeout(ctx, p.acquireTimeout)
 	defer cancel()
 
-	select {
-	case p.sem <- struct{}{}:
-		p.mu.Lock()
-		p.activeCount++
+	p.mu.Lock()
+	if p.tryAcquireLocked(client) {
 		p.mu.Unlock()
 		return nil
-	case <-timeoutCtx.Done():
-		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+	}
+	if p.maxWaiting > 0 && p.waiting >= p.maxWaiting {
+		p.mu.Unlock()
+		return ErrQueueFull
+	}
+	p.waiting++
+	defer func() {
+		p.mu.Lock()
+		p.waiting--
+		p.mu.Unlock()
+	}()
+
+	for {
+		released := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-timeoutCtx.Done():
+			if !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				return timeoutCtx.Err()
+			}
+			p.mu.Lock()
+			limited := p.clientLimitedLocked(client)
+			p.mu.Unlock()
+			if limited {
+				return ErrClientLimit
+			}
 			return ErrPoolExhausted
 		}
-		return timeoutCtx.Err()
+
+		p.mu.Lock()
+		if p.tryAcquireLocked(client) {
+			p.mu.Unlock()
+			return nil
+		}
 	}
 }
 
 // Release releases a worker slot back to the pool.
 func (p *Pool) Release() {
-	select {
-	case <-p.sem:
-		p.mu.Lock()
-		p.activeCount--
-		p.totalProcessed++
-		p.mu.Unlock()
-	default:
+	p.ReleaseFor("")
+}
+
+// ReleaseFor releases a worker slot acquired with AcquireFor or TryAcquireFor.
+func (p *Pool) ReleaseFor(client string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.activeCount == 0 {
 		// Pool was not acquired, ignore
+		return
+	}
+	p.activeCount--
+	p.totalProcessed++
+	if client != "" {
+		if p.perClient[client] <= 1 {
+			delete(p.perClient, client)
+		} else {
+			p.perClient[client]--
+		}
+	}
+	// Slots are interchangeable, so pairing with the oldest start time
+	// keeps totalHeld exact once all slots are released.
+	if len(p.acquiredAt) > 0 {
+		p.totalHeld += time.Since(p.acquiredAt[0])
+		p.acquiredAt = p.acquiredAt[1:]
 	}
+
+	// Wake all waiters; each rechecks whether it can take a slot
+	close(p.released)
+	p.released = make(chan struct{})
 }
 
 // TryAcquire attempts to acquire a worker slot without blocking.
 // Returns true if a slot was acquired, false otherwise.
 func (p *Pool) TryAcquire() bool {
-	select {
-	case p.sem <- struct{}{}:
-		p.mu.Lock()
-		p.activeCount++
-		p.mu.Unlock()
-		return true
-	default:
+	return p.TryAcquireFor("")
+}
+
+// TryAcquireFor attempts to acquire a worker slot for client without blocking.
+func (p *Pool) TryAcquireFor(client string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tryAcquireLocked(client)
+}
+
+// tryAcquireLocked takes a slot if one is free and the client is under its
+// cap. Callers must hold p.mu.
+func (p *Pool) tryAcquireLocked(client string) bool {
+	if p.activeCount >= p.maxWorkers || p.clientLimitedLocked(client) {
 		return false
 	}
+	p.activeCount++
+	if client != "" {
+		p.perClient[client]++
+	}
+	p.acquiredAt = append(p.acquiredAt, time.Now())
+	return true
+}
+
+// clientLimitedLocked reports whether client is at its slot cap. Callers must
+// hold p.mu.
+func (p *Pool) clientLimitedLocked(client string) bool {
+	return client != "" && p.maxPerClient > 0 && p.perClient[client] >= p.maxPerClient
+}
+
+// RetryAfter estimates how long a caller turned away with ErrPoolExhausted
+// should wait before retrying, based on the average time a slot is held and
+// the number of callers already waiting.
+func (p *Pool) RetryAfter() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	avg := p.avgDuration()
+	if avg <= 0 {
+		avg = defaultRetryAfter
+	}
+
+	// Each round of maxWorkers waiters needs roughly one average execution
+	rounds := p.waiting/p.maxWorkers + 1
+	d := (avg * time.Duration(rounds)).Round(time.Second)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// avgDuration returns the mean slot hold time. Callers must hold p.mu.
+func (p *Pool) avgDuration() time.Duration {
+	if p.totalProcessed == 0 {
+		return 0
+	}
+	return p.totalHeld / time.Duration(p.totalProcessed)
 }
 
 // Stats returns the current pool statistics.
 type Stats struct {
 	MaxWorkers     int
+	MaxPerClient   int
 	ActiveWorkers  int
 	AvailableSlots int
+	ActiveClients  int
 	TotalProcessed int64
+	Waiting        int
+	AvgDuration    time.Duration
 }
 
 // Stats returns the current pool statistics.
 func (p *Pool) Stats() Stats {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return Stats{
 		MaxWorkers:     p.maxWorkers,
+		MaxPerClient:   p.maxPerClient,
 		ActiveWorkers:  p.activeCount,
-		AvailableSlots: p.maxWorkers - p.activeCount,
+		AvailableSlots: max(p.maxWorkers-p.activeCount, 0), // Would go negative after shrinking below the held slots
+		ActiveClients:  len(p.perClient),
 		TotalProcessed: p.totalProcessed,
+		Waiting:        p.waiting,
+		AvgDuration:    p.avgDuration(),
 	}
 }
 
 // IsFull returns true if all worker slots are currently in use.
 func (p *Pool) IsFull() bool {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.activeCount >= p.maxWorkers
 }
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2026 Sagacient <sagacient@gmail.com>
+//
+// See CONTRIBUTORS.md for full contributor list.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// shortWait is the acquire timeout of pools whose waits are expected to fail.
+const shortWait = 20 * time.Millisecond
+
+// eventually fails the test unless cond becomes true within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPerClientFairness(t *testing.T) {
+	p := NewPool(3, shortWait)
+	p.SetMaxPerClient(1)
+	steps := []struct {
+		client string
+		want   error
+	}{
+		{"a", nil},
+		{"a", ErrClientLimit}, // a is at its cap while slots are free
+		{"b", nil},
+		{"c", nil},
+		{"d", ErrPoolExhausted},
+		{"", ErrPoolExhausted}, // Anonymous callers aren't capped, but the pool is full
+	}
+	for i, s := range steps {
+		if err := p.AcquireFor(context.Background(), s.client); !errors.Is(err, s.want) {
+			t.Fatalf("step %d (%q): got %v, want %v", i, s.client, err, s.want)
+		}
+	}
+	if got := p.Stats().ActiveClients; got != 3 {
+		t.Errorf("ActiveClients = %d, want 3", got)
+	}
+
+	// A slot freed by another client goes to d, not to a over its cap
+	p.ReleaseFor("b")
+	if p.TryAcquireFor("a") {
+		t.Error("a acquired a second slot over its cap")
+	}
+	if !p.TryAcquireFor("d") {
+		t.Error("d could not take the released slot")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	p := NewPool(1, time.Second)
+	p.SetMaxWaiting(1)
+	if !p.TryAcquire() {
+		t.Fatal("could not take the only slot")
+	}
+
+	waiter := make(chan error, 1)
+	go func() { waiter <- p.Acquire(context.Background()) }()
+	eventually(t, "the waiter to queue", func() bool { return p.Stats().Waiting == 1 })
+
+	start := time.Now()
+	if err := p.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("second waiter: got %v, want ErrQueueFull", err)
+	}
+	if d := time.Since(start); d > time.Second/2 {
+		t.Errorf("ErrQueueFull took %v; it should be returned without waiting", d)
+	}
+
+	p.Release()
+	if err := <-waiter; err != nil {
+		t.Fatalf("queued waiter: %v", err)
+	}
+	if got := p.Stats().Waiting; got != 0 {
+		t.Errorf("Waiting = %d after the waiter acquired, want 0", got)
+	}
+}
+
+func TestShrinkWhileHeld(t *testing.T) {
+	p := NewPool(3, shortWait)
+	for range 3 {
+		if !p.TryAcquire() {
+			t.Fatal("could not fill the pool")
+		}
+	}
+	p.SetMaxWorkers(1)
+
+	steps := []struct {
+		release   bool
+		available int
+		full      bool
+	}{
+		{available: 0, full: true}, // 3 held of 1
+		{release: true, available: 0, full: true},
+		{release: true, available: 0, full: true}, // 1 held of 1
+		{release: true, available: 1, full: false},
+	}
+	for i, s := range steps {
+		if s.release {
+			p.Release()
+		}
+		stats := p.Stats()
+		if stats.AvailableSlots != s.available {
+			t.Errorf("step %d: AvailableSlots = %d, want %d", i, stats.AvailableSlots, s.available)
+		}
+		if got := p.IsFull(); got != s.full {
+			t.Errorf("step %d: IsFull = %v, want %v", i, got, s.full)
+		}
+	}
+	if !p.TryAcquire() {
+		t.Error("could not acquire after the held slots drained")
+	}
+}
+
+func TestGrowWakesWaiters(t *testing.T) {
+	p := NewPool(1, time.Second)
+	if !p.TryAcquire() {
+		t.Fatal("could not take the only slot")
+	}
+	waiter := make(chan error, 1)
+	go func() { waiter <- p.Acquire(context.Background()) }()
+	eventually(t, "the waiter to queue", func() bool { return p.Stats().Waiting == 1 })
+
+	p.SetMaxWorkers(2)
+	select {
+	case err := <-waiter:
+		if err != nil {
+			t.Fatalf("waiter: %v", err)
+		}
+	case <-time.After(time.Second / 2):
+		t.Fatal("growing the pool did not wake the waiter")
+	}
+}
+
+func TestCancelWhileWaiting(t *testing.T) {
+	p := NewPool(1, time.Minute)
+	if !p.TryAcquire() {
+		t.Fatal("could not take the only slot")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := make(chan error, 1)
+	go func() { waiter <- p.AcquireFor(ctx, "a") }()
+	eventually(t, "the waiter to queue", func() bool { return p.Stats().Waiting == 1 })
+
+	cancel()
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancellation did not end the wait")
+	}
+	stats := p.Stats()
+	if stats.Waiting != 0 || stats.ActiveWorkers != 1 || stats.ActiveClients != 0 {
+		t.Errorf("after cancel: %+v, want 0 waiting, 1 active, no clients", stats)
+	}
+}
+
+func TestConcurrentAcquireRelease(t *testing.T) {
+	const workers, callers, rounds = 4, 32, 25
+	p := NewPool(workers, 10*time.Second)
+	p.SetMaxPerClient(2)
+
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := range callers {
+		client := string(rune('a' + i%8))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if err := p.AcquireFor(context.Background(), client); err != nil {
+					t.Errorf("AcquireFor(%s): %v", client, err)
+					return
+				}
+				n := active.Add(1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(time.Microsecond)
+				active.Add(-1)
+				p.ReleaseFor(client)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > workers {
+		t.Errorf("%d slots held at once, want at most %d", got, workers)
+	}
+	stats := p.Stats()
+	if stats.ActiveWorkers != 0 || stats.Waiting != 0 || stats.ActiveClients != 0 {
+		t.Errorf("pool not drained: %+v", stats)
+	}
+	if stats.TotalProcessed != callers*rounds {
+		t.Errorf("TotalProcessed = %d, want %d", stats.TotalProcessed, callers*rounds)
+	}
+}